@@ -0,0 +1,202 @@
+/*
+Package accesslog provides HTTP access logging middleware.
+
+Each request is logged once it has been served, in one of the formats:
+
+	common    the Common Log Format (CLF), followed by the latency
+	combined  CLF with the referer and user agent, followed by the latency
+	json      a JSON object per line
+
+The user is the principal that the request was authenticated as, or `-`.
+*/
+package accesslog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kward/avid-s3l/carbonio/auth"
+	"github.com/kward/avid-s3l/carbonio/helpers"
+)
+
+// Formats of the log entries.
+const (
+	Common   = "common"
+	Combined = "combined"
+	JSON     = "json"
+)
+
+// Destinations of the log, in addition to file paths.
+const (
+	Stderr = "-"
+	Stdout = "stdout"
+	// Off disables the log.
+	Off = "off"
+)
+
+// clfTime is the time layout of the Common Log Format.
+const clfTime = "02/Jan/2006:15:04:05 -0700"
+
+// Logger writes an entry for each request to a writer.
+type Logger struct {
+	mu     sync.Mutex
+	w      io.Writer
+	format string
+	now    func() time.Time
+}
+
+// New returns a logger that writes entries of the format to w.
+func New(w io.Writer, format string) (*Logger, error) {
+	switch format {
+	case Common, Combined, JSON:
+	default:
+		return nil, fmt.Errorf("unknown access log format %q; want %s, %s, or %s", format, Common, Combined, JSON)
+	}
+	return &Logger{w: w, format: format, now: time.Now}, nil
+}
+
+// Open returns the writer of a log destination: `-` for stderr, `stdout`, or
+// a file, which is appended to. The returned function closes the file.
+func Open(dest string) (io.Writer, func() error, error) {
+	switch dest {
+	case Stderr, "stderr":
+		return os.Stderr, func() error { return nil }, nil
+	case Stdout:
+		return os.Stdout, func() error { return nil }, nil
+	}
+	f, err := os.OpenFile(dest, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error opening access log; %s", err)
+	}
+	return f, f.Close, nil
+}
+
+// Entry is a logged request.
+type Entry struct {
+	Time      time.Time     `json:"time"`
+	Remote    string        `json:"remote"`
+	User      string        `json:"user,omitempty"`
+	Method    string        `json:"method"`
+	URI       string        `json:"uri"`
+	Proto     string        `json:"proto"`
+	Status    int           `json:"status"`
+	Bytes     int64         `json:"bytes"`
+	Latency   time.Duration `json:"-"`
+	LatencyMS float64       `json:"latency_ms"`
+	Referer   string        `json:"referer,omitempty"`
+	UserAgent string        `json:"user_agent,omitempty"`
+}
+
+// userKey is the context key of the user of a request.
+type userKey struct{}
+
+// SetUser records the user of the request being logged. Middleware that
+// authenticates requests calls it, as the principal it adds to the request
+// context is not visible to the logger.
+func SetUser(ctx context.Context, name string) {
+	if u, ok := ctx.Value(userKey{}).(*string); ok {
+		*u = name
+	}
+}
+
+// Handler logs the requests of the handler.
+func (l *Logger) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := l.now()
+		user := ""
+		if p, ok := auth.FromContext(r.Context()); ok {
+			user = p.Name // E.g. of a trusted listener.
+		}
+		rec := helpers.NewResponseRecorder(w)
+		next.ServeHTTP(rec, r.WithContext(context.WithValue(r.Context(), userKey{}, &user)))
+
+		status := rec.Status
+		if status == 0 {
+			status = http.StatusOK // Nothing was written.
+		}
+		latency := l.now().Sub(start)
+		l.Log(&Entry{
+			Time:      start,
+			Remote:    remoteHost(r.RemoteAddr),
+			User:      user,
+			Method:    r.Method,
+			URI:       requestURI(r),
+			Proto:     r.Proto,
+			Status:    status,
+			Bytes:     rec.Bytes,
+			Latency:   latency,
+			LatencyMS: float64(latency) / float64(time.Millisecond),
+			Referer:   r.Referer(),
+			UserAgent: r.UserAgent(),
+		})
+	})
+}
+
+// requestURI returns the URI of the request, without the token of its query,
+// if any, so that credentials are not logged.
+func requestURI(r *http.Request) string {
+	q := r.URL.Query()
+	if _, ok := q[auth.TokenParameter]; !ok {
+		return r.RequestURI
+	}
+	q.Del(auth.TokenParameter)
+	u := *r.URL
+	u.RawQuery = q.Encode()
+	return u.RequestURI()
+}
+
+// Log writes the entry.
+func (l *Logger) Log(e *Entry) {
+	var line string
+	switch l.format {
+	case JSON:
+		data, err := json.Marshal(e)
+		if err != nil {
+			return
+		}
+		line = string(data)
+	default:
+		line = fmt.Sprintf("%s - %s [%s] %q %d %s",
+			e.Remote, dash(strings.Replace(e.User, " ", "_", -1)), e.Time.Format(clfTime),
+			e.Method+" "+e.URI+" "+e.Proto, e.Status, clfBytes(e.Bytes))
+		if l.format == Combined {
+			line += fmt.Sprintf(" %q %q", dash(e.Referer), dash(e.UserAgent))
+		}
+		line += fmt.Sprintf(" %.3fms", e.LatencyMS)
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	io.WriteString(l.w, line+"\n")
+}
+
+// remoteHost returns the host of a remote address, without the port.
+func remoteHost(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// dash returns `-` for an empty field, as CLF does.
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// clfBytes returns the size of a response, which CLF gives as `-` when empty.
+func clfBytes(n int64) string {
+	if n == 0 {
+		return "-"
+	}
+	return fmt.Sprint(n)
+}
@@ -0,0 +1,145 @@
+package accesslog
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kward/avid-s3l/carbonio/auth"
+)
+
+func TestNew(t *testing.T) {
+	for _, tc := range []struct {
+		desc   string
+		format string
+		ok     bool
+	}{
+		{"common", Common, true},
+		{"combined", Combined, true},
+		{"json", JSON, true},
+		{"unknown", "xml", false},
+	} {
+		t.Run(fmt.Sprintf("New() %s", tc.desc), func(t *testing.T) {
+			_, err := New(&bytes.Buffer{}, tc.format)
+			if err == nil && !tc.ok {
+				t.Fatalf("expected an error")
+			}
+			if err != nil && tc.ok {
+				t.Fatalf("unexpected error; %s", err)
+			}
+		})
+	}
+}
+
+// serve a request with the logger, and return the log.
+func serve(t *testing.T, format string, h http.HandlerFunc, req *http.Request) string {
+	buf := &bytes.Buffer{}
+	l, err := New(buf, format)
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	start := time.Date(2020, 2, 24, 13, 55, 36, 0, time.UTC)
+	now := start
+	l.now = func() time.Time {
+		t := now
+		now = now.Add(1500 * time.Microsecond)
+		return t
+	}
+	l.Handler(h).ServeHTTP(httptest.NewRecorder(), req)
+	return buf.String()
+}
+
+func TestHandler(t *testing.T) {
+	hello := func(w http.ResponseWriter, r *http.Request) {
+		SetUser(r.Context(), "foh tablet")
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprint(w, "hello")
+	}
+	for _, tc := range []struct {
+		desc    string
+		format  string
+		handler http.HandlerFunc
+		want    string
+	}{
+		{"common", Common, hello,
+			`192.0.2.1 - foh_tablet [24/Feb/2020:13:55:36 +0000] "PUT /a?b=c HTTP/1.1" 201 5 1.500ms` + "\n"},
+		{"combined", Combined, hello,
+			`192.0.2.1 - foh_tablet [24/Feb/2020:13:55:36 +0000] "PUT /a?b=c HTTP/1.1" 201 5 "-" "test" 1.500ms` + "\n"},
+		{"empty response", Common, func(http.ResponseWriter, *http.Request) {},
+			`192.0.2.1 - - [24/Feb/2020:13:55:36 +0000] "PUT /a?b=c HTTP/1.1" 200 - 1.500ms` + "\n"},
+	} {
+		t.Run(fmt.Sprintf("Handler() %s", tc.desc), func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/a?b=c", nil)
+			req.RemoteAddr = "192.0.2.1:1234"
+			req.Header.Set("User-Agent", "test")
+			if got := serve(t, tc.format, tc.handler, req); got != tc.want {
+				t.Errorf("log = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHandlerToken(t *testing.T) {
+	for _, tc := range []struct {
+		desc string
+		uri  string
+		want string
+	}{
+		{"only parameter", "/ws?access_token=secret", "/ws"},
+		{"other parameters", "/events?b=c&access_token=secret", "/events?b=c"},
+	} {
+		t.Run(fmt.Sprintf("Handler() %s", tc.desc), func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.uri, nil)
+			log := serve(t, JSON, func(http.ResponseWriter, *http.Request) {}, req)
+			if strings.Contains(log, "secret") {
+				t.Errorf("log = %q, want no token", log)
+			}
+			e := &Entry{}
+			if err := json.Unmarshal([]byte(log), e); err != nil {
+				t.Fatalf("error decoding %q; %s", log, err)
+			}
+			if e.URI != tc.want {
+				t.Errorf("uri = %q, want %q", e.URI, tc.want)
+			}
+		})
+	}
+}
+
+func TestHandlerJSON(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/status", nil)
+	req.RemoteAddr = "[fe80::1]:1234"
+	// The principal of a trusted listener is logged as the user.
+	req = req.WithContext(auth.NewContext(req.Context(), &auth.Principal{Name: "admin", Role: auth.Admin}))
+	log := serve(t, JSON, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusNotFound)
+	}, req)
+
+	e := &Entry{}
+	if err := json.Unmarshal([]byte(log), e); err != nil {
+		t.Fatalf("error decoding %q; %s", log, err)
+	}
+	for _, c := range []struct {
+		field     string
+		got, want interface{}
+	}{
+		{"remote", e.Remote, "fe80::1"},
+		{"user", e.User, "admin"},
+		{"method", e.Method, http.MethodGet},
+		{"uri", e.URI, "/status"},
+		{"status", e.Status, http.StatusNotFound},
+		{"bytes", e.Bytes, int64(5)},
+		{"latency_ms", e.LatencyMS, 1.5},
+	} {
+		if c.got != c.want {
+			t.Errorf("%s = %v, want %v", c.field, c.got, c.want)
+		}
+	}
+	if !strings.HasSuffix(log, "}\n") {
+		t.Errorf("log = %q, want one line", log)
+	}
+}
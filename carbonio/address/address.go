@@ -0,0 +1,305 @@
+// Package address parses, formats, and resolves hierarchical carbonio
+// addresses.
+//
+// Addresses are slash separated paths that identify the signals, parameters,
+// and LEDs of a device. For example:
+//
+//	input/mic/1            mic input #1 (all properties)
+//	input/mic/1/gain       gain of mic input #1
+//	input/mic/1-8/pad      pad of mic inputs #1 through #8
+//	input/mic/*/phantom    phantom of all mic inputs
+//	led/status             the status LED
+//	avb/<uid>/hostname     AVB hostname of the device <uid>
+//
+// A segment is either a comma separated list of literals (e.g. `input` or
+// `power,status`), a wildcard (`*`) that matches any single segment, or a comma
+// separated list of numbers and ranges (e.g. `1-4,9`). An address that is
+// shorter than the address it is matched against matches all addresses below
+// it, i.e. `input/mic/1` matches `input/mic/1/gain`.
+package address
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kward/golib/errors"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	// Separator between address segments.
+	Separator = "/"
+	// Wildcard matches any single segment.
+	Wildcard = "*"
+)
+
+// Address describes a parsed carbonio address.
+type Address struct {
+	segs []segment
+}
+
+// Parse an address string. Leading and trailing separators are ignored, which
+// allows OSC style addresses (e.g. `/input/mic/1/gain`) to be parsed as well.
+func Parse(s string) (Address, error) {
+	s = strings.Trim(strings.TrimSpace(s), Separator)
+	if s == "" {
+		return Address{}, errors.Errorf(codes.InvalidArgument, "empty address")
+	}
+
+	var a Address
+	for _, str := range strings.Split(s, Separator) {
+		seg, err := parseSegment(str)
+		if err != nil {
+			return Address{}, errors.Errorf(codes.InvalidArgument, "invalid address %q; %s", s, errors.ErrorDesc(err))
+		}
+		a.segs = append(a.segs, seg)
+	}
+	return a, nil
+}
+
+// MustParse is like Parse, but panics if the address cannot be parsed. It is
+// intended for addresses that are known at compile time.
+func MustParse(s string) Address {
+	a, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// Join returns a new address with the segments `elem` appended to `a`.
+func (a Address) Join(elem ...string) (Address, error) {
+	b := Address{segs: append([]segment{}, a.segs...)}
+	for _, e := range elem {
+		c, err := Parse(e)
+		if err != nil {
+			return Address{}, err
+		}
+		b.segs = append(b.segs, c.segs...)
+	}
+	return b, nil
+}
+
+// Len returns the number of segments in the address.
+func (a Address) Len() int { return len(a.segs) }
+
+// IsZero returns true if the address is empty.
+func (a Address) IsZero() bool { return len(a.segs) == 0 }
+
+// Segment returns the string form of the i'th segment.
+func (a Address) Segment(i int) string {
+	if i < 0 || i >= len(a.segs) {
+		return ""
+	}
+	return a.segs[i].String()
+}
+
+// Number returns the number of the i'th segment, if the segment is a single
+// number.
+func (a Address) Number(i int) (int, bool) {
+	if i < 0 || i >= len(a.segs) {
+		return 0, false
+	}
+	return a.segs[i].number()
+}
+
+// Parent returns the address with the last segment removed.
+func (a Address) Parent() Address {
+	if len(a.segs) == 0 {
+		return a
+	}
+	return Address{segs: a.segs[:len(a.segs)-1]}
+}
+
+// Base returns the string form of the last segment.
+func (a Address) Base() string { return a.Segment(len(a.segs) - 1) }
+
+// IsConcrete returns true if the address contains neither wildcards nor
+// ranges, i.e. it identifies a single item.
+func (a Address) IsConcrete() bool {
+	for _, s := range a.segs {
+		if !s.isConcrete() {
+			return false
+		}
+	}
+	return true
+}
+
+// Match returns true if the concrete address `b` is matched by `a`. An address
+// that is shorter than `b` matches when all of its segments match, i.e. it
+// matches everything below it.
+func (a Address) Match(b Address) bool {
+	if len(a.segs) == 0 || len(a.segs) > len(b.segs) {
+		return false
+	}
+	for i, s := range a.segs {
+		if !s.match(b.segs[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// Equal returns true if both addresses are identical.
+func (a Address) Equal(b Address) bool { return a.String() == b.String() }
+
+// String implements fmt.Stringer.
+func (a Address) String() string {
+	strs := make([]string, len(a.segs))
+	for i, s := range a.segs {
+		strs[i] = s.String()
+	}
+	return strings.Join(strs, Separator)
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (a Address) MarshalText() ([]byte, error) { return []byte(a.String()), nil }
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (a *Address) UnmarshalText(text []byte) error {
+	b, err := Parse(string(text))
+	if err != nil {
+		return err
+	}
+	*a = b
+	return nil
+}
+
+//-----------------------------------------------------------------------------
+// Segments.
+
+type segmentKind int
+
+const (
+	literalSegment segmentKind = iota
+	wildcardSegment
+	rangeSegment
+)
+
+type segment struct {
+	kind   segmentKind
+	lits   []string
+	ranges []numRange
+}
+
+// numRange is an inclusive range of numbers.
+type numRange struct {
+	lo, hi int
+}
+
+func parseSegment(s string) (segment, error) {
+	switch {
+	case s == "":
+		return segment{}, errors.Errorf(codes.InvalidArgument, "empty segment")
+	case s == Wildcard:
+		return segment{kind: wildcardSegment}, nil
+	case isNumeric(s):
+		return parseRanges(s)
+	}
+	seg := segment{kind: literalSegment}
+	for _, lit := range strings.Split(s, ",") {
+		if lit == "" || strings.ContainsAny(lit, " \t\n*") {
+			return segment{}, errors.Errorf(codes.InvalidArgument, "invalid characters in segment %q", s)
+		}
+		seg.lits = append(seg.lits, lit)
+	}
+	return seg, nil
+}
+
+// parseRanges parses a comma separated list of numbers and ranges, e.g.
+// `1-4,9`.
+func parseRanges(s string) (segment, error) {
+	seg := segment{kind: rangeSegment}
+	for _, str := range strings.Split(s, ",") {
+		parts := strings.SplitN(str, "-", 2)
+		lo, err := strconv.Atoi(parts[0])
+		if err != nil || lo < 0 {
+			return segment{}, errors.Errorf(codes.InvalidArgument, "invalid number %q", parts[0])
+		}
+		hi := lo
+		if len(parts) == 2 {
+			if hi, err = strconv.Atoi(parts[1]); err != nil || hi < 0 {
+				return segment{}, errors.Errorf(codes.InvalidArgument, "invalid number %q", parts[1])
+			}
+		}
+		if lo > hi {
+			return segment{}, errors.Errorf(codes.InvalidArgument, "invalid range %d-%d", lo, hi)
+		}
+		seg.ranges = append(seg.ranges, numRange{lo, hi})
+	}
+	return seg, nil
+}
+
+// isNumeric returns true if the segment starts with a digit, and contains only
+// digits and range characters.
+func isNumeric(s string) bool {
+	if s[0] < '0' || s[0] > '9' {
+		return false
+	}
+	return strings.Trim(s, "0123456789-,") == ""
+}
+
+func (s segment) isConcrete() bool {
+	switch s.kind {
+	case wildcardSegment:
+		return false
+	case rangeSegment:
+		return len(s.ranges) == 1 && s.ranges[0].lo == s.ranges[0].hi
+	}
+	return len(s.lits) == 1
+}
+
+func (s segment) number() (int, bool) {
+	if s.kind != rangeSegment || !s.isConcrete() {
+		return 0, false
+	}
+	return s.ranges[0].lo, true
+}
+
+// match returns true if the concrete segment `t` is matched by `s`.
+func (s segment) match(t segment) bool {
+	switch s.kind {
+	case wildcardSegment:
+		return true
+	case literalSegment:
+		if t.kind != literalSegment || !t.isConcrete() {
+			return false
+		}
+		for _, lit := range s.lits {
+			if lit == t.lits[0] {
+				return true
+			}
+		}
+		return false
+	}
+	n, ok := t.number()
+	if !ok {
+		return false
+	}
+	for _, r := range s.ranges {
+		if n >= r.lo && n <= r.hi {
+			return true
+		}
+	}
+	return false
+}
+
+// String implements fmt.Stringer.
+func (s segment) String() string {
+	switch s.kind {
+	case wildcardSegment:
+		return Wildcard
+	case literalSegment:
+		return strings.Join(s.lits, ",")
+	}
+	strs := make([]string, len(s.ranges))
+	for i, r := range s.ranges {
+		if r.lo == r.hi {
+			strs[i] = strconv.Itoa(r.lo)
+			continue
+		}
+		strs[i] = fmt.Sprintf("%d-%d", r.lo, r.hi)
+	}
+	return strings.Join(strs, ",")
+}
@@ -0,0 +1,27 @@
+// Code generated by "stringer -output=address_string.go -type=Kind properties.go"; DO NOT EDIT.
+
+package address
+
+import "strconv"
+
+func _() {
+	// An "invalid array index" compiler error signifies that the constant values have changed.
+	// Re-run the stringer command to generate them again.
+	var x [1]struct{}
+	_ = x[unknownKind-0]
+	_ = x[Bool-1]
+	_ = x[Int-2]
+	_ = x[Enum-3]
+}
+
+const _Kind_name = "unknownKindBoolIntEnum"
+
+var _Kind_index = [...]uint8{0, 11, 15, 18, 22}
+
+func (i Kind) String() string {
+	idx := int(i) - 0
+	if i < 0 || idx >= len(_Kind_index)-1 {
+		return "Kind(" + strconv.FormatInt(int64(i), 10) + ")"
+	}
+	return _Kind_name[_Kind_index[idx]:_Kind_index[idx+1]]
+}
@@ -0,0 +1,133 @@
+package address
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		desc string
+		ok   bool
+
+		addr     string
+		str      string
+		concrete bool
+	}{
+		// Valid addresses.
+		{"input", true, "input/mic/1", "input/mic/1", true},
+		{"property", true, "input/mic/1/gain", "input/mic/1/gain", true},
+		{"osc style", true, "/input/mic/1/gain", "input/mic/1/gain", true},
+		{"trailing slash", true, "input/mic/1/", "input/mic/1", true},
+		{"range", true, "input/mic/1-8", "input/mic/1-8", false},
+		{"ranges", true, "input/mic/1-4,9,12-16/pad", "input/mic/1-4,9,12-16/pad", false},
+		{"single range", true, "input/mic/3-3", "input/mic/3", true},
+		{"wildcard", true, "input/mic/*/phantom", "input/mic/*/phantom", false},
+		{"led", true, "led/status", "led/status", true},
+		{"leds", true, "led/power,status", "led/power,status", false},
+		{"avb", true, "avb/0x001cab0000000000/hostname", "avb/0x001cab0000000000/hostname", true},
+		{"ext", true, "ext/ibank/0/ch/3/48V", "ext/ibank/0/ch/3/48V", true},
+
+		// Invalid addresses.
+		{desc: "empty", addr: ""},
+		{desc: "empty segment", addr: "input//1"},
+		{desc: "reversed range", addr: "input/mic/8-1"},
+		{desc: "open range", addr: "input/mic/1-"},
+		{desc: "bad range", addr: "input/mic/1--2"},
+		{desc: "embedded wildcard", addr: "input/m*c/1"},
+		{desc: "whitespace", addr: "input/mic 1"},
+	} {
+		t.Run(fmt.Sprintf("Parse() %s", tc.desc), func(t *testing.T) {
+			a, err := Parse(tc.addr)
+			if err != nil && tc.ok {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			if err == nil && !tc.ok {
+				t.Fatal("expected an error")
+			}
+			if !tc.ok {
+				return
+			}
+			if got, want := a.String(), tc.str; got != want {
+				t.Errorf("String() = %q, want %q", got, want)
+			}
+			if got, want := a.IsConcrete(), tc.concrete; got != want {
+				t.Errorf("IsConcrete() = %t, want %t", got, want)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	for _, tc := range []struct {
+		pattern string
+		addr    string
+		match   bool
+	}{
+		{"input/mic/1/gain", "input/mic/1/gain", true},
+		{"input/mic/1", "input/mic/1/gain", true},
+		{"input", "input/mic/1/gain", true},
+		{"input/mic/1-8", "input/mic/8/pad", true},
+		{"input/mic/1-8", "input/mic/9/pad", false},
+		{"input/mic/1-4,9", "input/mic/9/pad", true},
+		{"input/mic/*/phantom", "input/mic/16/phantom", true},
+		{"input/mic/*/phantom", "input/mic/16/pad", false},
+		{"*/*/1", "input/mic/1/gain", true},
+		{"input/mic/1/gain", "input/mic/1", false},
+		{"input/mic/1", "input/mic/10/gain", false},
+		{"led/status", "led/power/state", false},
+		{"led/power,mute", "led/mute/state", true},
+	} {
+		t.Run(fmt.Sprintf("%s matches %s", tc.pattern, tc.addr), func(t *testing.T) {
+			if got, want := MustParse(tc.pattern).Match(MustParse(tc.addr)), tc.match; got != want {
+				t.Errorf("= %t, want %t", got, want)
+			}
+		})
+	}
+}
+
+func TestProperty_Parse(t *testing.T) {
+	state := &Property{Name: "state", Kind: Enum, States: []string{"Off", "On"}}
+
+	for _, tc := range []struct {
+		desc string
+		ok   bool
+
+		prop  *Property
+		str   string
+		value interface{}
+	}{
+		// Valid values.
+		{"gain", true, GainProperty, "30", 30},
+		{"gain min", true, GainProperty, "10", 10},
+		{"gain max", true, GainProperty, "60", 60},
+		{"pad on", true, PadProperty, "on", true},
+		{"pad off", true, PadProperty, "Off", false},
+		{"phantom 1", true, PhantomProperty, "1", true},
+		{"state", true, state, "on", "On"},
+
+		// Invalid values.
+		{desc: "gain too low", prop: GainProperty, str: "9"},
+		{desc: "gain too high", prop: GainProperty, str: "61"},
+		{desc: "gain not a number", prop: GainProperty, str: "loud"},
+		{desc: "pad unknown", prop: PadProperty, str: "maybe"},
+		{desc: "pad enabled", prop: PadProperty, str: "enabled"},
+		{desc: "state unknown", prop: state, str: "Alert"},
+	} {
+		t.Run(fmt.Sprintf("Parse() %s", tc.desc), func(t *testing.T) {
+			v, err := tc.prop.Parse(tc.str)
+			if err != nil && tc.ok {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			if err == nil && !tc.ok {
+				t.Fatal("expected an error")
+			}
+			if !tc.ok {
+				return
+			}
+			if got, want := v, tc.value; got != want {
+				t.Errorf("= %v, want %v", got, want)
+			}
+		})
+	}
+}
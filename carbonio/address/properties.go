@@ -0,0 +1,142 @@
+package address
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/kward/golib/errors"
+	"google.golang.org/grpc/codes"
+)
+
+//go:generate stringer -output=address_string.go -type=Kind properties.go
+
+// Kind of a property value.
+type Kind int
+
+const (
+	unknownKind Kind = iota
+	Bool             // Values are of type bool.
+	Int              // Values are of type int.
+	Enum             // Values are of type string, one of States.
+)
+
+// ParseKind returns the kind with the given name, e.g. `Int`. The name is
+// matched case-insensitively.
+func ParseKind(name string) (Kind, error) {
+	for _, k := range []Kind{Bool, Int, Enum} {
+		if strings.EqualFold(k.String(), name) {
+			return k, nil
+		}
+	}
+	return unknownKind, errors.Errorf(codes.InvalidArgument, "unknown kind %q", name)
+}
+
+// Property describes a readable (and possibly writable) value of a device.
+type Property struct {
+	Name     string
+	Kind     Kind
+	Unit     string   // Unit of Int values, e.g. "dB".
+	Min, Max int      // Range of Int values.
+	States   []string // Valid Enum values.
+	Editable bool
+}
+
+// Properties of the supported signals and LEDs.
+var (
+	GainProperty    = &Property{Name: "gain", Kind: Int, Unit: "dB", Min: 10, Max: 60, Editable: true}
+	PadProperty     = &Property{Name: "pad", Kind: Bool, Editable: true}
+	PhantomProperty = &Property{Name: "phantom", Kind: Bool, Editable: true}
+)
+
+// Parse a string into a value of the property kind. Bool values accept
+// on/off, true/false, yes/no, and 1/0.
+func (p *Property) Parse(s string) (interface{}, error) {
+	s = strings.TrimSpace(s)
+	switch p.Kind {
+	case Bool:
+		switch strings.ToLower(s) {
+		case "on", "true", "yes", "1":
+			return true, nil
+		case "off", "false", "no", "0":
+			return false, nil
+		}
+		return nil, errors.Errorf(codes.InvalidArgument, "invalid %s value %q; want on or off", p.Name, s)
+	case Int:
+		v, err := strconv.Atoi(s)
+		if err != nil {
+			return nil, errors.Errorf(codes.InvalidArgument, "invalid %s value %q; want a number", p.Name, s)
+		}
+		return p.Normalize(v)
+	case Enum:
+		return p.Normalize(s)
+	}
+	return nil, errors.Errorf(codes.Internal, "unsupported %s kind %s", p.Name, p.Kind)
+}
+
+// Normalize converts `v` into the canonical type for the property kind, and
+// validates that it is within range. It accepts the types produced by
+// encoding/json as well.
+func (p *Property) Normalize(v interface{}) (interface{}, error) {
+	switch p.Kind {
+	case Bool:
+		switch t := v.(type) {
+		case bool:
+			return t, nil
+		case string:
+			return p.Parse(t)
+		}
+	case Int:
+		var i int
+		switch t := v.(type) {
+		case int:
+			i = t
+		case uint:
+			i = int(t)
+		case float64:
+			if t != float64(int(t)) {
+				return nil, errors.Errorf(codes.InvalidArgument, "invalid %s value %v; want a whole number", p.Name, t)
+			}
+			i = int(t)
+		case json.Number:
+			n, err := t.Int64()
+			if err != nil {
+				return nil, errors.Errorf(codes.InvalidArgument, "invalid %s value %v; want a whole number", p.Name, t)
+			}
+			i = int(n)
+		case string:
+			return p.Parse(t)
+		default:
+			return nil, errors.Errorf(codes.InvalidArgument, "invalid %s value %v (%T)", p.Name, v, v)
+		}
+		if i < p.Min || i > p.Max {
+			return nil, errors.Errorf(codes.OutOfRange, "%s value %d out of range [%d:%d]", p.Name, i, p.Min, p.Max)
+		}
+		return i, nil
+	case Enum:
+		if s, ok := v.(string); ok {
+			for _, state := range p.States {
+				if strings.EqualFold(state, strings.TrimSpace(s)) {
+					return state, nil
+				}
+			}
+			return nil, errors.Errorf(codes.OutOfRange, "invalid %s value %q; want one of %s", p.Name, s, strings.Join(p.States, ", "))
+		}
+	}
+	return nil, errors.Errorf(codes.InvalidArgument, "invalid %s value %v (%T)", p.Name, v, v)
+}
+
+// Format a value of the property kind for human consumption.
+func (p *Property) Format(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return "-"
+	case bool:
+		if t {
+			return "On"
+		}
+		return "Off"
+	}
+	return fmt.Sprintf("%v", v)
+}
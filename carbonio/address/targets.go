@@ -0,0 +1,284 @@
+package address
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kward/avid-s3l/carbonio/devices"
+	"github.com/kward/avid-s3l/carbonio/leds"
+	"github.com/kward/avid-s3l/carbonio/signals"
+	"github.com/kward/avid-s3l/carbonio/spi"
+	"github.com/kward/golib/errors"
+	"google.golang.org/grpc/codes"
+)
+
+// Address roots.
+const (
+	InputRoot  = "input"
+	OutputRoot = "output"
+	LEDRoot    = "led"
+	AVBRoot    = "avb"
+	ExtRoot    = "ext"
+)
+
+// Target is a single, concrete property of a device.
+type Target struct {
+	// Address of the property, e.g. `input/mic/1/gain`.
+	Address Address
+	// Property describes the value of the target.
+	Property *Property
+	// Label is a human readable name of the node, e.g. `Mic input #1`.
+	Label string
+
+	impl spi.Implementation
+	get  func() (interface{}, error)
+	set  func(interface{}) error
+}
+
+// NewTarget returns a target that reads and writes its value using the
+// provided functions. The `set` function may be nil for read-only targets.
+func NewTarget(a Address, p *Property, label string, get func() (interface{}, error), set func(interface{}) error) *Target {
+	return &Target{
+		Address:  a,
+		Property: p,
+		Label:    label,
+		get:      get,
+		set:      set,
+	}
+}
+
+// Node returns the address of the signal or LED that the target belongs to,
+// e.g. `input/mic/1`.
+func (t *Target) Node() Address { return t.Address.Parent() }
+
+// Implementation returns the SPI implementation behind the target, or nil if
+// the target is not backed by a local SPI device.
+func (t *Target) Implementation() spi.Implementation { return t.impl }
+
+// Raw returns the most recent raw SPI value, if available.
+func (t *Target) Raw() []byte {
+	if t.impl == nil {
+		return nil
+	}
+	return t.impl.Raw()
+}
+
+// Value reads the current value of the target.
+func (t *Target) Value() (interface{}, error) {
+	v, err := t.get()
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s; %s", t.Address, err)
+	}
+	return v, nil
+}
+
+// SetValue validates and writes a new value to the target.
+func (t *Target) SetValue(v interface{}) error {
+	if !t.Property.Editable || t.set == nil {
+		return errors.Errorf(codes.PermissionDenied, "%s is read-only", t.Address)
+	}
+	n, err := t.Property.Normalize(v)
+	if err != nil {
+		return err
+	}
+	if err := t.set(n); err != nil {
+		return fmt.Errorf("error writing %s; %s", t.Address, err)
+	}
+	return nil
+}
+
+// String implements fmt.Stringer.
+func (t *Target) String() string { return t.Address.String() }
+
+// Node groups the targets of a single signal or LED.
+type Node struct {
+	// Address of the node, e.g. `input/mic/1`.
+	Address Address
+	// Label is a human readable name of the node.
+	Label   string
+	Targets []*Target
+}
+
+// Nodes groups targets by their node, preserving the target order.
+func Nodes(ts []*Target) []*Node {
+	ns := []*Node{}
+	idx := map[string]*Node{}
+	for _, t := range ts {
+		key := t.Node().String()
+		n, ok := idx[key]
+		if !ok {
+			n = &Node{Address: t.Node(), Label: t.Label}
+			idx[key] = n
+			ns = append(ns, n)
+		}
+		n.Targets = append(n.Targets, t)
+	}
+	return ns
+}
+
+// Target returns the target of the named property, or nil.
+func (n *Node) Target(property string) *Target {
+	for _, t := range n.Targets {
+		if t.Property.Name == property {
+			return t
+		}
+	}
+	return nil
+}
+
+// Resolver resolves addresses into concrete targets.
+type Resolver interface {
+	// Resolve returns the targets matched by the address, in address order.
+	Resolve(a Address) ([]*Target, error)
+}
+
+// ResolveString parses and resolves an address string.
+func ResolveString(r Resolver, s string) ([]*Target, error) {
+	a, err := Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	return r.Resolve(a)
+}
+
+// Filter returns the targets matched by the address.
+func Filter(ts []*Target, a Address) ([]*Target, error) {
+	switch a.Segment(0) {
+	case InputRoot, LEDRoot, Wildcard:
+	case OutputRoot, AVBRoot, ExtRoot:
+		return nil, errors.Errorf(codes.Unimplemented, "%s addresses are not supported yet", a.Segment(0))
+	default:
+		return nil, errors.Errorf(codes.InvalidArgument, "unknown address root %q; want one of %s", a.Segment(0),
+			strings.Join([]string{InputRoot, OutputRoot, LEDRoot, AVBRoot, ExtRoot}, ", "))
+	}
+
+	matched := []*Target{}
+	for _, t := range ts {
+		if a.Match(t.Address) {
+			matched = append(matched, t)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, errors.Errorf(codes.NotFound, "no targets match %s", a)
+	}
+	return matched, nil
+}
+
+// deviceResolver resolves addresses against a local device.
+type deviceResolver struct {
+	device devices.Device
+}
+
+// Verify that the interface is implemented properly.
+var _ Resolver = new(deviceResolver)
+
+// NewDeviceResolver returns a resolver for a local device.
+func NewDeviceResolver(d devices.Device) Resolver {
+	return &deviceResolver{device: d}
+}
+
+// Resolve implements Resolver.
+func (r *deviceResolver) Resolve(a Address) ([]*Target, error) {
+	return Filter(Targets(r.device), a)
+}
+
+// Resolve the address against a local device.
+func Resolve(d devices.Device, a Address) ([]*Target, error) {
+	return NewDeviceResolver(d).Resolve(a)
+}
+
+// Targets returns all the targets of a device, in address order.
+func Targets(d devices.Device) []*Target {
+	ts := []*Target{}
+	for i := 1; i <= d.NumMicInputs(); i++ {
+		s, err := d.MicInput(i)
+		if err != nil {
+			continue
+		}
+		ts = append(ts, signalTargets(MustParse(fmt.Sprintf("%s/mic/%d", InputRoot, i)), s)...)
+	}
+	for _, l := range []struct {
+		name string
+		led  *leds.LED
+	}{
+		{"power", d.LEDs().Power()},
+		{"status", d.LEDs().Status()},
+		{"mute", d.LEDs().Mute()},
+	} {
+		ts = append(ts, ledTarget(MustParse(LEDRoot+Separator+l.name), l.led))
+	}
+	return ts
+}
+
+func signalTargets(node Address, s *signals.Signal) []*Target {
+	join := func(p *Property) Address {
+		a, _ := node.Join(p.Name)
+		return a
+	}
+	return []*Target{
+		{
+			Address:  join(GainProperty),
+			Property: GainProperty,
+			Label:    s.Name(),
+			impl:     s.Gain(),
+			get: func() (interface{}, error) {
+				v, err := s.Gain().Value()
+				return int(v), err
+			},
+			set: func(v interface{}) error { return s.Gain().SetValue(uint(v.(int))) },
+		},
+		{
+			Address:  join(PadProperty),
+			Property: PadProperty,
+			Label:    s.Name(),
+			impl:     s.Pad(),
+			get:      func() (interface{}, error) { return s.Pad().IsEnabled() },
+			set: func(v interface{}) error {
+				if v.(bool) {
+					return s.Pad().Enable()
+				}
+				return s.Pad().Disable()
+			},
+		},
+		{
+			Address:  join(PhantomProperty),
+			Property: PhantomProperty,
+			Label:    s.Name(),
+			impl:     s.Phantom(),
+			get:      func() (interface{}, error) { return s.Phantom().IsEnabled() },
+			set: func(v interface{}) error {
+				if v.(bool) {
+					return s.Phantom().Enable()
+				}
+				return s.Phantom().Disable()
+			},
+		},
+	}
+}
+
+// StateProperty is the name of the LED state property.
+const StateProperty = "state"
+
+func ledTarget(node Address, l *leds.LED) *Target {
+	p := &Property{Name: StateProperty, Kind: Enum, Editable: true}
+	byName := map[string]leds.State{}
+	for _, s := range l.States() {
+		p.States = append(p.States, s.String())
+		byName[s.String()] = s
+	}
+	a, _ := node.Join(StateProperty)
+	return &Target{
+		Address:  a,
+		Property: p,
+		Label:    strings.Title(node.Base()) + " LED",
+		impl:     l,
+		get: func() (interface{}, error) {
+			s, err := l.State()
+			if err != nil {
+				return nil, err
+			}
+			return s.String(), nil
+		},
+		set: func(v interface{}) error { return l.SetState(byName[v.(string)]) },
+	}
+}
@@ -0,0 +1,123 @@
+package address
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/kward/avid-s3l/carbonio/devices"
+	"github.com/kward/avid-s3l/carbonio/devices/devicestest"
+)
+
+func newDevice(t *testing.T) (devices.Device, func()) {
+	dir, err := ioutil.TempDir("", "address")
+	if err != nil {
+		t.Fatalf("error creating temp dir; %s", err)
+	}
+	d, err := devicestest.NewStage16(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("error instantiating Stage16; %s", err)
+	}
+	return d, func() { os.RemoveAll(dir) }
+}
+
+func TestResolve(t *testing.T) {
+	d, cleanup := newDevice(t)
+	defer cleanup()
+
+	for _, tc := range []struct {
+		desc string
+		ok   bool
+
+		addr  string
+		count int
+		first string
+	}{
+		// Valid addresses.
+		{"all", true, "*", 16*3 + 3, "input/mic/1/gain"},
+		{"inputs", true, "input", 16 * 3, "input/mic/1/gain"},
+		{"input", true, "input/mic/3", 3, "input/mic/3/gain"},
+		{"input property", true, "input/mic/3/pad", 1, "input/mic/3/pad"},
+		{"range", true, "input/mic/1-8", 8 * 3, "input/mic/1/gain"},
+		{"wildcard", true, "input/mic/*/phantom", 16, "input/mic/1/phantom"},
+		{"leds", true, "led", 3, "led/power/state"},
+		{"led", true, "led/status", 1, "led/status/state"},
+
+		// Invalid addresses.
+		{desc: "out of range", addr: "input/mic/17"},
+		{desc: "unknown property", addr: "input/mic/1/volume"},
+		{desc: "unknown root", addr: "bogus/1"},
+		{desc: "unsupported root", addr: "avb/1234/hostname"},
+	} {
+		t.Run(fmt.Sprintf("Resolve() %s", tc.desc), func(t *testing.T) {
+			ts, err := Resolve(d, MustParse(tc.addr))
+			if err != nil && tc.ok {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			if err == nil && !tc.ok {
+				t.Fatal("expected an error")
+			}
+			if !tc.ok {
+				return
+			}
+			if got, want := len(ts), tc.count; got != want {
+				t.Fatalf("len() = %d, want %d", got, want)
+			}
+			if got, want := ts[0].Address.String(), tc.first; got != want {
+				t.Errorf("[0] = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestTarget_SetValue(t *testing.T) {
+	d, cleanup := newDevice(t)
+	defer cleanup()
+
+	for _, tc := range []struct {
+		desc string
+		ok   bool
+
+		addr  string
+		value interface{}
+	}{
+		// Valid values.
+		{"gain", true, "input/mic/2/gain", 42},
+		{"gain from json", true, "input/mic/2/gain", float64(33)},
+		{"pad", true, "input/mic/2/pad", true},
+		{"phantom", true, "input/mic/2/phantom", true},
+		{"led", true, "led/status/state", "Alert"},
+
+		// Invalid values.
+		{desc: "gain out of range", addr: "input/mic/2/gain", value: 99},
+		{desc: "pad wrong type", addr: "input/mic/2/pad", value: 1.5},
+		{desc: "led unknown state", addr: "led/mute/state", value: "Alert"},
+	} {
+		t.Run(fmt.Sprintf("SetValue() %s", tc.desc), func(t *testing.T) {
+			ts, err := Resolve(d, MustParse(tc.addr))
+			if err != nil {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			err = ts[0].SetValue(tc.value)
+			if err != nil && tc.ok {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			if err == nil && !tc.ok {
+				t.Fatal("expected an error")
+			}
+			if !tc.ok {
+				return
+			}
+			v, err := ts[0].Value()
+			if err != nil {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			want, _ := ts[0].Property.Normalize(tc.value)
+			if got := v; got != want {
+				t.Errorf("Value() = %v, want %v", got, want)
+			}
+		})
+	}
+}
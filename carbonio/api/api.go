@@ -0,0 +1,194 @@
+/*
+Package api defines the versioned, machine-readable representation of carbonio
+device state. The same schema is used for the JSON, YAML, and CSV output of the
+command-line, and for the HTTP responses of the server.
+
+Values are typed: gains are numbers, pads and phantoms are booleans, and LED
+states are strings. Fields are only ever added to a schema version; any other
+change requires a new version.
+*/
+package api
+
+// Version of the schema.
+const Version = 1
+
+// Kinds of documents.
+const (
+	ListKind   = "List"
+	StatusKind = "Status"
+)
+
+// List of signals or LEDs.
+type List struct {
+	Version int     `json:"version" yaml:"version"`
+	Kind    string  `json:"kind" yaml:"kind"`
+	Items   []*Item `json:"items" yaml:"items"`
+}
+
+// NewList returns an empty list of the given kind.
+func NewList(kind string) *List {
+	return &List{
+		Version: Version,
+		Kind:    kind,
+		Items:   []*Item{},
+	}
+}
+
+// Item describes a single signal or LED.
+type Item struct {
+	// Address of the signal or LED, e.g. `input/mic/1`.
+	Address string `json:"address" yaml:"address"`
+	// Type of the item, i.e. the address root (e.g. `input` or `led`).
+	Type string `json:"type" yaml:"type"`
+	// Label is a human readable name, e.g. `Mic input #1`.
+	Label string `json:"label" yaml:"label"`
+	// Properties holds the typed values by property name.
+	Properties map[string]interface{} `json:"properties" yaml:"properties"`
+	// Errors holds the read errors by property name.
+	Errors map[string]string `json:"errors,omitempty" yaml:"errors,omitempty"`
+}
+
+// ValuesKind is the kind of a Values document.
+const ValuesKind = "Values"
+
+// Values holds typed values by target address, e.g. `input/mic/1/gain: 30`.
+type Values struct {
+	Version int                    `json:"version" yaml:"version"`
+	Kind    string                 `json:"kind" yaml:"kind"`
+	Values  map[string]interface{} `json:"values" yaml:"values"`
+}
+
+// NewValues returns an empty Values document.
+func NewValues() *Values {
+	return &Values{
+		Version: Version,
+		Kind:    ValuesKind,
+		Values:  map[string]interface{}{},
+	}
+}
+
+// ChangesKind is the kind of a Changes document.
+const ChangesKind = "Changes"
+
+// Changes describes the result of writing values.
+type Changes struct {
+	Version int       `json:"version" yaml:"version"`
+	Kind    string    `json:"kind" yaml:"kind"`
+	Items   []*Change `json:"items" yaml:"items"`
+	// Error describes why the changes failed, if they did.
+	Error *ErrorDetail `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// NewChanges returns an empty Changes document.
+func NewChanges() *Changes {
+	return &Changes{
+		Version: Version,
+		Kind:    ChangesKind,
+		Items:   []*Change{},
+	}
+}
+
+// Change of the value of a single target.
+type Change struct {
+	Address string      `json:"address" yaml:"address"`
+	From    interface{} `json:"from" yaml:"from"`
+	To      interface{} `json:"to" yaml:"to"`
+	// Status is one of `ok`, `dry-run`, `unchanged`, `reverted`, `skipped`, or
+	// `FAILED`.
+	Status string `json:"status" yaml:"status"`
+}
+
+// Error is the body of an error response.
+type Error struct {
+	Error *ErrorDetail `json:"error" yaml:"error"`
+}
+
+// ErrorDetail describes an error.
+type ErrorDetail struct {
+	// Code is the name of the gRPC code of the error, e.g. `NotFound`.
+	Code    string `json:"code" yaml:"code"`
+	Message string `json:"message" yaml:"message"`
+}
+
+// ProblemContentType is the content type of a Problem.
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem document, the body of an error response to
+// clients that accept `application/problem+json`.
+type Problem struct {
+	// Type is a URI of the type of problem; `about:blank` when it is only
+	// described by the status.
+	Type   string `json:"type" yaml:"type"`
+	Title  string `json:"title" yaml:"title"`
+	Status int    `json:"status" yaml:"status"`
+	Detail string `json:"detail,omitempty" yaml:"detail,omitempty"`
+	// Instance is the path of the request.
+	Instance string `json:"instance,omitempty" yaml:"instance,omitempty"`
+	// Code is the name of the gRPC code of the error, e.g. `NotFound`.
+	Code string `json:"code" yaml:"code"`
+}
+
+// TargetsKind is the kind of a Targets document.
+const TargetsKind = "Targets"
+
+// Targets describes the addressable values of a device.
+type Targets struct {
+	Version int       `json:"version" yaml:"version"`
+	Kind    string    `json:"kind" yaml:"kind"`
+	Items   []*Target `json:"items" yaml:"items"`
+}
+
+// NewTargets returns an empty Targets document.
+func NewTargets() *Targets {
+	return &Targets{
+		Version: Version,
+		Kind:    TargetsKind,
+		Items:   []*Target{},
+	}
+}
+
+// Target describes a single addressable value, e.g. `input/mic/1/gain`.
+type Target struct {
+	Address  string      `json:"address" yaml:"address"`
+	Label    string      `json:"label" yaml:"label"`
+	Property *Property   `json:"property" yaml:"property"`
+	Value    interface{} `json:"value" yaml:"value"`
+}
+
+// Property describes the value of a target.
+type Property struct {
+	Name string `json:"name" yaml:"name"`
+	// Kind is one of `bool`, `int`, or `enum`.
+	Kind     string   `json:"kind" yaml:"kind"`
+	Unit     string   `json:"unit,omitempty" yaml:"unit,omitempty"`
+	Min      int      `json:"min,omitempty" yaml:"min,omitempty"`
+	Max      int      `json:"max,omitempty" yaml:"max,omitempty"`
+	States   []string `json:"states,omitempty" yaml:"states,omitempty"`
+	Editable bool     `json:"editable" yaml:"editable"`
+}
+
+// Kinds of resource documents.
+const (
+	DeviceKind  = "Device"
+	SignalsKind = "Signals"
+	LEDsKind    = "LEDs"
+)
+
+// Device describes the carbonio device.
+type Device struct {
+	Version int    `json:"version" yaml:"version"`
+	Kind    string `json:"kind" yaml:"kind"`
+	// IP address of the device.
+	IP string `json:"ip" yaml:"ip"`
+	// MicInputs is the number of mic inputs.
+	MicInputs int `json:"mic_inputs" yaml:"mic_inputs"`
+	// LEDs holds the names of the LEDs, e.g. `power`.
+	LEDs []string `json:"leds" yaml:"leds"`
+	// Links holds the paths of the related resources by name.
+	Links map[string]string `json:"links" yaml:"links"`
+}
+
+// Value is the body of a parameter change.
+type Value struct {
+	Value interface{} `json:"value" yaml:"value"`
+}
@@ -0,0 +1,320 @@
+/*
+Package auth provides authentication and roles for the carbonio servers.
+
+Clients authenticate with a static token, sent as an `Authorization: Bearer`
+header, or with HTTP basic auth. Event streams also accept the token as an
+`access_token` query parameter, as the browser APIs of WebSockets and
+server-sent events cannot set headers (see AuthenticateStream). The tokens and
+users are read from a YAML credentials file:
+
+	# Role of unauthenticated requests, if any.
+	anonymous: viewer
+	tokens:
+	  - name: foh-tablet
+	    token: 7c0d4bb2e5a14f52
+	    role: operator
+	users:
+	  - name: admin
+	    # Plain text, or the bcrypt hash of the password as `bcrypt:<hash>`.
+	    password: bcrypt:$2a$10$hw83G/GPDNUP5ObQUsD2u.KCvqC3avHmrdZw0HELe4O8H9FPlUMfa
+	    role: admin
+*/
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/kward/avid-s3l/carbonio/address"
+	"github.com/kward/golib/errors"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Role of a client. Each role may do everything that the lower roles may.
+type Role int
+
+const (
+	// None may do nothing.
+	None Role = iota
+	// Viewer may read the device state.
+	Viewer
+	// Operator may also change gains and pads.
+	Operator
+	// Admin may also change phantom power and LEDs, and manage snapshots.
+	Admin
+)
+
+var roleNames = []string{"none", "viewer", "operator", "admin"}
+
+// String implements fmt.Stringer.
+func (r Role) String() string {
+	if r < None || int(r) >= len(roleNames) {
+		return fmt.Sprintf("Role(%d)", int(r))
+	}
+	return roleNames[r]
+}
+
+// ParseRole parses the name of a role, e.g. `operator`.
+func ParseRole(name string) (Role, error) {
+	for i, n := range roleNames {
+		if strings.EqualFold(name, n) {
+			return Role(i), nil
+		}
+	}
+	return None, errors.Errorf(codes.InvalidArgument, "unknown role %q; want %s", name, strings.Join(roleNames[1:], ", "))
+}
+
+// ChangeRole returns the role required to change the target. Phantom power and
+// LEDs require an admin, as they affect the equipment, and everything else an
+// operator.
+func ChangeRole(t *address.Target) Role {
+	if t.Address.Segment(0) == address.LEDRoot || t.Property.Name == address.PhantomProperty.Name {
+		return Admin
+	}
+	return Operator
+}
+
+// Principal is an authenticated client.
+type Principal struct {
+	Name string
+	Role Role
+}
+
+// Anonymous is the name of unauthenticated principals.
+const Anonymous = "anonymous"
+
+// PasswordPrefix is the prefix of hashed passwords.
+const PasswordPrefix = "bcrypt:"
+
+// HashPassword returns the salted bcrypt hash of a password, for the
+// credentials file.
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return PasswordPrefix + string(hash), nil
+}
+
+// file is the format of the credentials file.
+type file struct {
+	Anonymous string `yaml:"anonymous"`
+	Tokens    []struct {
+		Name  string `yaml:"name"`
+		Token string `yaml:"token"`
+		Role  string `yaml:"role"`
+	} `yaml:"tokens"`
+	Users []struct {
+		Name     string `yaml:"name"`
+		Password string `yaml:"password"`
+		Role     string `yaml:"role"`
+	} `yaml:"users"`
+}
+
+type credential struct {
+	secret    []byte // Token, or password hash.
+	principal *Principal
+}
+
+// Authenticator authenticates HTTP requests.
+type Authenticator struct {
+	anonymous Role
+	tokens    []*credential
+	users     map[string]*credential
+	// unknown is a hash to compare the passwords of unknown users with, so that
+	// users cannot be told apart by the time taken.
+	unknown []byte
+
+	mu sync.Mutex
+	// verified holds the SHA-256 of the last verified password of each user, so
+	// that bcrypt is not run on every request of browsers.
+	verified map[string][sha256.Size]byte
+}
+
+// Load the credentials file.
+func Load(path string) (*Authenticator, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading credentials; %s", err)
+	}
+	a, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing credentials %s; %s", path, err)
+	}
+	return a, nil
+}
+
+// Parse the contents of a credentials file.
+func Parse(data []byte) (*Authenticator, error) {
+	f := &file{}
+	if err := yaml.UnmarshalStrict(data, f); err != nil {
+		return nil, err
+	}
+	a := &Authenticator{users: map[string]*credential{}, verified: map[string][sha256.Size]byte{}}
+	if f.Anonymous != "" {
+		r, err := ParseRole(f.Anonymous)
+		if err != nil {
+			return nil, err
+		}
+		a.anonymous = r
+	}
+
+	seen := map[string]bool{}
+	for _, t := range f.Tokens {
+		if t.Name == "" || t.Token == "" {
+			return nil, fmt.Errorf("tokens need a name and a token")
+		}
+		if seen[t.Token] {
+			return nil, fmt.Errorf("token of %s is not unique", t.Name)
+		}
+		seen[t.Token] = true
+		r, err := parseRole(t.Name, t.Role)
+		if err != nil {
+			return nil, err
+		}
+		a.tokens = append(a.tokens, &credential{[]byte(t.Token), &Principal{t.Name, r}})
+	}
+	for _, u := range f.Users {
+		if u.Name == "" || u.Password == "" {
+			return nil, fmt.Errorf("users need a name and a password")
+		}
+		if _, ok := a.users[u.Name]; ok {
+			return nil, fmt.Errorf("user %s is given more than once", u.Name)
+		}
+		r, err := parseRole(u.Name, u.Role)
+		if err != nil {
+			return nil, err
+		}
+		hash, err := passwordHash(u.Name, u.Password)
+		if err != nil {
+			return nil, err
+		}
+		a.users[u.Name] = &credential{hash, &Principal{u.Name, r}}
+	}
+	if len(a.users) > 0 {
+		hash, err := bcrypt.GenerateFromPassword([]byte(Anonymous), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		a.unknown = hash
+	}
+	return a, nil
+}
+
+// passwordHash returns the bcrypt hash of the password of a user, which is
+// hashed if it is given in plain text.
+func passwordHash(name, password string) ([]byte, error) {
+	if strings.HasPrefix(password, PasswordPrefix) {
+		hash := []byte(strings.TrimPrefix(password, PasswordPrefix))
+		if _, err := bcrypt.Cost(hash); err != nil {
+			return nil, fmt.Errorf("invalid password hash of %s; %s", name, err)
+		}
+		return hash, nil
+	}
+	if strings.HasPrefix(password, "sha256:") {
+		return nil, fmt.Errorf("password of %s is an unsalted sha256 hash; use a %s hash", name, PasswordPrefix)
+	}
+	return bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+}
+
+// verify returns whether the password is that of the user.
+func (a *Authenticator) verify(name, password string) bool {
+	c, ok := a.users[name]
+	if !ok {
+		bcrypt.CompareHashAndPassword(a.unknown, []byte(password))
+		return false
+	}
+	sum := sha256.Sum256([]byte(password))
+	a.mu.Lock()
+	last, ok := a.verified[name]
+	a.mu.Unlock()
+	if ok && subtle.ConstantTimeCompare(last[:], sum[:]) == 1 {
+		return true
+	}
+	if bcrypt.CompareHashAndPassword(c.secret, []byte(password)) != nil {
+		return false
+	}
+	a.mu.Lock()
+	a.verified[name] = sum
+	a.mu.Unlock()
+	return true
+}
+
+// parseRole parses the role of a token or user, which must be given.
+func parseRole(name, role string) (Role, error) {
+	r, err := ParseRole(role)
+	if err != nil {
+		return None, fmt.Errorf("%s; %s", name, errors.ErrorDesc(err))
+	}
+	if r == None {
+		return None, fmt.Errorf("%s has no role", name)
+	}
+	return r, nil
+}
+
+// TokenParameter is the query parameter of tokens.
+const TokenParameter = "access_token"
+
+// AnonymousRole returns the role of unauthenticated clients, e.g. of protocols
+// without credentials.
+func (a *Authenticator) AnonymousRole() Role { return a.anonymous }
+
+// Authenticate returns the principal of the request. Requests without
+// credentials are anonymous, if anonymous access is allowed.
+func (a *Authenticator) Authenticate(r *http.Request) (*Principal, error) {
+	return a.authenticate(r, "")
+}
+
+// AuthenticateStream is Authenticate for the requests of event streams, whose
+// token may also be given by the TokenParameter query parameter.
+func (a *Authenticator) AuthenticateStream(r *http.Request) (*Principal, error) {
+	return a.authenticate(r, r.URL.Query().Get(TokenParameter))
+}
+
+// authenticate the request, of the query token, if any.
+func (a *Authenticator) authenticate(r *http.Request, token string) (*Principal, error) {
+	if h := r.Header.Get("Authorization"); strings.HasPrefix(h, "Bearer ") {
+		token = strings.TrimPrefix(h, "Bearer ")
+	}
+	if token != "" {
+		for _, c := range a.tokens {
+			if subtle.ConstantTimeCompare(c.secret, []byte(token)) == 1 {
+				return c.principal, nil
+			}
+		}
+		return nil, errors.Errorf(codes.Unauthenticated, "invalid token")
+	}
+
+	if name, password, ok := r.BasicAuth(); ok {
+		if !a.verify(name, password) {
+			return nil, errors.Errorf(codes.Unauthenticated, "invalid user or password")
+		}
+		return a.users[name].principal, nil
+	}
+
+	if a.anonymous == None {
+		return nil, errors.Errorf(codes.Unauthenticated, "authentication required")
+	}
+	return &Principal{Anonymous, a.anonymous}, nil
+}
+
+type contextKey struct{}
+
+// NewContext returns a context holding the principal.
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, p)
+}
+
+// FromContext returns the principal of the context, if any.
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(contextKey{}).(*Principal)
+	return p, ok
+}
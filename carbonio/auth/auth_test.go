@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kward/golib/errors"
+	"google.golang.org/grpc/codes"
+)
+
+const creds = `
+anonymous: viewer
+tokens:
+  - name: foh-tablet
+    token: s3cr3t
+    role: operator
+users:
+  - name: admin
+    password: bcrypt:$2a$10$hw83G/GPDNUP5ObQUsD2u.KCvqC3avHmrdZw0HELe4O8H9FPlUMfa
+    role: admin
+  - name: monitor
+    password: plain
+    role: viewer
+`
+
+func TestParseRole(t *testing.T) {
+	for _, tc := range []struct {
+		desc string
+		name string
+		role Role
+		ok   bool
+	}{
+		{"viewer", "viewer", Viewer, true},
+		{"mixed case", "Operator", Operator, true},
+		{"admin", "admin", Admin, true},
+		{"unknown", "root", None, false},
+	} {
+		t.Run(fmt.Sprintf("ParseRole() %s", tc.desc), func(t *testing.T) {
+			r, err := ParseRole(tc.name)
+			if err != nil && tc.ok {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			if err == nil && !tc.ok {
+				t.Fatalf("expected an error")
+			}
+			if r != tc.role {
+				t.Errorf("= %s, want %s", r, tc.role)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, tc := range []struct {
+		desc string
+		data string
+		ok   bool
+	}{
+		{"valid", creds, true},
+		{"empty", "", true},
+		{"unknown field", "bogus: true", false},
+		{"unknown role", "tokens: [{name: a, token: b, role: root}]", false},
+		{"missing role", "tokens: [{name: a, token: b}]", false},
+		{"missing token", "tokens: [{name: a, role: admin}]", false},
+		{"duplicate token", "tokens: [{name: a, token: b, role: admin}, {name: c, token: b, role: viewer}]", false},
+		{"duplicate user", "users: [{name: a, password: b, role: admin}, {name: a, password: c, role: admin}]", false},
+		{"invalid hash", "users: [{name: a, password: 'bcrypt:nope', role: admin}]", false},
+		{"sha256 hash", "users: [{name: a, password: 'sha256:8c6976e5b5410415bde908bd4dee15dfb167a9c873fc4bb8a81f6f2ab448a918', role: admin}]", false},
+	} {
+		t.Run(fmt.Sprintf("Parse() %s", tc.desc), func(t *testing.T) {
+			_, err := Parse([]byte(tc.data))
+			if err != nil && tc.ok {
+				t.Errorf("unexpected error; %s", err)
+			}
+			if err == nil && !tc.ok {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
+
+func TestAuthenticate(t *testing.T) {
+	a, err := Parse([]byte(creds))
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	closed, err := Parse([]byte("tokens: [{name: a, token: b, role: admin}]"))
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+
+	for _, tc := range []struct {
+		desc     string
+		a        *Authenticator
+		url      string
+		header   string
+		user, pw string
+		name     string
+		role     Role
+		code     codes.Code
+	}{
+		{"bearer token", a, "/", "Bearer s3cr3t", "", "", "foh-tablet", Operator, codes.OK},
+		{"query token", a, "/?access_token=s3cr3t", "", "", "", Anonymous, Viewer, codes.OK},
+		{"query token not allowed", closed, "/?access_token=b", "", "", "", "", None, codes.Unauthenticated},
+		{"invalid token", a, "/", "Bearer nope", "", "", "", None, codes.Unauthenticated},
+		{"hashed password", a, "/", "", "admin", "admin", "admin", Admin, codes.OK},
+		{"plain password", a, "/", "", "monitor", "plain", "monitor", Viewer, codes.OK},
+		{"invalid password", a, "/", "", "admin", "nope", "", None, codes.Unauthenticated},
+		{"verified password", a, "/", "", "admin", "admin", "admin", Admin, codes.OK},
+		{"unknown user", a, "/", "", "nobody", "admin", "", None, codes.Unauthenticated},
+		{"anonymous", a, "/", "", "", "", Anonymous, Viewer, codes.OK},
+		{"anonymous not allowed", closed, "/", "", "", "", "", None, codes.Unauthenticated},
+	} {
+		t.Run(fmt.Sprintf("Authenticate() %s", tc.desc), func(t *testing.T) {
+			r := httptest.NewRequest("GET", tc.url, nil)
+			if tc.header != "" {
+				r.Header.Set("Authorization", tc.header)
+			}
+			if tc.user != "" {
+				r.SetBasicAuth(tc.user, tc.pw)
+			}
+			p, err := tc.a.Authenticate(r)
+			if got := errors.Code(err); got != tc.code {
+				t.Fatalf("code = %s, want %s; %v", got, tc.code, err)
+			}
+			if err != nil {
+				return
+			}
+			if p.Name != tc.name || p.Role != tc.role {
+				t.Errorf("= %s (%s), want %s (%s)", p.Name, p.Role, tc.name, tc.role)
+			}
+		})
+	}
+}
+
+func TestAuthenticateStream(t *testing.T) {
+	a, err := Parse([]byte(creds))
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	for _, tc := range []struct {
+		desc   string
+		url    string
+		header string
+		name   string
+		code   codes.Code
+	}{
+		{"query token", "/ws?access_token=s3cr3t", "", "foh-tablet", codes.OK},
+		{"invalid query token", "/ws?access_token=nope", "", "", codes.Unauthenticated},
+		{"header token", "/ws?access_token=nope", "Bearer s3cr3t", "foh-tablet", codes.OK},
+		{"anonymous", "/ws", "", Anonymous, codes.OK},
+	} {
+		t.Run(fmt.Sprintf("AuthenticateStream() %s", tc.desc), func(t *testing.T) {
+			r := httptest.NewRequest("GET", tc.url, nil)
+			if tc.header != "" {
+				r.Header.Set("Authorization", tc.header)
+			}
+			p, err := a.AuthenticateStream(r)
+			if got := errors.Code(err); got != tc.code {
+				t.Fatalf("code = %s, want %s; %v", got, tc.code, err)
+			}
+			if err == nil && p.Name != tc.name {
+				t.Errorf("= %s, want %s", p.Name, tc.name)
+			}
+		})
+	}
+}
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("s3cr3t")
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	if !strings.HasPrefix(hash, PasswordPrefix) {
+		t.Errorf("HashPassword() = %s, want a %s prefix", hash, PasswordPrefix)
+	}
+	if again, _ := HashPassword("s3cr3t"); again == hash {
+		t.Errorf("HashPassword() = %s twice, want salted hashes", hash)
+	}
+	a, err := Parse([]byte(fmt.Sprintf("users: [{name: a, password: '%s', role: admin}]", hash)))
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	r := httptest.NewRequest("GET", "/", nil)
+	r.SetBasicAuth("a", "s3cr3t")
+	if _, err := a.Authenticate(r); err != nil {
+		t.Errorf("unexpected error; %s", err)
+	}
+}
+
+func TestContext(t *testing.T) {
+	if _, ok := FromContext(context.Background()); ok {
+		t.Errorf("FromContext() of an empty context is ok")
+	}
+	p := &Principal{"admin", Admin}
+	if got, ok := FromContext(NewContext(context.Background(), p)); !ok || got != p {
+		t.Errorf("FromContext() = %v, %t; want %v", got, ok, p)
+	}
+}
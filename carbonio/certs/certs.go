@@ -0,0 +1,183 @@
+/*
+Package certs provides the TLS certificates of the carbonio servers.
+
+A server uses either a provided certificate and key, or a self-signed
+certificate that is generated on first start, and persisted so that clients can
+pin it. The self-signed certificate is regenerated when it expires, or when the
+hosts of the device (e.g. its IP) change.
+*/
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// CertFile and KeyFile are the names of the self-signed certificate and
+	// key in the certificate directory.
+	CertFile = "cert.pem"
+	KeyFile  = "key.pem"
+
+	// validity of self-signed certificates.
+	validity = 10 * 365 * 24 * time.Hour
+	// renewBefore is how long before expiry a certificate is regenerated.
+	renewBefore = 30 * 24 * time.Hour
+)
+
+// DefaultDir returns the default certificate directory.
+func DefaultDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "carbonio", "tls")
+	}
+	return filepath.Join(home, ".carbonio", "tls")
+}
+
+// Load the certificate and key from PEM files.
+func Load(certFile, keyFile string) (tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("error loading certificate; %s", err)
+	}
+	return cert, nil
+}
+
+// SelfSigned returns the self-signed certificate for the hosts (IPs or
+// hostnames) from the directory. A new certificate is generated and saved if
+// there is none, or if it does not cover the hosts or is about to expire.
+func SelfSigned(dir string, hosts []string) (tls.Certificate, error) {
+	certFile, keyFile := filepath.Join(dir, CertFile), filepath.Join(dir, KeyFile)
+	if cert, err := tls.LoadX509KeyPair(certFile, keyFile); err == nil && valid(cert, hosts) {
+		return cert, nil
+	}
+
+	certPEM, keyPEM, err := generate(hosts)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return tls.Certificate{}, fmt.Errorf("error creating certificate dir; %s", err)
+	}
+	if err := ioutil.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		return tls.Certificate{}, fmt.Errorf("error writing key; %s", err)
+	}
+	if err := ioutil.WriteFile(certFile, certPEM, 0644); err != nil {
+		return tls.Certificate{}, fmt.Errorf("error writing certificate; %s", err)
+	}
+	return Load(certFile, keyFile)
+}
+
+// valid returns true if the certificate covers the hosts, and does not expire
+// soon.
+func valid(cert tls.Certificate, hosts []string) bool {
+	c, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return false
+	}
+	if time.Now().Add(renewBefore).After(c.NotAfter) {
+		return false
+	}
+	for _, h := range hosts {
+		if err := c.VerifyHostname(strings.Trim(h, "[]")); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// generate a self-signed certificate and key for the hosts, PEM encoded.
+func generate(hosts []string) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error generating key; %s", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, fmt.Errorf("error generating serial number; %s", err)
+	}
+
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{Organization: []string{"carbonio"}},
+		NotBefore:             now.Add(-time.Hour), // Allow for clock skew.
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true, // So that clients can trust it as a root.
+	}
+	for _, h := range hosts {
+		h = strings.Trim(h, "[]")
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+	if len(hosts) > 0 {
+		tmpl.Subject.CommonName = strings.Trim(hosts[0], "[]")
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error creating certificate; %s", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error marshaling key; %s", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), nil
+}
+
+// Fingerprint returns the SHA-256 fingerprint of the certificate, e.g.
+// `AB:CD:...`, so that users can verify it.
+func Fingerprint(cert tls.Certificate) string {
+	sum := sha256.Sum256(cert.Certificate[0])
+	hexes := []string{}
+	for _, b := range sum {
+		hexes = append(hexes, strings.ToUpper(hex.EncodeToString([]byte{b})))
+	}
+	return strings.Join(hexes, ":")
+}
+
+// Hosts returns the hosts of the device: its IP, and the hostname of the
+// machine, also as an mDNS `.local` name.
+func Hosts(ip net.IP) []string {
+	hosts := []string{}
+	if ip != nil {
+		hosts = append(hosts, ip.String())
+	}
+	if name, err := os.Hostname(); err == nil && name != "" {
+		hosts = append(hosts, name)
+		if !strings.Contains(name, ".") {
+			hosts = append(hosts, name+".local")
+		}
+	}
+	return append(hosts, "localhost")
+}
+
+// Config returns a TLS server configuration with the certificate.
+func Config(cert tls.Certificate) *tls.Config {
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+}
@@ -0,0 +1,73 @@
+package certs
+
+import (
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSelfSigned(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certs")
+	if err != nil {
+		t.Fatalf("error creating temp dir; %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	hosts := []string{"fe80::1", "stagebox", "stagebox.local"}
+	cert, err := SelfSigned(dir, hosts)
+	if err != nil {
+		t.Fatalf("SelfSigned() unexpected error; %s", err)
+	}
+	fi, err := os.Stat(filepath.Join(dir, KeyFile))
+	if err != nil {
+		t.Fatalf("key was not saved; %s", err)
+	}
+	if got, want := fi.Mode().Perm(), os.FileMode(0600); got != want {
+		t.Errorf("key mode = %s, want %s", got, want)
+	}
+
+	// The certificate verifies for all the hosts, when pinned as a root.
+	c, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("error parsing certificate; %s", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(c)
+	for _, h := range hosts {
+		if _, err := c.Verify(x509.VerifyOptions{DNSName: h, Roots: roots}); err != nil {
+			t.Errorf("Verify(%s) unexpected error; %s", h, err)
+		}
+	}
+
+	for _, tc := range []struct {
+		desc  string
+		hosts []string
+		same  bool
+	}{
+		{"same hosts", hosts, true},
+		{"subset of hosts", hosts[:1], true},
+		{"new host", append(hosts, "10.0.0.5"), false},
+	} {
+		t.Run(fmt.Sprintf("SelfSigned() %s", tc.desc), func(t *testing.T) {
+			again, err := SelfSigned(dir, tc.hosts)
+			if err != nil {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			if got := Fingerprint(again) == Fingerprint(cert); got != tc.same {
+				t.Errorf("reused certificate = %t, want %t", got, tc.same)
+			}
+			cert = again
+		})
+	}
+}
+
+func TestHosts(t *testing.T) {
+	hosts := Hosts(net.ParseIP("fe80::1"))
+	if len(hosts) < 2 || hosts[0] != "fe80::1" || hosts[len(hosts)-1] != "localhost" {
+		t.Errorf("Hosts() = %v, want the IP first and localhost last", hosts)
+	}
+}
@@ -0,0 +1,193 @@
+/*
+Package changes applies sets of value changes to a device as a single
+transaction.
+
+Changes are applied in a phantom-safe order: phantoms are disabled before any
+pad or gain is changed, and enabled only after all pads and gains have been
+changed. This prevents pops from reaching the speakers when the preamps are
+reconfigured. Every write is verified with a read-after-write. If any change
+fails, the changes that were already applied are reverted in reverse order.
+*/
+package changes
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/kward/avid-s3l/carbonio/address"
+	"github.com/kward/golib/errors"
+	"google.golang.org/grpc/codes"
+)
+
+// Change of the value of a single target.
+type Change struct {
+	Target   *address.Target
+	From, To interface{}
+}
+
+// String implements fmt.Stringer.
+func (c *Change) String() string {
+	p := c.Target.Property
+	return fmt.Sprintf("%s %s -> %s", c.Target.Address, p.Format(c.From), p.Format(c.To))
+}
+
+// New returns the change of the target to the value `to`, reading the current
+// value of the target. The value is validated against the target property.
+func New(t *address.Target, to interface{}) (*Change, error) {
+	if !t.Property.Editable {
+		return nil, errors.Errorf(codes.PermissionDenied, "%s is read-only", t.Address)
+	}
+	v, err := t.Property.Normalize(to)
+	if err != nil {
+		return nil, errors.Errorf(errors.Code(err), "%s; %s", t.Address, errors.ErrorDesc(err))
+	}
+	from, err := t.Value()
+	if err != nil {
+		return nil, err
+	}
+	return &Change{Target: t, From: from, To: v}, nil
+}
+
+// Diff returns the changes that are required to bring the targets to the
+// wanted values, keyed by target address. Targets without a wanted value, or
+// that already have the wanted value, are skipped.
+func Diff(ts []*address.Target, want map[string]interface{}) ([]*Change, error) {
+	cs := []*Change{}
+	for _, t := range ts {
+		v, ok := want[t.Address.String()]
+		if !ok || !t.Property.Editable {
+			continue
+		}
+		c, err := New(t, v)
+		if err != nil {
+			return nil, err
+		}
+		if c.From == c.To {
+			continue
+		}
+		cs = append(cs, c)
+	}
+	return cs, nil
+}
+
+// phase of a change within the phantom-safe ordering.
+func phase(c *Change) int {
+	switch c.Target.Property.Name {
+	case address.PhantomProperty.Name:
+		if on, _ := c.To.(bool); on {
+			return 2 // Enable phantoms last.
+		}
+		return 0 // Disable phantoms first.
+	}
+	return 1
+}
+
+// Order returns the changes in the phantom-safe order. Within a phase, the
+// order of the changes is preserved.
+func Order(cs []*Change) []*Change {
+	ordered := append([]*Change{}, cs...)
+	sort.SliceStable(ordered, func(i, j int) bool { return phase(ordered[i]) < phase(ordered[j]) })
+	return ordered
+}
+
+// mu serializes transactions, so that concurrent writers (e.g. the HTTP and OSC
+// servers) do not interleave their changes.
+var mu sync.Mutex
+
+// draining is set once the writes have been drained. Guarded by mu.
+var draining bool
+
+// Drain waits for the transaction in progress, if any, to complete. Later
+// transactions fail with codes.Unavailable. Drain is used on shutdown, so that
+// no transaction is cut short.
+func Drain(ctx context.Context) error {
+	drained := make(chan struct{})
+	go func() {
+		mu.Lock()
+		draining = true
+		mu.Unlock()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+		return nil
+	case <-ctx.Done():
+		return errors.Errorf(codes.DeadlineExceeded, "timed out draining writes")
+	}
+}
+
+// Error describes a failed transaction.
+type Error struct {
+	// Failed is the change that failed.
+	Failed *Change
+	// Err is the error of the failed change.
+	Err error
+	// Reverted holds the changes that were reverted.
+	Reverted []*Change
+	// RevertErrs holds the errors of changes that could not be reverted.
+	RevertErrs []error
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	str := fmt.Sprintf("change of %s failed; %s", e.Failed.Target.Address, e.Err)
+	if len(e.Reverted) > 0 {
+		str += fmt.Sprintf("; reverted %d changes", len(e.Reverted))
+	}
+	if len(e.RevertErrs) > 0 {
+		strs := []string{}
+		for _, err := range e.RevertErrs {
+			strs = append(strs, err.Error())
+		}
+		str += fmt.Sprintf("; %d changes could not be reverted: %s", len(e.RevertErrs), strings.Join(strs, "; "))
+	}
+	return str
+}
+
+// Apply the changes as a single transaction, in the phantom-safe order. The
+// applied changes are returned in the order they were applied. On failure, the
+// applied changes are reverted and an *Error is returned.
+func Apply(cs []*Change) ([]*Change, error) {
+	mu.Lock()
+	defer mu.Unlock()
+	if draining {
+		return nil, errors.Errorf(codes.Unavailable, "shutting down; no more changes are accepted")
+	}
+
+	applied := []*Change{}
+	for _, c := range Order(cs) {
+		if err := write(c.Target, c.To); err != nil {
+			e := &Error{Failed: c, Err: err}
+			for i := len(applied) - 1; i >= 0; i-- {
+				a := applied[i]
+				if err := write(a.Target, a.From); err != nil {
+					e.RevertErrs = append(e.RevertErrs, err)
+					continue
+				}
+				e.Reverted = append(e.Reverted, a)
+			}
+			return nil, e
+		}
+		applied = append(applied, c)
+	}
+	return applied, nil
+}
+
+// write the value, and verify it with a read-after-write.
+func write(t *address.Target, v interface{}) error {
+	if err := t.SetValue(v); err != nil {
+		return err
+	}
+	got, err := t.Value()
+	if err != nil {
+		return fmt.Errorf("read-after-write of %s failed; %s", t.Address, err)
+	}
+	if got != v {
+		p := t.Property
+		return fmt.Errorf("read-after-write mismatch for %s; got %s, want %s", t.Address, p.Format(got), p.Format(v))
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package changes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kward/avid-s3l/carbonio/address"
+	"github.com/kward/golib/errors"
+	"google.golang.org/grpc/codes"
+)
+
+// memTarget returns a target that stores its value in memory, and records the
+// order of writes in `log`. Writes of the `fail` value fail.
+func memTarget(addr string, p *address.Property, v interface{}, fail interface{}, log *[]string) *address.Target {
+	a := address.MustParse(addr)
+	return address.NewTarget(a, p, addr,
+		func() (interface{}, error) { return v, nil },
+		func(n interface{}) error {
+			if fail != nil && n == fail {
+				return fmt.Errorf("write of %v failed", n)
+			}
+			*log = append(*log, fmt.Sprintf("%s=%v", a.Base(), n))
+			v = n
+			return nil
+		})
+}
+
+func TestOrder(t *testing.T) {
+	log := []string{}
+	gain := memTarget("input/mic/1/gain", address.GainProperty, 10, nil, &log)
+	on := memTarget("input/mic/1/phantom", address.PhantomProperty, false, nil, &log)
+	off := memTarget("input/mic/2/phantom", address.PhantomProperty, true, nil, &log)
+	pad := memTarget("input/mic/2/pad", address.PadProperty, false, nil, &log)
+
+	cs := []*Change{
+		{Target: on, From: false, To: true},
+		{Target: gain, From: 10, To: 40},
+		{Target: off, From: true, To: false},
+		{Target: pad, From: false, To: true},
+	}
+	got := []string{}
+	for _, c := range Order(cs) {
+		got = append(got, c.Target.Address.String())
+	}
+	want := []string{"input/mic/2/phantom", "input/mic/1/gain", "input/mic/2/pad", "input/mic/1/phantom"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("Order() = %v, want %v", got, want)
+	}
+}
+
+func TestApply(t *testing.T) {
+	for _, tc := range []struct {
+		desc string
+		ok   bool
+
+		fail     interface{} // Gain value that fails to write.
+		log      string      // Writes, in order.
+		reverted int
+	}{
+		{"phantom safe order", true, nil, "phantom=false pad=true gain=40 phantom=true", 0},
+		{"revert on failure", false, 40, "phantom=false pad=true pad=false phantom=true", 2},
+	} {
+		t.Run(fmt.Sprintf("Apply() %s", tc.desc), func(t *testing.T) {
+			log := []string{}
+			cs := []*Change{
+				{Target: memTarget("input/mic/1/pad", address.PadProperty, false, nil, &log), From: false, To: true},
+				{Target: memTarget("input/mic/2/phantom", address.PhantomProperty, false, nil, &log), From: false, To: true},
+				{Target: memTarget("input/mic/3/phantom", address.PhantomProperty, true, nil, &log), From: true, To: false},
+				{Target: memTarget("input/mic/1/gain", address.GainProperty, 10, tc.fail, &log), From: 10, To: 40},
+			}
+			_, err := Apply(cs)
+			if err != nil && tc.ok {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			if err == nil && !tc.ok {
+				t.Fatal("expected an error")
+			}
+			if err != nil {
+				e, ok := err.(*Error)
+				if !ok {
+					t.Fatalf("error is %T, want *Error", err)
+				}
+				if got, want := len(e.Reverted), tc.reverted; got != want {
+					t.Errorf("reverted %d changes, want %d", got, want)
+				}
+			}
+			if got, want := strings.Join(log, " "), tc.log; got != want {
+				t.Errorf("writes = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestDiff(t *testing.T) {
+	log := []string{}
+	ts := []*address.Target{
+		memTarget("input/mic/1/gain", address.GainProperty, 10, nil, &log),
+		memTarget("input/mic/2/gain", address.GainProperty, 20, nil, &log),
+		memTarget("input/mic/3/gain", address.GainProperty, 30, nil, &log),
+	}
+	cs, err := Diff(ts, map[string]interface{}{
+		"input/mic/1/gain": float64(10), // Unchanged, as decoded from JSON.
+		"input/mic/2/gain": float64(40),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	if got, want := fmt.Sprint(cs), "[input/mic/2/gain 20 -> 40]"; got != want {
+		t.Errorf("Diff() = %s, want %s", got, want)
+	}
+
+	if _, err := Diff(ts, map[string]interface{}{"input/mic/1/gain": 99}); err == nil {
+		t.Error("Diff() expected an error for an out of range value")
+	}
+}
+
+func TestDrain(t *testing.T) {
+	defer func() {
+		mu.Lock()
+		draining = false
+		mu.Unlock()
+	}()
+
+	// Drain waits for the transaction in progress.
+	mu.Lock()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := Drain(ctx); errors.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("Drain() during a transaction = %v, want %s", err, codes.DeadlineExceeded)
+	}
+	mu.Unlock()
+	if err := Drain(context.Background()); err != nil {
+		t.Fatalf("Drain() unexpected error; %s", err)
+	}
+
+	log := []string{}
+	cs := []*Change{{Target: memTarget("input/mic/1/gain", address.GainProperty, 10, nil, &log), From: 10, To: 20}}
+	if _, err := Apply(cs); errors.Code(err) != codes.Unavailable {
+		t.Errorf("Apply() after Drain() = %v, want %s", err, codes.Unavailable)
+	}
+	if len(log) != 0 {
+		t.Errorf("Apply() after Drain() wrote %v", log)
+	}
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"fmt"
+	"time"
+)
+
+const defaultTimeout = 10 * time.Second
+
+type options struct {
+	timeout time.Duration
+	token   string
+	caCert  string
+}
+
+// Timeout returns the timeout of requests.
+func Timeout(v time.Duration) func(*options) error {
+	return func(o *options) error { return o.setTimeout(v) }
+}
+func (o *options) setTimeout(v time.Duration) error {
+	if v < 0 {
+		return fmt.Errorf("invalid timeout %s", v)
+	}
+	o.timeout = v
+	return nil
+}
+
+// Token returns the bearer token that authenticates requests. Defaults to the
+// value of the TokenEnv environment variable.
+func Token(v string) func(*options) error {
+	return func(o *options) error { return o.setToken(v) }
+}
+func (o *options) setToken(v string) error {
+	o.token = v
+	return nil
+}
+
+// CACert returns the PEM file of the certificates that HTTPS servers are
+// verified with, e.g. the self-signed certificate of a server. Defaults to the
+// value of the CACertEnv environment variable, or else the system roots.
+func CACert(v string) func(*options) error {
+	return func(o *options) error { return o.setCACert(v) }
+}
+func (o *options) setCACert(v string) error {
+	o.caCert = v
+	return nil
+}
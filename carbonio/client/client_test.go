@@ -0,0 +1,121 @@
+package client
+
+import (
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseHost(t *testing.T) {
+	for _, tc := range []struct {
+		host string
+		ok   bool
+		url  string
+	}{
+		{"stagebox", true, "http://stagebox:8080"},
+		{"stagebox:8081", true, "http://stagebox:8081"},
+		{"10.0.0.5", true, "http://10.0.0.5:8080"},
+		{"fe80::1", true, "http://[fe80::1]:8080"},
+		{"[fe80::1]:9000", true, "http://[fe80::1]:9000"},
+		{"https://stagebox:8443/", true, "https://stagebox:8443"},
+		{"", false, ""},
+		{"ftp://stagebox", false, ""},
+	} {
+		t.Run(fmt.Sprintf("parseHost(%q)", tc.host), func(t *testing.T) {
+			u, err := parseHost(tc.host)
+			if err != nil && tc.ok {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			if err == nil && !tc.ok {
+				t.Fatal("expected an error")
+			}
+			if !tc.ok {
+				return
+			}
+			if got, want := u.String(), tc.url; got != want {
+				t.Errorf("= %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestCredentials(t *testing.T) {
+	got := map[string]string{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got[r.URL.Query().Get("case")] = r.Header.Get("Authorization")
+		w.Write([]byte(`{"values": {}}`))
+	}))
+	defer srv.Close()
+
+	u := strings.Replace(srv.URL, "http://", "http://admin:pw@", 1)
+	for _, tc := range []struct {
+		desc string
+		host string
+		opts []func(*options) error
+		want string
+	}{
+		{"none", srv.URL, nil, ""},
+		{"token", srv.URL, []func(*options) error{Token("s3cr3t")}, "Bearer s3cr3t"},
+		{"basic", u, nil, "Basic YWRtaW46cHc="},
+	} {
+		t.Run(fmt.Sprintf("New() %s", tc.desc), func(t *testing.T) {
+			// Ignore the token of the environment.
+			c, err := New(tc.host, append([]func(*options) error{Token("")}, tc.opts...)...)
+			if err != nil {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			if strings.Contains(c.String()+c.Host(), "pw") {
+				t.Errorf("password is not redacted from %s, %s", c, c.Host())
+			}
+			if err := c.do(http.MethodGet, "/", url.Values{"case": {tc.desc}}, nil, nil); err != nil {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			if got[tc.desc] != tc.want {
+				t.Errorf("Authorization = %q, want %q", got[tc.desc], tc.want)
+			}
+		})
+	}
+}
+
+func TestCACert(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"values": {}}`))
+	}))
+	defer srv.Close()
+	f, err := ioutil.TempFile("", "ca")
+	if err != nil {
+		t.Fatalf("error creating temp file; %s", err)
+	}
+	defer os.Remove(f.Name())
+	pem.Encode(f, &pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw})
+	f.Close()
+
+	for _, tc := range []struct {
+		desc   string
+		caCert string
+		ok     bool
+	}{
+		{"pinned", f.Name(), true},
+		{"system roots", "", false},
+	} {
+		t.Run(fmt.Sprintf("New() %s", tc.desc), func(t *testing.T) {
+			c, err := New(srv.URL, CACert(tc.caCert))
+			if err != nil {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			_, err = c.Values([]string{"*"}, nil)
+			if err != nil && tc.ok {
+				t.Errorf("unexpected error; %s", err)
+			}
+			if err == nil && !tc.ok {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+package client
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/kward/avid-s3l/carbonio/address"
+	"github.com/kward/avid-s3l/carbonio/api"
+	"github.com/kward/golib/errors"
+)
+
+// resolver resolves addresses against a remote server.
+type resolver struct {
+	c *Client
+}
+
+// Verify that the interface is implemented properly.
+var _ address.Resolver = new(resolver)
+
+// Resolver returns a resolver of addresses against the server. The targets
+// hold the values that were current when the address was resolved; after a
+// target is written, its value is read from the server again.
+func (c *Client) Resolver() address.Resolver { return &resolver{c: c} }
+
+// Resolve implements address.Resolver.
+func (r *resolver) Resolve(a address.Address) ([]*address.Target, error) {
+	q := url.Values{}
+	q.Set("address", a.String())
+	doc := &api.Targets{}
+	if err := r.c.do(http.MethodGet, "/api/v1/targets", q, nil, doc); err != nil {
+		return nil, err
+	}
+	ts := []*address.Target{}
+	for _, item := range doc.Items {
+		t, err := r.target(item)
+		if err != nil {
+			return nil, err
+		}
+		ts = append(ts, t)
+	}
+	return ts, nil
+}
+
+// target returns a target backed by the server.
+func (r *resolver) target(item *api.Target) (*address.Target, error) {
+	a, err := address.Parse(item.Address)
+	if err != nil {
+		return nil, err
+	}
+	k, err := address.ParseKind(item.Property.Kind)
+	if err != nil {
+		return nil, err
+	}
+	p := &address.Property{
+		Name:     item.Property.Name,
+		Kind:     k,
+		Unit:     item.Property.Unit,
+		Min:      item.Property.Min,
+		Max:      item.Property.Max,
+		States:   item.Property.States,
+		Editable: item.Property.Editable,
+	}
+	v, err := p.Normalize(item.Value)
+	if err != nil {
+		return nil, errors.Errorf(errors.Code(err), "%s; %s", a, errors.ErrorDesc(err))
+	}
+
+	var mu sync.Mutex
+	stale := false
+	get := func() (interface{}, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if !stale {
+			return v, nil
+		}
+		vs, err := r.c.Values([]string{a.String()}, nil)
+		if err != nil {
+			return nil, err
+		}
+		if v, err = p.Normalize(vs[a.String()]); err != nil {
+			return nil, err
+		}
+		stale = false
+		return v, nil
+	}
+	set := func(n interface{}) error {
+		mu.Lock()
+		defer mu.Unlock()
+		stale = true
+		_, err := r.c.SetValues(map[string]interface{}{a.String(): n}, false)
+		return err
+	}
+	return address.NewTarget(a, p, item.Label, get, set), nil
+}
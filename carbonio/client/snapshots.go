@@ -0,0 +1,58 @@
+package client
+
+import (
+	"net/http"
+	"net/url"
+
+	"github.com/kward/avid-s3l/carbonio/snapshots"
+)
+
+// snapshotStore stores snapshots on a remote server.
+type snapshotStore struct {
+	c *Client
+}
+
+// Verify that the interface is implemented properly.
+var _ snapshots.Store = new(snapshotStore)
+
+// Snapshots returns the snapshot store of the server.
+func (c *Client) Snapshots() snapshots.Store { return &snapshotStore{c: c} }
+
+func snapshotPath(name string) string { return "/api/v1/snapshots/" + url.PathEscape(name) }
+
+// List implements snapshots.Store.
+func (s *snapshotStore) List() ([]*snapshots.Snapshot, error) {
+	doc := &snapshots.List{}
+	if err := s.c.do(http.MethodGet, "/api/v1/snapshots", nil, nil, doc); err != nil {
+		return nil, err
+	}
+	return doc.Items, nil
+}
+
+// Load implements snapshots.Store.
+func (s *snapshotStore) Load(name string) (*snapshots.Snapshot, error) {
+	if err := snapshots.ValidName(name); err != nil {
+		return nil, err
+	}
+	snap := &snapshots.Snapshot{}
+	if err := s.c.do(http.MethodGet, snapshotPath(name), nil, nil, snap); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+// Save implements snapshots.Store.
+func (s *snapshotStore) Save(snap *snapshots.Snapshot) error {
+	if err := snapshots.ValidName(snap.Name); err != nil {
+		return err
+	}
+	return s.c.do(http.MethodPut, snapshotPath(snap.Name), nil, snap, &snapshots.Snapshot{})
+}
+
+// Delete implements snapshots.Store.
+func (s *snapshotStore) Delete(name string) error {
+	if err := snapshots.ValidName(name); err != nil {
+		return err
+	}
+	return s.c.do(http.MethodDelete, snapshotPath(name), nil, nil, nil)
+}
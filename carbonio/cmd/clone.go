@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"github.com/kward/avid-s3l/carbonio/handlers"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cloneCmd = &cobra.Command{
+		Use:   "clone [-o property]... [-t type]... @source @destination...",
+		Short: "clone settings between carbonio servers",
+		Long: `Clone copies the settings of the source carbonio server to each of the
+destination servers. Hosts are given as @host[:port]. The differences are shown,
+and must be confirmed before each destination is changed. Without types, all
+inputs are cloned. For example:
+
+  carbonio clone @stagebox-a @stagebox-b
+  carbonio clone -o gain -o pad @stagebox-a:8080 @stagebox-b @stagebox-c`,
+		Args:        cobra.MinimumNArgs(2),
+		Annotations: map[string]string{remoteAnnotation: "true"},
+		Run:         clone,
+	}
+	cloneTypes []string
+	cloneProps []string
+	cloneYes   bool
+)
+
+func init() {
+	rootCmd.AddCommand(cloneCmd)
+	cloneCmd.Flags().StringSliceVarP(&cloneTypes, "type", "t", nil, "types to clone (inputs, outputs, leds)")
+	cloneCmd.Flags().StringSliceVarP(&cloneProps, "property", "o", nil, "properties to clone")
+	cloneCmd.Flags().BoolVarP(&cloneYes, "yes", "y", false, "change the destinations without confirmation")
+}
+
+func clone(cmd *cobra.Command, args []string) {
+	h, err := handlers.NewClientHandlers(
+		handlers.DryRun(dryRun),
+		handlers.Types(cloneTypes),
+		handlers.Properties(cloneProps),
+		handlers.AssumeYes(cloneYes))
+	if err != nil {
+		exit(err)
+	}
+	if err := h.CloneCommand(cmd.OutOrStdout(), cmd.InOrStdin(), args); err != nil {
+		exit(err)
+	}
+}
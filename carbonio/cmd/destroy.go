@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(&cobra.Command{
+		Use:         "destroy @name",
+		Annotations: hostAnnotations,
+		Short:       "delete a snapshot",
+		Args:        cobra.ExactArgs(1),
+		Run:         destroy,
+	})
+}
+
+func destroy(cmd *cobra.Command, args []string) {
+	if err := snapHandlers().DestroyCommand(cmd.OutOrStdout(), args[0]); err != nil {
+		exit(err)
+	}
+}
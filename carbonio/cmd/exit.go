@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/kward/golib/errors"
+	"google.golang.org/grpc/codes"
+)
+
+// Exit codes of the commands.
+const (
+	exitError       = 1 // An unclassified error.
+	exitUsage       = 2 // An invalid argument or flag.
+	exitNotFound    = 3 // No target or snapshot matched.
+	exitDenied      = 4 // Authentication or authorization failed.
+	exitUnavailable = 5 // The device or the remote server is unavailable.
+	exitConflict    = 6 // A change failed, or conflicted with the device state.
+)
+
+// exitCode returns the exit code of an error, by its code.
+func exitCode(err error) int {
+	switch errors.Code(err) {
+	case codes.InvalidArgument, codes.OutOfRange, codes.Unimplemented:
+		return exitUsage
+	case codes.NotFound:
+		return exitNotFound
+	case codes.Unauthenticated, codes.PermissionDenied:
+		return exitDenied
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return exitUnavailable
+	case codes.FailedPrecondition, codes.Aborted, codes.AlreadyExists:
+		return exitConflict
+	}
+	return exitError
+}
+
+// exit prints the error, and exits with its exit code.
+func exit(err error) {
+	fmt.Fprintf(os.Stderr, "error: %s\n", errors.ErrorDesc(err))
+	os.Exit(exitCode(err))
+}
+
+// exitf exits with an error of the code.
+func exitf(c codes.Code, format string, a ...interface{}) {
+	exit(errors.Errorf(c, format, a...))
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kward/golib/errors"
+	"google.golang.org/grpc/codes"
+)
+
+func TestExitCode(t *testing.T) {
+	for _, tc := range []struct {
+		err  error
+		code int
+	}{
+		{fmt.Errorf("uncoded"), exitError},
+		{errors.Errorf(codes.Internal, "internal"), exitError},
+		{errors.Errorf(codes.InvalidArgument, "usage"), exitUsage},
+		{errors.Errorf(codes.NotFound, "not found"), exitNotFound},
+		{errors.Errorf(codes.PermissionDenied, "denied"), exitDenied},
+		{errors.Errorf(codes.Unauthenticated, "unauthenticated"), exitDenied},
+		{errors.Errorf(codes.Unavailable, "unavailable"), exitUnavailable},
+		{errors.Errorf(codes.Aborted, "reverted"), exitConflict},
+	} {
+		if got, want := exitCode(tc.err), tc.code; got != want {
+			t.Errorf("exitCode(%s) = %d, want %d", tc.err, got, want)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"github.com/kward/avid-s3l/carbonio/handlers"
+	"github.com/spf13/cobra"
+)
+
+var (
+	getCmd = &cobra.Command{
+		Use:   "get [-o property]... address...",
+		Short: "get carbonio device values",
+		Long: `Get prints the current values of the signals and LEDs matched by the
+addresses. For example:
+
+  carbonio get input/mic/1/gain led/status
+  carbonio get -o gain -o pad input/mic/1-8`,
+		Annotations: hostAnnotations,
+		Args:        cobra.MinimumNArgs(1),
+		Run:         get,
+	}
+	getProps []string
+	getRaw   bool
+)
+
+func init() {
+	rootCmd.AddCommand(getCmd)
+	getCmd.Flags().StringArrayVarP(&getProps, "property", "o", nil, "property to get")
+	getCmd.Flags().BoolVarP(&getRaw, "raw", "r", false, "raw output")
+}
+
+func get(cmd *cobra.Command, args []string) {
+	h, err := handlers.NewHandlers(device,
+		handlers.Host(host),
+		handlers.Raw(getRaw))
+	if err != nil {
+		exit(err)
+	}
+	if err := h.GetCommand(cmd.OutOrStdout(), args, getProps); err != nil {
+		exit(err)
+	}
+}
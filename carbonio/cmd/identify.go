@@ -1,29 +1,37 @@
 package cmd
 
-import "github.com/spf13/cobra"
+import (
+	"time"
 
-func init() {
-	rootCmd.AddCommand(&cobra.Command{
+	"github.com/kward/avid-s3l/carbonio/handlers"
+	"github.com/spf13/cobra"
+)
+
+var (
+	identifyCmd = &cobra.Command{
 		Use:   "identify",
 		Short: "identify the carbionio device",
 		Long: `identify flashes the LEDs of the carbionio parent device, making it
 easier to recognize which device is being controlled.`,
-		Run: identify,
-	})
+		Annotations: hostAnnotations,
+		Run:         identify,
+	}
+	identifyDuration time.Duration
+)
+
+func init() {
+	rootCmd.AddCommand(identifyCmd)
+	identifyCmd.Flags().DurationVarP(&identifyDuration, "duration", "d", 5*time.Second, "how long to flash the LEDs")
 }
 
 func identify(cmd *cobra.Command, args []string) {
-	// // LEDs.
-	// ls := []*leds.LED{leds.Power, leds.Status, leds.Mute}
-	// for _, l := range ls {
-	//  fmt.Println(l)
-	// }
-	// fmt.Println("Toggling LEDs…")
-	// for _, l := range ls {
-	//  l.SetState(leds.Off)
-	// }
-	// time.Sleep(2 * time.Second)
-	// for _, l := range ls {
-	//  l.SetState(leds.On)
-	// }
+	h, err := handlers.NewHandlers(device,
+		handlers.Host(host),
+		handlers.DryRun(dryRun))
+	if err != nil {
+		exit(err)
+	}
+	if err := h.IdentifyCommand(cmd.OutOrStdout(), identifyDuration); err != nil {
+		exit(err)
+	}
 }
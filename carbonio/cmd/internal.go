@@ -5,9 +5,9 @@ import (
 	"os"
 	"path"
 
-	"github.com/kward/avid-s3l/carbonio/helpers"
 	"github.com/kward/avid-s3l/carbonio/spi"
 	"github.com/spf13/cobra"
+	"google.golang.org/grpc/codes"
 )
 
 var (
@@ -31,7 +31,7 @@ func init() {
 
 func internal_create_spi(cmd *cobra.Command, args []string) {
 	if spiBaseDir == spi.DevicesDir {
-		helpers.Exit(fmt.Sprintf("refusing to overwrite core SPI dir %s", spiBaseDir))
+		exitf(codes.InvalidArgument, "refusing to overwrite core SPI dir %s", spiBaseDir)
 	}
 
 	// Gather the SPI devices to create.
@@ -71,7 +71,7 @@ func internal_create_spi(cmd *cobra.Command, args []string) {
 		}
 		dir := path.Dir(dev.path())
 		if err := os.MkdirAll(dir, 0755); err != nil {
-			helpers.Exit(fmt.Sprintf("error creating directory %s; %v", dir, err))
+			exitf(codes.Internal, "error creating directory %s; %v", dir, err)
 		}
 		dev.init()
 	}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/kward/avid-s3l/carbonio/handlers"
+	"github.com/kward/avid-s3l/carbonio/layouts"
+	"github.com/spf13/cobra"
+)
+
+var (
+	layoutCmd = &cobra.Command{
+		Use:   "layout [-f format] [address]...",
+		Short: "generate a control surface layout for OSC apps",
+		Long: `Layout writes a control surface layout of the signals matched by the
+addresses, all inputs by default, for an OSC app. Each signal gets a strip
+labelled with its name, with a fader for its gain, and toggles for its pad and
+phantom power. For example:
+
+  carbonio layout -f touchosc > carbonio.tosc
+  carbonio layout -f open-stage-control input/mic/1-8 > carbonio.json`,
+		Annotations: hostAnnotations,
+		Run:         layout,
+	}
+	layoutFormat string
+)
+
+func init() {
+	rootCmd.AddCommand(layoutCmd)
+	layoutCmd.Flags().StringVarP(&layoutFormat, "format", "f", layouts.TouchOSC,
+		"layout format ("+strings.Join(layouts.Formats(), "|")+")")
+}
+
+func layout(cmd *cobra.Command, args []string) {
+	h, err := handlers.NewHandlers(device,
+		handlers.Host(host))
+	if err != nil {
+		exit(err)
+	}
+	if err := h.LayoutCommand(cmd.OutOrStdout(), layoutFormat, args); err != nil {
+		exit(err)
+	}
+}
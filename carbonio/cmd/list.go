@@ -1,33 +1,54 @@
 package cmd
 
 import (
-	"fmt"
+	"strings"
 
 	"github.com/kward/avid-s3l/carbonio/handlers"
-	"github.com/kward/avid-s3l/carbonio/helpers"
 	"github.com/spf13/cobra"
 )
 
 var (
 	listCmd = &cobra.Command{
-		Use:   "list",
+		Use:   "list [-t type]... [-o property]... [-s column] [address]...",
 		Short: "list the carbonio device settings",
-		Long:  `List prints the current settings of the carbonio device.`,
-		Run:   list,
+		Long: `List prints the current settings of the carbonio device. Without types or
+addresses, all inputs are listed. For example:
+
+  carbonio list -t leds
+  carbonio list -o gain -o phantom -s -gain input/mic/1-8`,
+		Annotations: hostAnnotations,
+		Run:         list,
 	}
-	listRaw bool
+	listRaw    bool
+	listTypes  []string
+	listProps  []string
+	listSort   string
+	listFormat string
 )
 
 func init() {
 	rootCmd.AddCommand(listCmd)
 	listCmd.Flags().BoolVarP(&listRaw, "raw", "r", false, "raw output")
+	listCmd.Flags().StringSliceVarP(&listTypes, "type", "t", nil, "types to list (inputs, outputs, leds)")
+	listCmd.Flags().StringSliceVarP(&listProps, "property", "o", nil, "properties to list")
+	listCmd.Flags().StringVarP(&listSort, "sort", "s", "", "column to sort by; prefix with '-' for descending order")
+	listCmd.Flags().StringVarP(&listFormat, "format", "f", handlers.TableFormat,
+		"output format ("+strings.Join(handlers.Formats(), "|")+")")
 }
 
 func list(cmd *cobra.Command, args []string) {
 	h, err := handlers.NewHandlers(device,
-		handlers.Raw(listRaw))
+		handlers.Host(host),
+		handlers.Raw(listRaw),
+		handlers.Types(listTypes),
+		handlers.Properties(listProps),
+		handlers.Addresses(args),
+		handlers.SortBy(listSort),
+		handlers.Format(listFormat))
 	if err != nil {
-		helpers.Exit(fmt.Sprintf("error instantiating handlers; %s", err))
+		exit(err)
+	}
+	if err := h.ListCommand(cmd.OutOrStdout()); err != nil {
+		exit(err)
 	}
-	h.ListCommand(cmd.OutOrStdout())
 }
@@ -0,0 +1,24 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(&cobra.Command{
+		Use:   "rollback @name",
+		Short: "roll the carbonio device back to a snapshot",
+		Long: `Rollback restores the values of a snapshot as a single transaction. Phantoms
+are switched off before pads and gains are changed, and switched on afterwards.
+If any write fails, the values that were already changed are restored.`,
+		Annotations: hostAnnotations,
+		Args:        cobra.ExactArgs(1),
+		Run:         rollback,
+	})
+}
+
+func rollback(cmd *cobra.Command, args []string) {
+	if err := snapHandlers().RollbackCommand(cmd.OutOrStdout(), args[0]); err != nil {
+		exit(err)
+	}
+}
@@ -6,15 +6,18 @@ import (
 	"path/filepath"
 
 	"github.com/kward/avid-s3l/carbonio/devices"
-	"github.com/kward/avid-s3l/carbonio/helpers"
+	"github.com/kward/avid-s3l/carbonio/snapshots"
 	"github.com/kward/avid-s3l/carbonio/spi"
 	"github.com/spf13/cobra"
+	"google.golang.org/grpc/codes"
 )
 
 var (
-	dryRun     bool
-	spiBaseDir string
-	verbose    bool
+	dryRun      bool
+	host        string
+	snapshotDir string
+	spiBaseDir  string
+	verbose     bool
 
 	device devices.Device
 
@@ -29,6 +32,18 @@ https://github.com/kward/avid-s3l`,
 	}
 )
 
+// Command annotations.
+const (
+	// remoteAnnotation marks commands that only talk to remote carbonio servers,
+	// and do not need a local device.
+	remoteAnnotation = "remote"
+	// hostAnnotation marks commands that support the --host flag.
+	hostAnnotation = "host"
+)
+
+// hostAnnotations are the annotations of commands that support --host.
+var hostAnnotations = map[string]string{hostAnnotation: "true"}
+
 const (
 	ifs = " "
 	ofs = " "
@@ -39,14 +54,18 @@ func Execute() {
 		&dryRun, "dry_run", "n", false, "perform a dry-run")
 	rootCmd.PersistentFlags().BoolVarP(
 		&verbose, "verbose", "v", false, "verbose output")
+	rootCmd.PersistentFlags().StringVarP(
+		&host, "host", "", "", "remote carbonio server, as host[:port]")
 
 	// TODO(2020-02-18) If this flag is overridden, validate a "version" file to
 	// ensure the structure is appropriate for testing.
 	rootCmd.PersistentFlags().StringVarP(
 		&spiBaseDir, "spi_base_dir", "", spi.DevicesDir, "spi base directory")
+	rootCmd.PersistentFlags().StringVarP(
+		&snapshotDir, "snapshot_dir", "", snapshots.DefaultDir(), "snapshot directory")
 
 	if err := rootCmd.Execute(); err != nil {
-		helpers.Exit(fmt.Sprintf("error: %v", err))
+		exitf(codes.InvalidArgument, "%v", err)
 	}
 }
 
@@ -56,6 +75,15 @@ func persistentPreRun(cmd *cobra.Command, args []string) {
 	if cmd.Use == "help" || cmd.Use == "help [command]" {
 		return
 	}
+	if _, ok := cmd.Annotations[remoteAnnotation]; ok {
+		return
+	}
+	if host != "" {
+		if _, ok := cmd.Annotations[hostAnnotation]; !ok {
+			exitf(codes.InvalidArgument, "the --host flag is not supported by %s", cmd.Name())
+		}
+		return // The remote server provides the device.
+	}
 
 	// Validate spi_base_dir.
 	if cmd.Use != "create_spi" { // We're going to create the dir.
@@ -66,7 +94,7 @@ func persistentPreRun(cmd *cobra.Command, args []string) {
 			return nil
 		})
 		if err != nil {
-			helpers.Exit(fmt.Sprintf("invalid --spi_base_dir flag value %s", spiBaseDir))
+			exitf(codes.InvalidArgument, "invalid --spi_base_dir flag value %s", spiBaseDir)
 		}
 	}
 
@@ -82,7 +110,7 @@ func persistentPreRun(cmd *cobra.Command, args []string) {
 		devices.Verbose(verbose),
 	)
 	if err != nil {
-		helpers.Exit(fmt.Sprintf("error configuring the Stage 16 device; %s", err))
+		exitf(codes.Unavailable, "error configuring the Stage 16 device; %s", err)
 	}
 }
 
@@ -1,28 +1,210 @@
 package cmd
 
 import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/kward/avid-s3l/carbonio/accesslog"
+	"github.com/kward/avid-s3l/carbonio/certs"
 	"github.com/kward/avid-s3l/carbonio/servers"
+	"github.com/kward/golib/errors"
 	"github.com/spf13/cobra"
+	"google.golang.org/grpc/codes"
 )
 
 var (
 	serverCmd = &cobra.Command{
 		Use:   "server",
 		Short: "start the carbonio HTTP and OSC servers",
-		Long:  `Server starts the carbonio HTTP and OSC servers.`,
-		Run:   server,
+		Long: `Server starts the carbonio HTTP and OSC servers.
+
+The servers listen on the link-local address of the device by default; HTTP on
+--http_port, and OSC on UDP and TCP --osc_port. --listen is repeatable, and
+takes addresses, interface names, "all" for all interfaces, or "link-local",
+each with an optional HTTP port, e.g.
+
+  --listen eth1 --listen 10.1.0.5:8080 --listen '[fe80::1%eth0]'
+
+--admin_listen adds loopback HTTP listeners, e.g. 127.0.0.1:8081, whose requests
+are given the admin role without authentication.
+
+With --tls, the HTTP server uses HTTPS. The certificate is either given with
+--tls_cert and --tls_key, or is self-signed for the IP and hostname of the
+device. A self-signed certificate is generated on first start, and kept in
+--tls_dir so that clients can pin it.
+
+On SIGINT or SIGTERM, the servers stop accepting requests, and the requests and
+writes in progress are completed before exiting. A second signal exits
+immediately.`,
+		Run: server,
 	}
 
-	httpPort int
-	oscPort  int
+	httpPort    int
+	listen      []string
+	adminListen []string
+	oscPort     int
+	authFile    string
+
+	accessLog       string
+	accessLogFormat string
+
+	useTLS  bool
+	tlsCert string
+	tlsKey  string
+	tlsDir  string
+
+	shutdownTimeout time.Duration
 )
 
 func init() {
 	rootCmd.AddCommand(serverCmd)
 	serverCmd.Flags().IntVarP(&httpPort, "http_port", "H", 8080, "http port")
-	serverCmd.Flags().IntVarP(&oscPort, "osc_port", "O", 41789, "osc port")
+	serverCmd.Flags().StringSliceVarP(&listen, "listen", "", []string{servers.LinkLocal}, "http and osc listen addresses")
+	serverCmd.Flags().StringSliceVarP(&adminListen, "admin_listen", "", nil,
+		"loopback http listen addresses without authentication")
+	serverCmd.Flags().IntVarP(&oscPort, "osc_port", "O", servers.DefaultOSCPort, "osc udp and tcp port")
+	serverCmd.Flags().StringVarP(&accessLog, "access_log", "", accesslog.Stderr,
+		`access log destination: "-" for stderr, "stdout", a file, or "off"`)
+	serverCmd.Flags().StringVarP(&accessLogFormat, "access_log_format", "", accesslog.Common,
+		"access log format: common, combined, or json")
+	serverCmd.Flags().StringVarP(&authFile, "auth_file", "", "", "credentials file; enables authentication")
+	serverCmd.Flags().BoolVarP(&useTLS, "tls", "", false, "serve HTTPS")
+	serverCmd.Flags().StringVarP(&tlsCert, "tls_cert", "", "", "certificate file; implies --tls")
+	serverCmd.Flags().StringVarP(&tlsKey, "tls_key", "", "", "key file of --tls_cert")
+	serverCmd.Flags().StringVarP(&tlsDir, "tls_dir", "", certs.DefaultDir(), "directory of the self-signed certificate")
+	serverCmd.Flags().DurationVarP(&shutdownTimeout, "shutdown_timeout", "", servers.DefaultShutdownTimeout,
+		"time that requests in progress are given to complete on shutdown")
 }
 
 func server(cmd *cobra.Command, args []string) {
-	servers.HttpServer(httpPort, device)
+	cfg, err := tlsConfig()
+	if err != nil {
+		exit(err)
+	}
+	logger, closeLog, err := accessLogger()
+	if err != nil {
+		exit(err)
+	}
+	defer closeLog()
+	h, err := servers.NewHTTP(device,
+		servers.Port(httpPort),
+		servers.Listen(listen),
+		servers.AdminListen(adminListen),
+		servers.SnapshotDir(snapshotDir),
+		servers.AuthFile(authFile),
+		servers.TLSConfig(cfg),
+		servers.AccessLog(logger))
+	if err != nil {
+		exit(err)
+	}
+	srvs := []servers.Server{h}
+	// OSC has no admin listeners, so it is only served on the --listen addresses,
+	// and always on --osc_port; the ports of the specs are those of HTTP.
+	if len(listen) > 0 {
+		o, err := servers.NewOSC(device,
+			servers.Port(oscPort),
+			servers.Listen(listen),
+			servers.AuthFile(authFile),
+			servers.Watcher(h.Watcher()))
+		if err != nil {
+			exit(err)
+		}
+		srvs = append(srvs, o)
+	}
+
+	// Shut down gracefully on the first signal, and immediately on the second.
+	ctx, cancel := context.WithCancel(context.Background())
+	sigs := make(chan os.Signal, 2)
+	signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		sig := <-sigs
+		log.Printf("received %s", sig)
+		cancel()
+		<-sigs
+		exitf(codes.Canceled, "forced shutdown")
+	}()
+
+	log.SetFlags(0)
+	sup := servers.NewSupervisor(shutdownTimeout, srvs...)
+	h.Health().Add("listeners", sup.Ready)
+	if err := sup.Run(ctx); err != nil {
+		exit(err)
+	}
+}
+
+// accessLogger returns the access logger of the flags, or nil if it is off.
+// The returned function closes the log.
+func accessLogger() (*accesslog.Logger, func() error, error) {
+	if accessLog == accesslog.Off {
+		return nil, func() error { return nil }, nil
+	}
+	w, closer, err := accesslog.Open(accessLog)
+	if err != nil {
+		return nil, nil, err
+	}
+	l, err := accesslog.New(w, accessLogFormat)
+	if err != nil {
+		closer()
+		return nil, nil, err
+	}
+	return l, closer, nil
+}
+
+// tlsConfig returns the TLS configuration of the flags, or nil without TLS.
+func tlsConfig() (*tls.Config, error) {
+	if (tlsCert == "") != (tlsKey == "") {
+		return nil, errors.Errorf(codes.InvalidArgument, "--tls_cert and --tls_key must be given together")
+	}
+	if tlsCert == "" && !useTLS {
+		return nil, nil
+	}
+
+	var cert tls.Certificate
+	var err error
+	if tlsCert != "" {
+		cert, err = certs.Load(tlsCert, tlsKey)
+	} else {
+		cert, err = certs.SelfSigned(tlsDir, tlsHosts())
+		if err == nil {
+			fmt.Printf("using the self-signed certificate %s/%s\n", tlsDir, certs.CertFile)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("certificate fingerprint (SHA-256) %s\n", certs.Fingerprint(cert))
+	return certs.Config(cert), nil
+}
+
+// tlsHosts returns the hosts of the self-signed certificate: those of the
+// device, and the IPs that are listened on.
+func tlsHosts() []string {
+	hosts := certs.Hosts(device.IP())
+	for _, spec := range listen {
+		addrs, err := servers.ResolveListen(spec, httpPort, device.IP())
+		if err != nil {
+			continue // Reported by the server.
+		}
+		for _, addr := range addrs {
+			host, _, err := net.SplitHostPort(addr)
+			if err != nil {
+				continue
+			}
+			if i := strings.LastIndex(host, "%"); i >= 0 {
+				host = host[:i] // Certificates do not have zones.
+			}
+			if ip := net.ParseIP(host); ip != nil && !ip.IsUnspecified() && !ip.Equal(device.IP()) {
+				hosts = append(hosts, ip.String())
+			}
+		}
+	}
+	return hosts
 }
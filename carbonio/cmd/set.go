@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"github.com/kward/avid-s3l/carbonio/handlers"
+	"github.com/spf13/cobra"
+)
+
+var (
+	setCmd = &cobra.Command{
+		Use:   "set [-o property=value]... address[=value]...",
+		Short: "set carbonio device values",
+		Long: `Set changes the values of the signals and LEDs matched by the addresses.
+Values are given either with the address, or as properties that are applied to
+each address. Every write is verified by reading the value back. For example:
+
+  carbonio set input/mic/1-4/gain=30 led/status=on
+  carbonio set -o gain=30 -o pad=off input/mic/1-8`,
+		Annotations: hostAnnotations,
+		Args:        cobra.MinimumNArgs(1),
+		Run:         set,
+	}
+	setProps []string
+)
+
+func init() {
+	rootCmd.AddCommand(setCmd)
+	setCmd.Flags().StringArrayVarP(&setProps, "property", "o", nil, "property=value to set")
+}
+
+func set(cmd *cobra.Command, args []string) {
+	h, err := handlers.NewHandlers(device,
+		handlers.Host(host),
+		handlers.DryRun(dryRun))
+	if err != nil {
+		exit(err)
+	}
+	if err := h.SetCommand(cmd.OutOrStdout(), args, setProps); err != nil {
+		exit(err)
+	}
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"github.com/kward/avid-s3l/carbonio/handlers"
+	"github.com/spf13/cobra"
+)
+
+var (
+	snapCmd = &cobra.Command{
+		Use:     "snap [-o property]... [address]...@name",
+		Aliases: []string{"snapshot"},
+		Short:   "snapshot the carbonio device state",
+		Long: `Snap saves the current values of the signals and LEDs matched by the
+addresses as a named snapshot. Without addresses, the whole device is
+snapshotted. An existing snapshot of the same name is replaced. For example:
+
+  carbonio snap @pre-show
+  carbonio snap -o gain input@gains`,
+		Annotations: hostAnnotations,
+		Args:        cobra.MinimumNArgs(1),
+		Run:         snap,
+	}
+	snapProps []string
+)
+
+func init() {
+	rootCmd.AddCommand(snapCmd)
+	snapCmd.Flags().StringArrayVarP(&snapProps, "property", "o", nil, "property to snapshot")
+
+	snapCmd.AddCommand(&cobra.Command{
+		Use:         "list",
+		Annotations: hostAnnotations,
+		Short:       "list the snapshots",
+		Args:        cobra.NoArgs,
+		Run:         snapList,
+	})
+	snapCmd.AddCommand(&cobra.Command{
+		Use:         "show @name",
+		Annotations: hostAnnotations,
+		Short:       "show the values of a snapshot",
+		Args:        cobra.ExactArgs(1),
+		Run:         snapShow,
+	})
+	snapCmd.AddCommand(&cobra.Command{
+		Use:         "diff @name",
+		Annotations: hostAnnotations,
+		Short:       "show the differences between the live values and a snapshot",
+		Args:        cobra.ExactArgs(1),
+		Run:         snapDiff,
+	})
+}
+
+// snapHandlers returns handlers configured for the snapshot commands.
+func snapHandlers() *handlers.Handlers {
+	h, err := handlers.NewHandlers(device,
+		handlers.Host(host),
+		handlers.DryRun(dryRun),
+		handlers.SnapshotDir(snapshotDir))
+	if err != nil {
+		exit(err)
+	}
+	return h
+}
+
+func snap(cmd *cobra.Command, args []string) {
+	if err := snapHandlers().SnapCommand(cmd.OutOrStdout(), args, snapProps); err != nil {
+		exit(err)
+	}
+}
+
+func snapList(cmd *cobra.Command, args []string) {
+	if err := snapHandlers().SnapListCommand(cmd.OutOrStdout()); err != nil {
+		exit(err)
+	}
+}
+
+func snapShow(cmd *cobra.Command, args []string) {
+	if err := snapHandlers().SnapShowCommand(cmd.OutOrStdout(), args[0]); err != nil {
+		exit(err)
+	}
+}
+
+func snapDiff(cmd *cobra.Command, args []string) {
+	if err := snapHandlers().SnapDiffCommand(cmd.OutOrStdout(), args[0]); err != nil {
+		exit(err)
+	}
+}
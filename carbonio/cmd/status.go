@@ -1,34 +1,41 @@
 package cmd
 
 import (
-	"fmt"
+	"strings"
 
 	"github.com/kward/avid-s3l/carbonio/handlers"
-	"github.com/kward/avid-s3l/carbonio/helpers"
 	"github.com/spf13/cobra"
 )
 
 var (
 	statusCmd = &cobra.Command{
-		Use:   "status",
-		Short: "device status",
-		Long:  `Provide status overview of the carbonio device.`,
-		Run:   status,
+		Use:         "status",
+		Short:       "device status",
+		Long:        `Provide status overview of the carbonio device.`,
+		Annotations: hostAnnotations,
+		Run:         status,
 	}
 
-	statusRaw bool
+	statusRaw    bool
+	statusFormat string
 )
 
 func init() {
 	rootCmd.AddCommand(statusCmd)
 	statusCmd.Flags().BoolVarP(&statusRaw, "raw", "r", false, "raw output")
+	statusCmd.Flags().StringVarP(&statusFormat, "format", "f", handlers.TableFormat,
+		"output format ("+strings.Join(handlers.Formats(), "|")+")")
 }
 
 func status(cmd *cobra.Command, args []string) {
 	h, err := handlers.NewHandlers(device,
-		handlers.Raw(listRaw))
+		handlers.Host(host),
+		handlers.Raw(statusRaw),
+		handlers.Format(statusFormat))
 	if err != nil {
-		helpers.Exit(fmt.Sprintf("error instantiating handlers; %s", err))
+		exit(err)
+	}
+	if err := h.StatusCommand(cmd.OutOrStdout()); err != nil {
+		exit(err)
 	}
-	h.StatusCommand(cmd.OutOrStdout())
 }
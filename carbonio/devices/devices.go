@@ -8,6 +8,7 @@ import (
 
 	"github.com/kward/avid-s3l/carbonio/leds"
 	"github.com/kward/avid-s3l/carbonio/signals"
+	"github.com/kward/avid-s3l/carbonio/spi"
 	"github.com/kward/golib/errors"
 	"google.golang.org/grpc/codes"
 )
@@ -23,6 +24,24 @@ type Device interface {
 	IP() net.IP
 }
 
+// Implementations returns the SPI implementations of all the LEDs and signals
+// of the device.
+func Implementations(d Device) []spi.Implementation {
+	impls := []spi.Implementation{
+		d.LEDs().Power(),
+		d.LEDs().Status(),
+		d.LEDs().Mute(),
+	}
+	for i := 1; i <= d.NumMicInputs(); i++ {
+		s, err := d.MicInput(i)
+		if err != nil {
+			continue
+		}
+		impls = append(impls, s.Gain(), s.Pad(), s.Phantom())
+	}
+	return impls
+}
+
 func setDeviceOptions(opts *options) error {
 	ip, err := LinkLocalIP()
 	if err != nil {
@@ -68,3 +87,25 @@ found:
 	}
 	return ip, nil
 }
+
+// Zone returns the name of the interface with the IP, which is the zone of an
+// IPv6 link-local address, e.g. `eth0` of `fe80::1%eth0`. It returns "" if no
+// interface has the IP.
+func Zone(ip net.IP) string {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return ""
+	}
+	for _, i := range ifaces {
+		addrs, err := i.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if n, ok := addr.(*net.IPNet); ok && n.IP.Equal(ip) {
+				return i.Name
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,31 @@
+// Package devicestest provides devices for testing.
+package devicestest
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/kward/avid-s3l/carbonio/devices"
+)
+
+// NewStage16 returns a Stage16 that is backed by a freshly initialized SPI
+// devices directory structure in `dir`.
+func NewStage16(dir string) (*devices.Stage16, error) {
+	d, err := devices.NewStage16(
+		devices.SPIDelayRead(true),
+		devices.SPIBaseDir(dir),
+	)
+	if err != nil {
+		return nil, err
+	}
+	for _, impl := range devices.Implementations(d) {
+		if err := os.MkdirAll(path.Dir(impl.Path()), 0755); err != nil {
+			return nil, fmt.Errorf("error creating directory for %s; %s", impl.Name(), err)
+		}
+		if err := impl.Initialize(); err != nil {
+			return nil, fmt.Errorf("error initializing %s; %s", impl.Name(), err)
+		}
+	}
+	return d, nil
+}
@@ -0,0 +1,171 @@
+/*
+Package events publishes changes of the device state to subscribers.
+
+Changes are detected by a Watcher, which polls the values of the device. The
+device is polled periodically, so that changes by external writers are
+noticed, and immediately after carbonio itself has changed values.
+*/
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Types of events.
+const (
+	// SnapshotType events hold all the current values.
+	SnapshotType = "snapshot"
+	// ParameterType events hold a changed signal parameter, e.g. a gain.
+	ParameterType = "parameter"
+	// LEDType events hold a changed LED state.
+	LEDType = "led"
+	// HealthType events hold a change of the device health, i.e. whether its
+	// values can be read.
+	HealthType = "health"
+)
+
+// Health values of HealthType events.
+const (
+	Healthy   = "ok"
+	Unhealthy = "error"
+)
+
+// DefaultBacklog is the default number of recent events that a bus keeps, so
+// that subscribers can resume after a reconnect.
+const DefaultBacklog = 256
+
+// Event describes a change of the device state.
+type Event struct {
+	// ID of the event. IDs increase monotonically.
+	ID   uint64    `json:"id"`
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	// Address of the changed value, e.g. `input/mic/1/gain`.
+	Address string `json:"address,omitempty"`
+	// Value is the new value.
+	Value interface{} `json:"value,omitempty"`
+	// Previous is the previous value.
+	Previous interface{} `json:"previous,omitempty"`
+	// Values holds all the values of a snapshot event, by address.
+	Values map[string]interface{} `json:"values,omitempty"`
+	// Error describes why the device is unhealthy.
+	Error string `json:"error,omitempty"`
+}
+
+// Subscription to the events of a bus.
+type Subscription struct {
+	// C receives the events. It is closed when the subscription ends, either
+	// by Unsubscribe, or because the subscriber fell too far behind.
+	C <-chan *Event
+
+	c      chan *Event
+	closed bool
+}
+
+// Bus distributes events to subscribers, and keeps a backlog of the most
+// recent events.
+type Bus struct {
+	mu      sync.Mutex
+	lastID  uint64
+	subs    map[*Subscription]bool
+	backlog []*Event // Oldest first.
+	size    int      // Maximum size of the backlog.
+}
+
+// NewBus returns a new bus with a backlog of DefaultBacklog events.
+func NewBus() *Bus {
+	return NewBacklogBus(DefaultBacklog)
+}
+
+// NewBacklogBus returns a new bus with a backlog of `size` events.
+func NewBacklogBus(size int) *Bus {
+	return &Bus{subs: map[*Subscription]bool{}, size: size}
+}
+
+// Publish the event to all subscribers. The ID and time of the event are set.
+// Subscribers that cannot keep up are unsubscribed, rather than blocking the
+// publisher.
+func (b *Bus) Publish(e *Event) *Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastID++
+	e.ID = b.lastID
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	if b.size > 0 {
+		if len(b.backlog) == b.size {
+			b.backlog = b.backlog[1:]
+		}
+		b.backlog = append(b.backlog, e)
+	}
+	for s := range b.subs {
+		select {
+		case s.c <- e:
+		default:
+			b.unsubscribe(s)
+		}
+	}
+	return e
+}
+
+// LastID returns the ID of the most recently published event.
+func (b *Bus) LastID() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.lastID
+}
+
+// Subscribe to the events of the bus. Up to `size` events are buffered.
+func (b *Bus) Subscribe(size int) *Subscription {
+	c := make(chan *Event, size)
+	s := &Subscription{C: c, c: c}
+	b.mu.Lock()
+	b.subs[s] = true
+	b.mu.Unlock()
+	return s
+}
+
+// SubscribeSince subscribes to the events of the bus, and returns the events
+// published after the event with the given ID. If those events are no longer
+// all in the backlog, or the ID is unknown (e.g. from before a restart), false
+// is returned, and the subscriber must resync from a snapshot.
+func (b *Bus) SubscribeSince(id uint64, size int) (*Subscription, []*Event, bool) {
+	c := make(chan *Event, size)
+	s := &Subscription{C: c, c: c}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[s] = true
+
+	if id > b.lastID {
+		return s, nil, false
+	}
+	missed := []*Event{}
+	for _, e := range b.backlog {
+		if e.ID > id {
+			missed = append(missed, e)
+		}
+	}
+	// Without a gap, the first missed event directly follows the ID.
+	if uint64(len(missed)) != b.lastID-id {
+		return s, nil, false
+	}
+	return s, missed, true
+}
+
+// Unsubscribe ends the subscription.
+func (b *Bus) Unsubscribe(s *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.unsubscribe(s)
+}
+
+func (b *Bus) unsubscribe(s *Subscription) {
+	if s.closed {
+		return
+	}
+	s.closed = true
+	delete(b.subs, s)
+	close(s.c)
+}
@@ -0,0 +1,192 @@
+package events
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/kward/avid-s3l/carbonio/address"
+)
+
+func TestBus(t *testing.T) {
+	b := NewBus()
+	fast := b.Subscribe(4)
+	slow := b.Subscribe(1)
+
+	for i := 0; i < 3; i++ {
+		b.Publish(&Event{Type: ParameterType, Address: fmt.Sprintf("input/mic/%d/gain", i+1)})
+	}
+	if got, want := b.LastID(), uint64(3); got != want {
+		t.Errorf("LastID() = %d, want %d", got, want)
+	}
+
+	for i := 0; i < 3; i++ {
+		e := <-fast.C
+		if got, want := e.ID, uint64(i+1); got != want {
+			t.Errorf("event ID = %d, want %d", got, want)
+		}
+		if e.Time.IsZero() {
+			t.Errorf("event time is unset")
+		}
+	}
+
+	// The slow subscriber was dropped after its buffer filled up.
+	if e, ok := <-slow.C; !ok || e.ID != 1 {
+		t.Errorf("slow subscriber = %v, %t; want event 1", e, ok)
+	}
+	if _, ok := <-slow.C; ok {
+		t.Errorf("slow subscriber is still subscribed")
+	}
+
+	b.Unsubscribe(fast)
+	b.Unsubscribe(fast) // Unsubscribing twice is harmless.
+	if _, ok := <-fast.C; ok {
+		t.Errorf("unsubscribed channel is open")
+	}
+}
+
+// memResolver resolves addresses to targets that store their values in memory.
+type memResolver struct {
+	ts     []*address.Target
+	values map[string]interface{}
+}
+
+func newMemResolver(values map[string]interface{}) *memResolver {
+	r := &memResolver{values: values}
+	for k := range values {
+		k := k
+		r.ts = append(r.ts, address.NewTarget(address.MustParse(k), address.GainProperty, k,
+			func() (interface{}, error) { return r.values[k], nil },
+			func(v interface{}) error { r.values[k] = v; return nil }))
+	}
+	return r
+}
+
+func (r *memResolver) Resolve(a address.Address) ([]*address.Target, error) {
+	return address.Filter(r.ts, a)
+}
+
+func TestWatcher(t *testing.T) {
+	r := newMemResolver(map[string]interface{}{
+		"input/mic/1/gain": 10,
+		"input/mic/2/gain": 20,
+		"led/status/state": "Off",
+	})
+	b := NewBus()
+	w := NewWatcher(b, r, 0)
+	s := b.Subscribe(10)
+
+	// The first poll only records the values.
+	if err := w.Poll(); err != nil {
+		t.Fatalf("Poll() unexpected error; %s", err)
+	}
+	if got := b.LastID(); got != 0 {
+		t.Errorf("first Poll() published %d events", got)
+	}
+
+	r.values["input/mic/2/gain"] = 25
+	r.values["led/status/state"] = "On"
+	if err := w.Poll(); err != nil {
+		t.Fatalf("Poll() unexpected error; %s", err)
+	}
+	got := map[string]*Event{}
+	for i := uint64(0); i < b.LastID(); i++ {
+		e := <-s.C
+		got[e.Address] = e
+	}
+	for _, tc := range []struct {
+		addr            string
+		typ             string
+		value, previous interface{}
+	}{
+		{"input/mic/2/gain", ParameterType, 25, 20},
+		{"led/status/state", LEDType, "On", "Off"},
+	} {
+		e, ok := got[tc.addr]
+		if !ok {
+			t.Errorf("Poll() did not publish %s", tc.addr)
+			continue
+		}
+		if e.Type != tc.typ || e.Value != tc.value || e.Previous != tc.previous {
+			t.Errorf("Poll() %s = %s %v (was %v), want %s %v (was %v)",
+				tc.addr, e.Type, e.Value, e.Previous, tc.typ, tc.value, tc.previous)
+		}
+	}
+	if len(got) != 2 {
+		t.Errorf("Poll() published %d events, want 2", len(got))
+	}
+
+	snap, err := w.Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot() unexpected error; %s", err)
+	}
+	if snap.Type != SnapshotType || snap.ID != b.LastID() {
+		t.Errorf("Snapshot() = %s %d, want %s %d", snap.Type, snap.ID, SnapshotType, b.LastID())
+	}
+	if got, want := snap.Values["input/mic/2/gain"], 25; got != want {
+		t.Errorf("Snapshot() input/mic/2/gain = %v, want %v", got, want)
+	}
+}
+
+func TestSubscribeSince(t *testing.T) {
+	b := NewBacklogBus(3)
+	for i := 0; i < 5; i++ {
+		b.Publish(&Event{Type: ParameterType})
+	}
+	for _, tc := range []struct {
+		desc   string
+		id     uint64
+		missed []uint64
+		ok     bool
+	}{
+		{"up to date", 5, []uint64{}, true},
+		{"within the backlog", 3, []uint64{4, 5}, true},
+		{"start of the backlog", 2, []uint64{3, 4, 5}, true},
+		{"before the backlog", 1, nil, false},
+		{"unknown ID", 6, nil, false},
+	} {
+		t.Run(fmt.Sprintf("SubscribeSince() %s", tc.desc), func(t *testing.T) {
+			s, missed, ok := b.SubscribeSince(tc.id, 1)
+			defer b.Unsubscribe(s)
+			if ok != tc.ok {
+				t.Fatalf("ok = %t, want %t", ok, tc.ok)
+			}
+			if !ok {
+				return
+			}
+			ids := []uint64{}
+			for _, e := range missed {
+				ids = append(ids, e.ID)
+			}
+			if got, want := fmt.Sprint(ids), fmt.Sprint(tc.missed); got != want {
+				t.Errorf("missed = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestWatcherHealth(t *testing.T) {
+	b := NewBus()
+	w := NewWatcher(b, &memResolver{}, 0) // Resolves no targets.
+	if err := w.Poll(); err == nil {
+		t.Fatalf("Poll() expected an error")
+	}
+	w.Poll() // Still unhealthy; no new event.
+	w.resolver = newMemResolver(map[string]interface{}{"input/mic/1/gain": 10})
+	if err := w.Poll(); err != nil {
+		t.Fatalf("Poll() unexpected error; %s", err)
+	}
+
+	s, missed, ok := b.SubscribeSince(0, 1)
+	defer b.Unsubscribe(s)
+	if !ok || len(missed) != 2 {
+		t.Fatalf("published %d events, want 2", len(missed))
+	}
+	for i, want := range []string{Unhealthy, Healthy} {
+		if e := missed[i]; e.Type != HealthType || e.Value != want {
+			t.Errorf("event %d = %s %v, want %s %s", i, e.Type, e.Value, HealthType, want)
+		}
+	}
+	if missed[0].Error == "" {
+		t.Errorf("unhealthy event has no error")
+	}
+}
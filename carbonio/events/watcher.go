@@ -0,0 +1,125 @@
+package events
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/kward/avid-s3l/carbonio/address"
+)
+
+// DefaultInterval is the default polling interval of a watcher.
+const DefaultInterval = time.Second
+
+// Watcher detects changes of the device values, and publishes them.
+type Watcher struct {
+	bus      *Bus
+	resolver address.Resolver
+	interval time.Duration
+
+	mu        sync.Mutex
+	values    map[string]interface{} // Most recently polled values.
+	unhealthy bool
+}
+
+// NewWatcher returns a watcher of the values of all the targets of the
+// resolver, that publishes to the bus.
+func NewWatcher(bus *Bus, r address.Resolver, interval time.Duration) *Watcher {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Watcher{bus: bus, resolver: r, interval: interval}
+}
+
+// Bus returns the bus of the watcher.
+func (w *Watcher) Bus() *Bus { return w.bus }
+
+// Poll reads all the values, and publishes an event for every value that
+// changed since the previous poll. The first poll only records the values. A
+// health event is published when reading the values starts or stops failing.
+func (w *Watcher) Poll() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	err := w.poll()
+	switch {
+	case err != nil && !w.unhealthy:
+		w.unhealthy = true
+		w.bus.Publish(&Event{Type: HealthType, Value: Unhealthy, Error: err.Error()})
+	case err == nil && w.unhealthy:
+		w.unhealthy = false
+		w.bus.Publish(&Event{Type: HealthType, Value: Healthy})
+	}
+	return err
+}
+
+func (w *Watcher) poll() error {
+	ts, err := address.ResolveString(w.resolver, address.Wildcard)
+	if err != nil {
+		return fmt.Errorf("error resolving targets; %s", err)
+	}
+	values := map[string]interface{}{}
+	for _, t := range ts {
+		v, err := t.Value()
+		if err != nil {
+			return fmt.Errorf("error reading %s; %s", t.Address, err)
+		}
+		values[t.Address.String()] = v
+	}
+
+	if w.values != nil {
+		for _, t := range ts {
+			a := t.Address.String()
+			prev, ok := w.values[a]
+			if ok && prev == values[a] {
+				continue
+			}
+			typ := ParameterType
+			if t.Address.Segment(0) == address.LEDRoot {
+				typ = LEDType
+			}
+			w.bus.Publish(&Event{Type: typ, Address: a, Value: values[a], Previous: prev})
+		}
+	}
+	w.values = values
+	return nil
+}
+
+// Snapshot polls the values, and returns a snapshot event of them. The event
+// holds the ID of the most recent event, so that subscribers can tell which
+// events the snapshot includes. The snapshot is not published.
+func (w *Watcher) Snapshot() (*Event, error) {
+	if err := w.Poll(); err != nil {
+		return nil, err
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	values := map[string]interface{}{}
+	for k, v := range w.values {
+		values[k] = v
+	}
+	return &Event{
+		ID:     w.bus.LastID(),
+		Type:   SnapshotType,
+		Time:   time.Now().UTC(),
+		Values: values,
+	}, nil
+}
+
+// Run polls the values until the context is done.
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		if err := w.Poll(); err != nil {
+			log.Printf("error polling values; %s", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/kward/avid-s3l/carbonio/address"
+	"github.com/kward/avid-s3l/carbonio/api"
+	"github.com/kward/avid-s3l/carbonio/changes"
+	"github.com/kward/avid-s3l/carbonio/snapshots"
+	"github.com/kward/golib/errors"
+	"google.golang.org/grpc/codes"
+)
+
+// writeJSON writes the value as a JSON response.
+func writeJSON(w http.ResponseWriter, r *http.Request, stts int, v interface{}) {
+	writeJSONAs(w, r, stts, contentTypes[JSONFormat], v)
+}
+
+// writeJSONAs writes the value as a JSON response with the content type.
+func writeJSONAs(w http.ResponseWriter, r *http.Request, stts int, contentType string, v interface{}) {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		log.Printf("error marshaling json; %s", err)
+		stts = http.StatusInternalServerError
+		contentType = contentTypes[JSONFormat]
+		data = []byte(`{"error":{"code":"Internal","message":"error marshaling json"}}`)
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.WriteHeader(stts)
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		log.Printf("error writing response; %s", err)
+	}
+}
+
+// htmlContentType is the content type of HTML pages.
+const htmlContentType = "text/html; charset=utf-8"
+
+// writeBody writes a response with the content type.
+func writeBody(w http.ResponseWriter, contentType string, body []byte) {
+	w.Header().Set("Content-Type", contentType)
+	if _, err := w.Write(body); err != nil {
+		log.Printf("error writing response; %s", err)
+	}
+}
+
+// queryList returns the values of a query parameter. Multiple values are given
+// either by repeating the parameter, or as a comma separated list.
+func queryList(r *http.Request, key string) []string {
+	strs := []string{}
+	for _, s := range r.URL.Query()[key] {
+		for _, str := range strings.Split(s, ",") {
+			if str != "" {
+				strs = append(strs, str)
+			}
+		}
+	}
+	return strs
+}
+
+// ValuesHandler returns the values of the targets matched by the `address`
+// query parameters (default all), optionally limited by `property`.
+func (h *Handlers) ValuesHandler(w http.ResponseWriter, r *http.Request) {
+	addrs := queryList(r, "address")
+	if len(addrs) == 0 {
+		addrs = []string{address.Wildcard}
+	}
+	ts, err := h.resolve(addrs, queryList(r, "property"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	doc := api.NewValues()
+	for _, t := range ts {
+		v, err := t.Value()
+		if err != nil {
+			writeError(w, r, errors.Errorf(codes.Internal, "%s", err))
+			return
+		}
+		doc.Values[t.Address.String()] = v
+	}
+	writeJSON(w, r, http.StatusOK, doc)
+}
+
+// PatchValuesHandler writes the values of an api.Values document as a single
+// transaction. The keys of the values are addresses, and may match more than
+// one target. With the `dry_run` query parameter set, nothing is changed.
+func (h *Handlers) PatchValuesHandler(w http.ResponseWriter, r *http.Request) {
+	dryRun := false
+	if s := r.URL.Query().Get("dry_run"); s != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(s); err != nil {
+			writeError(w, r, errors.Errorf(codes.InvalidArgument, "invalid dry_run value %q", s))
+			return
+		}
+	}
+	doc := &api.Values{}
+	dec := json.NewDecoder(r.Body)
+	dec.UseNumber()
+	if err := dec.Decode(doc); err != nil {
+		writeError(w, r, errors.Errorf(codes.InvalidArgument, "error decoding values; %s", err))
+		return
+	}
+
+	cs, err := h.valueChanges(doc.Values)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if err := h.authorizeChanges(r, cs); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	status, err := h.transact(cs, dryRun)
+	if status == nil {
+		writeError(w, r, err)
+		return
+	}
+	res := api.NewChanges()
+	for _, c := range changes.Order(cs) {
+		res.Items = append(res.Items, &api.Change{
+			Address: c.Target.Address.String(),
+			From:    c.From,
+			To:      c.To,
+			Status:  status[c],
+		})
+	}
+	if err != nil {
+		// Return the statuses with the error, so that clients can tell what was
+		// reverted.
+		log.Printf("%s", err)
+		res.Error = &api.ErrorDetail{Code: errors.Code(err).String(), Message: errors.ErrorDesc(err)}
+		writeJSON(w, r, httpStatus(err), res)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, res)
+}
+
+// valueChanges validates the values, and returns the changes to the targets
+// matched by their addresses.
+func (h *Handlers) valueChanges(values map[string]interface{}) ([]*changes.Change, error) {
+	if len(values) == 0 {
+		return nil, errors.Errorf(codes.InvalidArgument, "nothing to set")
+	}
+	keys := []string{}
+	for k := range values {
+		keys = append(keys, k)
+	}
+	// Sort for a deterministic order of the changes.
+	sortAddresses(keys)
+
+	cs := []*changes.Change{}
+	idx := map[string]bool{}
+	for _, k := range keys {
+		ts, err := h.resolve([]string{k}, nil)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range ts {
+			if idx[t.Address.String()] {
+				return nil, errors.Errorf(codes.InvalidArgument, "%s is given more than once", t.Address)
+			}
+			idx[t.Address.String()] = true
+			c, err := changes.New(t, values[k])
+			if err != nil {
+				return nil, err
+			}
+			cs = append(cs, c)
+		}
+	}
+	return cs, nil
+}
+
+// sortAddresses sorts address strings with addressLess. Strings that are not
+// valid addresses sort last.
+func sortAddresses(strs []string) {
+	as := map[string]address.Address{}
+	for _, s := range strs {
+		if a, err := address.Parse(s); err == nil {
+			as[s] = a
+		}
+	}
+	less := func(i, j int) bool {
+		a, aok := as[strs[i]]
+		b, bok := as[strs[j]]
+		if !aok || !bok {
+			return aok && !bok
+		}
+		return addressLess(a, b)
+	}
+	sort.SliceStable(strs, less)
+}
+
+// TargetsHandler describes the targets matched by the `address` query
+// parameters (default all), including their current values.
+func (h *Handlers) TargetsHandler(w http.ResponseWriter, r *http.Request) {
+	addrs := queryList(r, "address")
+	if len(addrs) == 0 {
+		addrs = []string{address.Wildcard}
+	}
+	ts, err := h.resolve(addrs, queryList(r, "property"))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	doc := api.NewTargets()
+	for _, t := range ts {
+		v, err := t.Value()
+		if err != nil {
+			writeError(w, r, errors.Errorf(codes.Internal, "%s", err))
+			return
+		}
+		doc.Items = append(doc.Items, apiTarget(t, v))
+	}
+	writeJSON(w, r, http.StatusOK, doc)
+}
+
+// apiTarget returns the api.Target of the target with value v.
+func apiTarget(t *address.Target, v interface{}) *api.Target {
+	p := t.Property
+	return &api.Target{
+		Address: t.Address.String(),
+		Label:   t.Label,
+		Property: &api.Property{
+			Name:     p.Name,
+			Kind:     strings.ToLower(p.Kind.String()),
+			Unit:     p.Unit,
+			Min:      p.Min,
+			Max:      p.Max,
+			States:   p.States,
+			Editable: p.Editable,
+		},
+		Value: v,
+	}
+}
+
+// SnapshotsHandler lists the snapshots of the server.
+func (h *Handlers) SnapshotsHandler(w http.ResponseWriter, r *http.Request) {
+	ss, err := h.snapshots.List()
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, snapshots.NewList(ss))
+}
+
+// SnapshotHandler gets (GET), saves (PUT), or deletes (DELETE) the snapshot
+// named by the `name` route variable.
+func (h *Handlers) SnapshotHandler(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	switch r.Method {
+	case http.MethodGet:
+		s, err := h.snapshots.Load(name)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		writeJSON(w, r, http.StatusOK, s)
+	case http.MethodPut:
+		s := &snapshots.Snapshot{}
+		if err := json.NewDecoder(r.Body).Decode(s); err != nil {
+			writeError(w, r, errors.Errorf(codes.InvalidArgument, "error decoding snapshot; %s", err))
+			return
+		}
+		if s.Name != name {
+			writeError(w, r, errors.Errorf(codes.InvalidArgument, "snapshot name %q does not match %q", s.Name, name))
+			return
+		}
+		if err := h.snapshots.Save(s); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		writeJSON(w, r, http.StatusOK, s)
+	case http.MethodDelete:
+		if err := h.snapshots.Delete(name); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeError(w, r, errors.Errorf(codes.Unimplemented, "method %s not allowed", r.Method))
+	}
+}
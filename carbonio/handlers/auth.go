@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/kward/avid-s3l/carbonio/accesslog"
+	"github.com/kward/avid-s3l/carbonio/auth"
+	"github.com/kward/avid-s3l/carbonio/changes"
+	"github.com/kward/golib/errors"
+	"google.golang.org/grpc/codes"
+)
+
+// realm of HTTP basic auth.
+const realm = "carbonio"
+
+// streamPaths are the paths of the event streams, whose browser clients cannot
+// send an Authorization header, and may send their token in the query instead.
+var streamPaths = map[string]bool{
+	"/ws":                 true,
+	APIPrefix + "/events": true,
+}
+
+// Authenticate is middleware that authenticates requests with the Auth option,
+// and stores the principal in the request context. Without the option, all
+// requests are allowed. Requests that already have a principal, e.g. from a
+// trusted listener, are not authenticated again.
+func (h *Handlers) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := auth.FromContext(r.Context()); ok || h.opts.auth == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		authenticate := h.opts.auth.Authenticate
+		if r.Method == http.MethodGet && streamPaths[r.URL.Path] {
+			authenticate = h.opts.auth.AuthenticateStream
+		}
+		p, err := authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Basic realm="`+realm+`"`)
+			writeError(w, r, err)
+			return
+		}
+		if p.Name != auth.Anonymous {
+			accesslog.SetUser(r.Context(), p.Name)
+		}
+		next.ServeHTTP(w, r.WithContext(auth.NewContext(r.Context(), p)))
+	})
+}
+
+// authorize returns an error unless the principal of the request has the role.
+func (h *Handlers) authorize(r *http.Request, role auth.Role) error {
+	if h.opts.auth == nil {
+		return nil
+	}
+	p, ok := auth.FromContext(r.Context())
+	if !ok {
+		return errors.Errorf(codes.Unauthenticated, "authentication required")
+	}
+	if p.Role < role {
+		return errors.Errorf(codes.PermissionDenied, "%s has the %s role; %s %s requires %s",
+			p.Name, p.Role, r.Method, r.URL.Path, role)
+	}
+	return nil
+}
+
+// authorizeChanges returns an error unless the principal of the request may
+// make all the changes.
+func (h *Handlers) authorizeChanges(r *http.Request, cs []*changes.Change) error {
+	role := auth.Operator
+	for _, c := range cs {
+		if r := auth.ChangeRole(c.Target); r > role {
+			role = r
+		}
+	}
+	return h.authorize(r, role)
+}
+
+// requireRole wraps the handler, so that it is only called for principals with
+// the role.
+func (h *Handlers) requireRole(role auth.Role, next http.HandlerFunc) http.HandlerFunc {
+	if role <= auth.Viewer {
+		return next // Every authenticated principal is a viewer.
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := h.authorize(r, role); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kward/avid-s3l/carbonio/auth"
+)
+
+func TestAuthorization(t *testing.T) {
+	d, cleanup := newDevice(t)
+	defer cleanup()
+	dir, err := ioutil.TempDir("", "snapshots")
+	if err != nil {
+		t.Fatalf("error creating temp dir; %s", err)
+	}
+	defer os.RemoveAll(dir)
+	a, err := auth.Parse([]byte(`
+tokens:
+  - {name: monitor, token: v, role: viewer}
+  - {name: foh, token: o, role: operator}
+  - {name: tech, token: a, role: admin}
+`))
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	h, err := NewHandlers(d, SnapshotDir(dir), Auth(a))
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	r := mux.NewRouter()
+	r.Use(h.Authenticate)
+	h.RegisterAPI(r)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	const (
+		gain    = `{"values": {"input/mic/1/gain": 30}}`
+		phantom = `{"values": {"input/mic/1/phantom": true}}`
+		snap    = `{"name": "s", "values": {}}`
+	)
+	for _, tc := range []struct {
+		desc   string
+		token  string
+		method string
+		path   string
+		body   string
+		stts   int
+	}{
+		{"anonymous read", "", http.MethodGet, "/values", "", http.StatusUnauthorized},
+		{"invalid token", "x", http.MethodGet, "/values", "", http.StatusUnauthorized},
+		{"viewer read", "v", http.MethodGet, "/values", "", http.StatusOK},
+		{"viewer gain", "v", http.MethodPatch, "/values", gain, http.StatusForbidden},
+		{"operator gain", "o", http.MethodPatch, "/values", gain, http.StatusOK},
+		{"operator gain parameter", "o", http.MethodPut, "/signals/input/mic/1/gain", `{"value": 40}`, http.StatusOK},
+		{"operator phantom", "o", http.MethodPatch, "/values", phantom, http.StatusForbidden},
+		{"operator phantom dry-run", "o", http.MethodPatch, "/values?dry_run=true", phantom, http.StatusForbidden},
+		{"operator phantom node", "o", http.MethodPatch, "/signals/input/mic/1", `{"properties": {"phantom": true}}`, http.StatusForbidden},
+		{"operator led", "o", http.MethodPut, "/leds/status/state", `{"value": "On"}`, http.StatusForbidden},
+		{"operator snapshot", "o", http.MethodPut, "/snapshots/s", snap, http.StatusForbidden},
+		{"admin phantom", "a", http.MethodPatch, "/values", phantom, http.StatusOK},
+		{"admin led", "a", http.MethodPut, "/leds/status/state", `{"value": "On"}`, http.StatusOK},
+		{"admin snapshot", "a", http.MethodPut, "/snapshots/s", snap, http.StatusOK},
+		{"query token read", "", http.MethodGet, "/values?access_token=v", "", http.StatusUnauthorized},
+		{"query token gain", "", http.MethodPatch, "/values?access_token=o", gain, http.StatusUnauthorized},
+	} {
+		t.Run(fmt.Sprintf("%s %s", tc.desc, tc.path), func(t *testing.T) {
+			req, err := http.NewRequest(tc.method, srv.URL+APIPrefix+tc.path, strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatalf("error creating request; %s", err)
+			}
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			res.Body.Close()
+			if res.StatusCode != tc.stts {
+				t.Errorf("status = %d, want %d", res.StatusCode, tc.stts)
+			}
+			if res.StatusCode == http.StatusUnauthorized && res.Header.Get("WWW-Authenticate") == "" {
+				t.Errorf("WWW-Authenticate header is missing")
+			}
+		})
+	}
+}
+
+func TestAuthenticateStreams(t *testing.T) {
+	a, err := auth.Parse([]byte("tokens: [{name: monitor, token: v, role: viewer}]"))
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	h, err := NewHandlers(nil, Host("localhost"), Auth(a))
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	ok := h.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, tc := range []struct {
+		desc   string
+		method string
+		url    string
+		stts   int
+	}{
+		{"websocket", http.MethodGet, "/ws?access_token=v", http.StatusOK},
+		{"events", http.MethodGet, APIPrefix + "/events?access_token=v", http.StatusOK},
+		{"other route", http.MethodGet, APIPrefix + "/values?access_token=v", http.StatusUnauthorized},
+		{"other method", http.MethodPost, "/ws?access_token=v", http.StatusUnauthorized},
+	} {
+		t.Run(fmt.Sprintf("Authenticate() %s", tc.desc), func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			ok.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.url, nil))
+			if rec.Code != tc.stts {
+				t.Errorf("status = %d, want %d", rec.Code, tc.stts)
+			}
+		})
+	}
+}
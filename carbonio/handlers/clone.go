@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/kward/avid-s3l/carbonio/client"
+	"github.com/kward/golib/errors"
+	"google.golang.org/grpc/codes"
+)
+
+// hostPrefix is the prefix of host arguments, e.g. `@stagebox-a`.
+const hostPrefix = "@"
+
+// parseHosts parses `@host` arguments into the source and destination hosts.
+func parseHosts(args []string) (string, []string, error) {
+	if len(args) < 2 {
+		return "", nil, errors.Errorf(codes.InvalidArgument, "want a source and at least one destination host")
+	}
+	hosts := []string{}
+	seen := map[string]bool{}
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, hostPrefix) || len(arg) == len(hostPrefix) {
+			return "", nil, errors.Errorf(codes.InvalidArgument, "invalid host %q; want %shost[:port]", arg, hostPrefix)
+		}
+		host := strings.TrimPrefix(arg, hostPrefix)
+		if seen[host] {
+			return "", nil, errors.Errorf(codes.InvalidArgument, "host %s is given more than once", host)
+		}
+		seen[host] = true
+		hosts = append(hosts, host)
+	}
+	return hosts[0], hosts[1:], nil
+}
+
+// cloneAddresses returns the addresses to clone for the configured types.
+// Without types, all inputs are cloned.
+func (h *Handlers) cloneAddresses() ([]string, error) {
+	ts := h.opts.types
+	if len(ts) == 0 {
+		ts = []string{"inputs"}
+	}
+	addrs := []string{}
+	seen := map[string]bool{}
+	for _, t := range ts {
+		root, ok := types[t]
+		if !ok {
+			return nil, errors.Errorf(codes.InvalidArgument, "unknown type %q; want inputs, outputs, or leds", t)
+		}
+		if !seen[root] {
+			seen[root] = true
+			addrs = append(addrs, root)
+		}
+	}
+	return addrs, nil
+}
+
+// CloneCommand copies the selected values from the source host to each of the
+// destination hosts. The differences are shown for each destination, and must
+// be confirmed (read from `in`) unless AssumeYes is set. The values are written
+// with the safe-write path of each destination, as a single transaction.
+func (h *Handlers) CloneCommand(w io.Writer, in io.Reader, args []string) error {
+	src, dsts, err := parseHosts(args)
+	if err != nil {
+		return err
+	}
+	addrs, err := h.cloneAddresses()
+	if err != nil {
+		return err
+	}
+
+	sc, err := client.New(src)
+	if err != nil {
+		return err
+	}
+	want, err := sc.Values(addrs, h.opts.props)
+	if err != nil {
+		return err
+	}
+	keys := []string{}
+	for k := range want {
+		keys = append(keys, k)
+	}
+	sortAddresses(keys)
+
+	confirm := bufio.NewReader(in)
+	for _, dst := range dsts {
+		dc, err := client.New(dst)
+		if err != nil {
+			return err
+		}
+		have, err := dc.Values(addrs, h.opts.props)
+		if err != nil {
+			return err
+		}
+
+		diff := map[string]interface{}{}
+		lines := []string{"ADDRESS " + strings.ToUpper(dst) + " " + strings.ToUpper(src)}
+		for _, k := range keys {
+			v, ok := have[k]
+			if !ok {
+				return errors.Errorf(codes.FailedPrecondition, "%s is missing on %s", k, dst)
+			}
+			if fmt.Sprint(v) == fmt.Sprint(want[k]) {
+				continue
+			}
+			diff[k] = want[k]
+			lines = append(lines, fmt.Sprintf("%s %s %s", k, formatJSON(v), formatJSON(want[k])))
+		}
+		if len(diff) == 0 {
+			fmt.Fprintf(w, "%s already matches %s\n", dst, src)
+			continue
+		}
+		if err := writePlain(w, lines); err != nil {
+			return err
+		}
+		if h.opts.dryRun {
+			fmt.Fprintf(w, "would change %d values on %s\n", len(diff), dst)
+			continue
+		}
+		if !h.opts.yes {
+			fmt.Fprintf(w, "change %d values on %s? [y/N] ", len(diff), dst)
+			answer, _ := confirm.ReadString('\n')
+			if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+				fmt.Fprintf(w, "skipped %s\n", dst)
+				continue
+			}
+		}
+		if _, err := dc.SetValues(diff, false); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "changed %d values on %s\n", len(diff), dst)
+	}
+	return nil
+}
+
+// formatJSON formats a value decoded from JSON like address.Property.Format.
+func formatJSON(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return "-"
+	case bool:
+		if x {
+			return "On"
+		}
+		return "Off"
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	}
+	return fmt.Sprintf("%v", v)
+}
@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestParseHosts(t *testing.T) {
+	for _, tc := range []struct {
+		desc string
+		ok   bool
+
+		args []string
+		src  string
+		dsts []string
+	}{
+		{"two hosts", true, []string{"@a", "@b:8081"}, "a", []string{"b:8081"}},
+		{"three hosts", true, []string{"@a", "@b", "@c"}, "a", []string{"b", "c"}},
+		{desc: "one host", args: []string{"@a"}},
+		{desc: "missing prefix", args: []string{"@a", "b"}},
+		{desc: "empty host", args: []string{"@a", "@"}},
+		{desc: "duplicate host", args: []string{"@a", "@a"}},
+	} {
+		t.Run(fmt.Sprintf("parseHosts() %s", tc.desc), func(t *testing.T) {
+			src, dsts, err := parseHosts(tc.args)
+			if err != nil && tc.ok {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			if err == nil && !tc.ok {
+				t.Fatal("expected an error")
+			}
+			if !tc.ok {
+				return
+			}
+			if got, want := src, tc.src; got != want {
+				t.Errorf("source = %s, want %s", got, want)
+			}
+			if got, want := fmt.Sprint(dsts), fmt.Sprint(tc.dsts); got != want {
+				t.Errorf("destinations = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestCloneCommand(t *testing.T) {
+	src, srcSrv, cleanup := newServer(t)
+	defer cleanup()
+	dst, dstSrv, cleanup := newServer(t)
+	defer cleanup()
+
+	if err := src.SetCommand(&bytes.Buffer{}, []string{"input/mic/1/gain=40", "input/mic/2/phantom=on", "input/mic/3/pad=on"}, nil); err != nil {
+		t.Fatalf("SetCommand() unexpected error; %s", err)
+	}
+	args := []string{"@" + srcSrv.URL, "@" + dstSrv.URL}
+	get := func(addr string) string {
+		buf := &bytes.Buffer{}
+		if err := dst.GetCommand(buf, []string{addr}, nil); err != nil {
+			t.Fatalf("GetCommand() unexpected error; %s", err)
+		}
+		return strings.Fields(buf.String())[3]
+	}
+
+	for _, tc := range []struct {
+		desc   string
+		opts   []func(*options) error
+		answer string
+		gain   string // Expected gain of input/mic/1 on the destination.
+		pad    string // Expected pad of input/mic/3 on the destination.
+	}{
+		{"dry run", []func(*options) error{DryRun(true)}, "", "10", "Off"},
+		{"declined", nil, "n\n", "10", "Off"},
+		{"gain only", []func(*options) error{Properties([]string{"gain"}), AssumeYes(true)}, "", "40", "Off"},
+		{"confirmed", nil, "y\n", "40", "On"},
+	} {
+		t.Run(fmt.Sprintf("CloneCommand() %s", tc.desc), func(t *testing.T) {
+			h, err := NewClientHandlers(tc.opts...)
+			if err != nil {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			if err := h.CloneCommand(&bytes.Buffer{}, strings.NewReader(tc.answer), args); err != nil {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			if got, want := get("input/mic/1/gain"), tc.gain; got != want {
+				t.Errorf("gain = %s, want %s", got, want)
+			}
+			if got, want := get("input/mic/3/pad"), tc.pad; got != want {
+				t.Errorf("pad = %s, want %s", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+
+	"github.com/kward/golib/errors"
+	"google.golang.org/grpc/codes"
+)
+
+const controlTmpl = "html/control.tmpl"
+
+func init() {
+	mustTemplate(controlTmpl)
+}
+
+// ControlHandler returns the control surface page. The page is built in the
+// browser from the HTTP API, and only uses the embedded static assets, so it
+// works without internet access.
+func (h *Handlers) ControlHandler(w http.ResponseWriter, r *http.Request) {
+	data := struct {
+		Title string
+	}{
+		Title: fmt.Sprintf("Carbon I/O %s", h.device.IP()),
+	}
+	buf := &bytes.Buffer{}
+	if err := tmpls[controlTmpl].Execute(buf, data); err != nil {
+		writeError(w, r, errors.Errorf(codes.Internal, "error executing template; %s", err))
+		return
+	}
+	writeBody(w, htmlContentType, buf.Bytes())
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"bytes"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kward/avid-s3l/carbonio/api"
+	"github.com/kward/golib/errors"
+	"google.golang.org/grpc/codes"
+)
+
+const errorTmpl = "html/error.tmpl"
+
+func init() {
+	mustTemplate(errorTmpl)
+}
+
+// httpStatuses maps gRPC codes to HTTP statuses.
+var httpStatuses = map[codes.Code]int{
+	codes.OK:                 http.StatusOK,
+	codes.Canceled:           499, // Client Closed Request.
+	codes.Unknown:            http.StatusInternalServerError,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.FailedPrecondition: http.StatusPreconditionFailed,
+	codes.Aborted:            http.StatusConflict,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Internal:           http.StatusInternalServerError,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DataLoss:           http.StatusInternalServerError,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+}
+
+// statusError is an error with an HTTP status that no gRPC code maps to.
+type statusError struct {
+	status int
+	err    error
+}
+
+// Error implements the error interface.
+func (e *statusError) Error() string { return e.err.Error() }
+
+// notAcceptable returns the error of a request for formats that are not
+// supported.
+func notAcceptable() error {
+	return &statusError{
+		status: http.StatusNotAcceptable,
+		err: errors.Errorf(codes.InvalidArgument, "none of the accepted formats are supported; want %s",
+			strings.Join(Formats(), ", ")),
+	}
+}
+
+// httpStatus returns the HTTP status of an error.
+func httpStatus(err error) int {
+	if e, ok := err.(*statusError); ok {
+		return e.status
+	}
+	if s, ok := httpStatuses[errors.Code(err)]; ok {
+		return s
+	}
+	return http.StatusInternalServerError
+}
+
+// errorCode returns the gRPC code and the description of an error.
+func errorCode(err error) (codes.Code, string) {
+	if e, ok := err.(*statusError); ok {
+		err = e.err
+	}
+	return errors.Code(err), errors.ErrorDesc(err)
+}
+
+// Representations of errors, in addition to JSONFormat.
+const (
+	htmlError    = "html"
+	problemError = "problem"
+)
+
+// errorFormat returns the representation of an error that the client prefers
+// by its Accept header: an HTML page, an RFC 7807 problem document, or a JSON
+// api.Error otherwise.
+func errorFormat(r *http.Request) string {
+	format, best := JSONFormat, 0.0
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		f := ""
+		switch mt {
+		case "text/html":
+			f = htmlError
+		case api.ProblemContentType:
+			f = problemError
+		case "application/json":
+			f = JSONFormat
+		default:
+			continue
+		}
+		if q > best {
+			format, best = f, q
+		}
+	}
+	return format
+}
+
+// writeError writes the error with the HTTP status of its code, in the
+// representation that the client prefers. Server errors are also logged.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	stts := httpStatus(err)
+	code, desc := errorCode(err)
+	if stts >= http.StatusInternalServerError {
+		log.Printf("%s %s: %s", r.Method, r.URL.Path, desc)
+	}
+
+	switch errorFormat(r) {
+	case htmlError:
+		data := struct {
+			Status  int
+			Title   string
+			Code    string
+			Message string
+		}{
+			Status:  stts,
+			Title:   http.StatusText(stts),
+			Code:    code.String(),
+			Message: desc,
+		}
+		buf := &bytes.Buffer{}
+		if err := tmpls[errorTmpl].Execute(buf, data); err != nil {
+			log.Printf("error executing template; %s", err)
+			break // Fall back to JSON.
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(stts)
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			log.Printf("error writing response; %s", err)
+		}
+		return
+	case problemError:
+		writeJSONAs(w, r, stts, api.ProblemContentType, &api.Problem{
+			Type:     "about:blank",
+			Title:    http.StatusText(stts),
+			Status:   stts,
+			Detail:   desc,
+			Instance: r.URL.Path,
+			Code:     code.String(),
+		})
+		return
+	}
+	writeJSON(w, r, stts, &api.Error{Error: &api.ErrorDetail{
+		Code:    code.String(),
+		Message: desc,
+	}})
+}
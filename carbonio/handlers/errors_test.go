@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kward/avid-s3l/carbonio/api"
+	"github.com/kward/golib/errors"
+	"google.golang.org/grpc/codes"
+)
+
+func TestErrorFormat(t *testing.T) {
+	for _, tc := range []struct {
+		desc   string
+		accept string
+		format string
+	}{
+		{"no accept", "", JSONFormat},
+		{"anything", "*/*", JSONFormat},
+		{"json", "application/json", JSONFormat},
+		{"problem", "application/problem+json", problemError},
+		{"browser", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", htmlError},
+		{"quality", "text/html;q=0.5, application/problem+json", problemError},
+		{"unsupported", "text/csv", JSONFormat},
+	} {
+		t.Run(fmt.Sprintf("errorFormat() %s", tc.desc), func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if tc.accept != "" {
+				r.Header.Set("Accept", tc.accept)
+			}
+			if got, want := errorFormat(r), tc.format; got != want {
+				t.Errorf("= %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	err := errors.Errorf(codes.NotFound, "no targets match <input/mic/99>")
+	for _, tc := range []struct {
+		desc        string
+		accept      string
+		contentType string
+		body        string
+	}{
+		{"json", "", "application/json", `"code": "NotFound"`},
+		{"problem", "application/problem+json", api.ProblemContentType, `"instance": "/list_query"`},
+		// The message is escaped.
+		{"html", "text/html", "text/html", "no targets match &lt;input/mic/99&gt;"},
+	} {
+		t.Run(fmt.Sprintf("writeError() %s", tc.desc), func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/list_query", nil)
+			if tc.accept != "" {
+				r.Header.Set("Accept", tc.accept)
+			}
+			rec := httptest.NewRecorder()
+			writeError(rec, r, err)
+			if got, want := rec.Code, http.StatusNotFound; got != want {
+				t.Errorf("status = %d, want %d", got, want)
+			}
+			if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, tc.contentType) {
+				t.Errorf("Content-Type = %q, want %s", got, tc.contentType)
+			}
+			if got := rec.Body.String(); !strings.Contains(got, tc.body) {
+				t.Errorf("body = %s, want %s included", got, tc.body)
+			}
+		})
+	}
+}
+
+func TestListQueryHandlerErrors(t *testing.T) {
+	d, cleanup := newDevice(t)
+	defer cleanup()
+	h, err := NewHandlers(d)
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+
+	for _, tc := range []struct {
+		desc   string
+		url    string
+		accept string
+		stts   int
+		code   string
+	}{
+		{"not acceptable", "/list_query?format=xml", "", http.StatusNotAcceptable, "InvalidArgument"},
+		{"no match", "/list_query?address=input/mic/99", "", http.StatusNotFound, "NotFound"},
+	} {
+		t.Run(fmt.Sprintf("ListQueryHandler() %s", tc.desc), func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			h.ListQueryHandler(rec, httptest.NewRequest(http.MethodGet, tc.url, nil))
+			if rec.Code != tc.stts {
+				t.Errorf("status = %d, want %d", rec.Code, tc.stts)
+			}
+			doc := &api.Error{}
+			if err := json.Unmarshal(rec.Body.Bytes(), doc); err != nil {
+				t.Fatalf("error decoding %s; %s", rec.Body, err)
+			}
+			if doc.Error == nil || doc.Error.Code != tc.code {
+				t.Errorf("error = %+v, want code %s", doc.Error, tc.code)
+			}
+		})
+	}
+}
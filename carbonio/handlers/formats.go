@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/kward/avid-s3l/carbonio/api"
+	"github.com/kward/tabulate/render"
+	"github.com/kward/tabulate/table"
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Supported output formats.
+const (
+	TableFormat = "table"
+	JSONFormat  = "json"
+	YAMLFormat  = "yaml"
+	CSVFormat   = "csv"
+)
+
+// contentTypes maps the output formats to their HTTP content types.
+var contentTypes = map[string]string{
+	TableFormat: "text/plain; charset=utf-8",
+	JSONFormat:  "application/json",
+	YAMLFormat:  "application/yaml",
+	CSVFormat:   "text/csv; charset=utf-8",
+}
+
+// mediaTypes maps the accepted HTTP media types to output formats.
+var mediaTypes = map[string]string{
+	"text/plain":         TableFormat,
+	"application/json":   JSONFormat,
+	"application/yaml":   YAMLFormat,
+	"application/x-yaml": YAMLFormat,
+	"text/yaml":          YAMLFormat,
+	"text/x-yaml":        YAMLFormat,
+	"text/csv":           CSVFormat,
+}
+
+// Formats returns the supported output formats.
+func Formats() []string { return []string{TableFormat, JSONFormat, YAMLFormat, CSVFormat} }
+
+func validFormat(f string) bool {
+	_, ok := contentTypes[f]
+	return ok
+}
+
+// negotiate determines the output format of a request. A `format` query
+// parameter takes precedence over the Accept header. The default format is
+// returned when the client accepts anything (or sends no Accept header), or
+// HTML. An empty string is returned when none of the accepted formats are
+// supported.
+func negotiate(r *http.Request, def string) string {
+	if f := r.URL.Query().Get("format"); f != "" {
+		if validFormat(f) {
+			return f
+		}
+		return ""
+	}
+
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return def
+	}
+	type choice struct {
+		format string
+		q      float64
+	}
+	choices := []choice{}
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if v, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+		switch f, ok := mediaTypes[mt]; {
+		case ok:
+			choices = append(choices, choice{f, q})
+		case mt == "*/*", mt == "text/*", mt == "text/html":
+			choices = append(choices, choice{def, q})
+		}
+	}
+	if len(choices) == 0 {
+		return ""
+	}
+	sort.SliceStable(choices, func(i, j int) bool { return choices[i].q > choices[j].q })
+	return choices[0].format
+}
+
+// document returns the listing as an api.List.
+func (l *listing) document(kind string, raw bool) *api.List {
+	doc := api.NewList(kind)
+	for _, r := range l.rows {
+		item := &api.Item{
+			Address:    r.node.Address.String(),
+			Type:       r.node.Address.Segment(0),
+			Label:      r.node.Label,
+			Properties: map[string]interface{}{},
+		}
+		for _, p := range l.props {
+			t := r.node.Target(p.Name)
+			if t == nil {
+				continue
+			}
+			if raw {
+				item.Properties[p.Name] = strings.TrimRight(string(t.Raw()), "\n")
+				continue
+			}
+			if err, ok := r.errs[p.Name]; ok {
+				if item.Errors == nil {
+					item.Errors = map[string]string{}
+				}
+				item.Errors[p.Name] = err.Error()
+			}
+			item.Properties[p.Name] = r.values[p.Name]
+		}
+		doc.Items = append(doc.Items, item)
+	}
+	return doc
+}
+
+// csv renders the listing as comma separated values, with a header line.
+func (l *listing) csv(raw bool) (string, error) {
+	header := []string{"address"}
+	for _, p := range l.props {
+		header = append(header, p.Name)
+	}
+	tbl, err := table.NewTable()
+	if err != nil {
+		return "", fmt.Errorf("error instantiating a table; %s", err)
+	}
+	tbl.Append(header)
+	for _, r := range l.rows {
+		records := []string{r.node.Address.String()}
+		for _, p := range l.props {
+			t := r.node.Target(p.Name)
+			switch {
+			case t == nil:
+				records = append(records, "")
+			case raw:
+				records = append(records, strings.TrimRight(string(t.Raw()), "\n"))
+			case r.values[p.Name] == nil:
+				records = append(records, "")
+			default:
+				records = append(records, fmt.Sprintf("%v", r.values[p.Name]))
+			}
+		}
+		tbl.Append(records)
+	}
+	return (&render.CSVRenderer{}).Render(tbl), nil
+}
+
+// render the listing in the requested format.
+func (l *listing) render(format, kind string, raw bool) (string, error) {
+	switch format {
+	case JSONFormat:
+		data, err := json.MarshalIndent(l.document(kind, raw), "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("error marshaling json; %s", err)
+		}
+		return string(data) + "\n", nil
+	case YAMLFormat:
+		data, err := yaml.Marshal(l.document(kind, raw))
+		if err != nil {
+			return "", fmt.Errorf("error marshaling yaml; %s", err)
+		}
+		return string(data), nil
+	case CSVFormat:
+		return l.csv(raw)
+	}
+	return l.plain(raw)
+}
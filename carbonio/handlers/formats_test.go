@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kward/avid-s3l/carbonio/api"
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestNegotiate(t *testing.T) {
+	for _, tc := range []struct {
+		desc   string
+		url    string
+		accept string
+		format string
+	}{
+		{"no accept", "/", "", TableFormat},
+		{"anything", "/", "*/*", TableFormat},
+		{"browser", "/", "text/html,application/xhtml+xml,*/*;q=0.8", TableFormat},
+		{"json", "/", "application/json", JSONFormat},
+		{"yaml", "/", "application/x-yaml", YAMLFormat},
+		{"csv", "/", "text/csv", CSVFormat},
+		{"quality", "/", "application/json;q=0.5, text/csv", CSVFormat},
+		{"query", "/?format=yaml", "application/json", YAMLFormat},
+		{"unsupported", "/", "application/xml", ""},
+		{"unsupported query", "/?format=xml", "", ""},
+	} {
+		t.Run(fmt.Sprintf("negotiate() %s", tc.desc), func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			if tc.accept != "" {
+				r.Header.Set("Accept", tc.accept)
+			}
+			if got, want := negotiate(r, TableFormat), tc.format; got != want {
+				t.Errorf("= %q, want %q", got, want)
+			}
+		})
+	}
+}
+
+func TestListQueryHandler(t *testing.T) {
+	d, cleanup := newDevice(t)
+	defer cleanup()
+
+	h, err := NewHandlers(d)
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/list_query?address=input/mic/2,led/mute", nil)
+	r.Header.Set("Accept", "application/json")
+	w := httptest.NewRecorder()
+	h.ListQueryHandler(w, r)
+
+	if got, want := w.Code, http.StatusOK; got != want {
+		t.Fatalf("status = %d, want %d", got, want)
+	}
+	if got, want := w.Header().Get("Content-Type"), "application/json"; got != want {
+		t.Errorf("Content-Type = %s, want %s", got, want)
+	}
+	doc := &api.List{}
+	if err := json.Unmarshal(w.Body.Bytes(), doc); err != nil {
+		t.Fatalf("error unmarshaling response; %s", err)
+	}
+	if got, want := doc.Version, api.Version; got != want {
+		t.Errorf("version = %d, want %d", got, want)
+	}
+	if got, want := len(doc.Items), 2; got != want {
+		t.Fatalf("len(items) = %d, want %d", got, want)
+	}
+	if got, want := doc.Items[0].Properties["gain"], float64(10); got != want {
+		t.Errorf("gain = %v (%T), want %v", got, got, want)
+	}
+	if got, want := doc.Items[0].Properties["phantom"], false; got != want {
+		t.Errorf("phantom = %v (%T), want %v", got, got, want)
+	}
+	if got, want := doc.Items[1].Properties["state"], "Off"; got != want {
+		t.Errorf("state = %v, want %v", got, want)
+	}
+}
+
+func TestRenderRaw(t *testing.T) {
+	d, cleanup := newDevice(t)
+	defer cleanup()
+
+	h, err := NewHandlers(d, Raw(true))
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	v := &view{addrs: []string{"input/mic/2/gain"}, raw: true}
+
+	for _, tc := range []struct {
+		desc      string
+		format    string
+		unmarshal func([]byte, interface{}) error
+	}{
+		{"json", JSONFormat, json.Unmarshal},
+		{"yaml", YAMLFormat, yaml.Unmarshal},
+	} {
+		t.Run(fmt.Sprintf("renderView() %s", tc.desc), func(t *testing.T) {
+			out, err := h.renderView(v, tc.format, "List")
+			if err != nil {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			doc := &api.List{}
+			if err := tc.unmarshal([]byte(out), doc); err != nil {
+				t.Fatalf("error unmarshaling output; %s", err)
+			}
+			if got, want := len(doc.Items), 1; got != want {
+				t.Fatalf("len(items) = %d, want %d", got, want)
+			}
+			if got, want := doc.Items[0].Properties["gain"], "1"; got != want {
+				t.Errorf("gain = %q, want %q", got, want)
+			}
+		})
+	}
+	t.Run("renderView() csv", func(t *testing.T) {
+		out, err := h.renderView(v, CSVFormat, "List")
+		if err != nil {
+			t.Fatalf("unexpected error; %s", err)
+		}
+		if got, want := out, "address,gain\ninput/mic/2,1\n"; got != want {
+			t.Errorf("= %q, want %q", got, want)
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+)
+
+// GetCommand writes the current values of the targets matched by the
+// addresses. When properties are given, only those properties are included.
+func (h *Handlers) GetCommand(w io.Writer, addrs, props []string) error {
+	ts, err := h.resolve(addrs, props)
+	if err != nil {
+		return err
+	}
+
+	lines := []string{"ADDRESS VALUE"}
+	for _, t := range ts {
+		v, err := t.Value()
+		if err != nil {
+			return err
+		}
+		if h.opts.raw {
+			lines = append(lines, fmt.Sprintf("%s %q", t.Address, t.Raw()))
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s %s", t.Address, t.Property.Format(v)))
+	}
+
+	str, err := renderPlain(lines)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, str); err != nil {
+		return fmt.Errorf("error writing values; %s", err)
+	}
+	return nil
+}
@@ -3,42 +3,90 @@ Package handlers provides common routines for handling requests.
 
 All templates are loaded in the `init()` of this file.
 
-TODO(2020-02-19) Provide HTML error pages.
+Handlers return errors with gRPC codes. Commands return them to the caller, and
+HTTP handlers write them with the HTTP status of their code, as an HTML error
+page, an RFC 7807 problem document, or an api.Error, depending on the Accept
+header of the request.
 */
 package handlers
 
 import (
 	"fmt"
 	"html/template"
+	"strings"
 
+	"github.com/kward/avid-s3l/carbonio/address"
+	"github.com/kward/avid-s3l/carbonio/client"
 	"github.com/kward/avid-s3l/carbonio/devices"
-	"github.com/kward/avid-s3l/carbonio/helpers"
+	"github.com/kward/avid-s3l/carbonio/snapshots"
 	"github.com/kward/avid-s3l/carbonio/templates"
+	"github.com/kward/golib/errors"
+	"github.com/kward/tabulate/render"
+	"github.com/kward/tabulate/table"
+	"google.golang.org/grpc/codes"
 )
 
 type Handlers struct {
-	opts   *options
-	device devices.Device
+	opts      *options
+	device    devices.Device
+	resolver  address.Resolver
+	snapshots snapshots.Store
+	client    *client.Client // Client of the remote server, if any.
 }
 
+// NewHandlers returns handlers for the device. With the Host option, the
+// handlers instead use the HTTP API of the remote carbonio server on that host,
+// and the device may be nil.
 func NewHandlers(device devices.Device, opts ...func(*options) error) (*Handlers, error) {
+	h, err := newHandlers(opts...)
+	if err != nil {
+		return nil, err
+	}
+	if h.opts.host != "" {
+		if h.opts.raw {
+			return nil, errors.Errorf(codes.Unimplemented, "raw values are not available from remote hosts")
+		}
+		c, err := client.New(h.opts.host)
+		if err != nil {
+			return nil, err
+		}
+		h.client = c
+		h.resolver = c.Resolver()
+		h.snapshots = c.Snapshots()
+		return h, nil
+	}
+
 	if device == nil {
 		return nil, fmt.Errorf("device is uninitialized")
 	}
+	h.device = device
+	h.resolver = address.NewDeviceResolver(device)
+	return h, nil
+}
+
+// NewClientHandlers returns handlers for commands that only talk to remote
+// carbonio servers, e.g. clone.
+func NewClientHandlers(opts ...func(*options) error) (*Handlers, error) {
+	return newHandlers(opts...)
+}
 
-	o := &options{}
+func newHandlers(opts ...func(*options) error) (*Handlers, error) {
+	o := &options{
+		format:      TableFormat,
+		snapshotDir: snapshots.DefaultDir(),
+	}
 	for _, opt := range opts {
 		if err := opt(o); err != nil {
-			return nil, fmt.Errorf("invalid option; %s", err)
+			return nil, errors.Errorf(codes.InvalidArgument, "invalid option; %s", err)
 		}
 	}
 	if err := o.validate(); err != nil {
-		return nil, fmt.Errorf("failed to validate options; %s", err)
+		return nil, errors.Errorf(codes.InvalidArgument, "failed to validate options; %s", err)
 	}
 
 	return &Handlers{
-		opts:   o,
-		device: device,
+		opts:      o,
+		snapshots: snapshots.NewDirStore(o.snapshotDir),
 	}, nil
 }
 
@@ -52,13 +100,14 @@ var tmpls = map[string]*template.Template{}
 
 func init() {
 	// Load and parse HTML templates.
+	// The templates are embedded, so failures are bugs.
 	as, err := templates.AssetDir("html")
 	if err != nil {
-		helpers.Exit(fmt.Sprintf("unable to locate html templates"))
+		panic("unable to locate html templates")
 	}
 	for _, a := range as {
 		if err := loadAndParse("html/" + a); err != nil {
-			helpers.Exit(fmt.Sprintf("%s", err))
+			panic(err)
 		}
 	}
 }
@@ -78,10 +127,55 @@ func loadAndParse(name string) error {
 	return nil
 }
 
+// resolve the addresses into targets, optionally limited to the named
+// properties. Duplicate targets are removed.
+func (h *Handlers) resolve(addrs, props []string) ([]*address.Target, error) {
+	want := map[string]bool{}
+	for _, p := range props {
+		want[p] = true
+	}
+
+	ts := []*address.Target{}
+	seen := map[string]bool{}
+	for _, str := range addrs {
+		rts, err := address.ResolveString(h.resolver, str)
+		if err != nil {
+			return nil, err
+		}
+		matched := false
+		for _, t := range rts {
+			if len(want) > 0 && !want[t.Property.Name] {
+				continue
+			}
+			matched = true
+			if seen[t.Address.String()] {
+				continue
+			}
+			seen[t.Address.String()] = true
+			ts = append(ts, t)
+		}
+		if !matched {
+			return nil, errors.Errorf(codes.NotFound, "no targets match %s with properties %s", str, strings.Join(props, ", "))
+		}
+	}
+	return ts, nil
+}
+
+// renderPlain renders lines of text as a plain table.
+func renderPlain(lines []string) (string, error) {
+	tbl, err := table.Split(lines, ifs, -1)
+	if err != nil {
+		return "", fmt.Errorf("error instantiating a table; %s", err)
+	}
+	rndr := &render.PlainRenderer{}
+	rndr.SetOFS(ofs)
+	return rndr.Render(tbl), nil
+}
+
 var assetNames map[string]bool
 
-// mustTemplate checks that a template name is known, or returns an error.
-func mustTemplate(name string) error {
+// mustTemplate checks that a template name is known, or panics.
+func mustTemplate(name string) {
 	if assetNames == nil {
 		// Cache the asset names.
 		assetNames = map[string]bool{}
@@ -90,7 +184,6 @@ func mustTemplate(name string) error {
 		}
 	}
 	if _, ok := assetNames[name]; !ok {
-		return fmt.Errorf("asset name %s unknown", name)
+		panic(fmt.Sprintf("asset name %s unknown", name))
 	}
-	return nil
 }
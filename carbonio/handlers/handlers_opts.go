@@ -1,16 +1,35 @@
 package handlers
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kward/avid-s3l/carbonio/auth"
+	"github.com/kward/avid-s3l/carbonio/events"
+)
 
 type options struct {
 	// Local flags.
-	port int
-	raw  bool
+	port   int
+	raw    bool
+	types  []string
+	props  []string
+	addrs  []string
+	sort   string
+	format string
+	// Global flags.
+	dryRun      bool
+	host        string
+	snapshotDir string
+	yes         bool
+	// Server state.
+	watcher *events.Watcher
+	auth    *auth.Authenticator
 }
 
 func (o *options) validate() error {
-	if o.port == 0 {
-		return fmt.Errorf("port option missing")
+	if o.port < 0 {
+		return fmt.Errorf("invalid port %d", o.port)
 	}
 	return nil
 }
@@ -30,3 +49,118 @@ func (o *options) setRaw(v bool) error {
 	o.raw = v
 	return nil
 }
+
+// DryRun returns whether changes should only be reported, not made.
+func DryRun(v bool) func(*options) error {
+	return func(o *options) error { return o.setDryRun(v) }
+}
+func (o *options) setDryRun(v bool) error {
+	o.dryRun = v
+	return nil
+}
+
+// Host returns the remote carbonio server to use instead of a local device, as
+// `host[:port]`.
+func Host(v string) func(*options) error {
+	return func(o *options) error { return o.setHost(v) }
+}
+func (o *options) setHost(v string) error {
+	o.host = v
+	return nil
+}
+
+// AssumeYes returns whether confirmations are assumed to be answered with yes.
+func AssumeYes(v bool) func(*options) error {
+	return func(o *options) error { return o.setAssumeYes(v) }
+}
+func (o *options) setAssumeYes(v bool) error {
+	o.yes = v
+	return nil
+}
+
+// SnapshotDir returns the directory where snapshots are stored.
+func SnapshotDir(v string) func(*options) error {
+	return func(o *options) error { return o.setSnapshotDir(v) }
+}
+func (o *options) setSnapshotDir(v string) error {
+	if v == "" {
+		return fmt.Errorf("snapshot dir is empty")
+	}
+	o.snapshotDir = v
+	return nil
+}
+
+// Watcher returns the watcher that publishes changes of the device values.
+// The watcher is polled after each change made by the handlers, so that
+// subscribers are notified immediately.
+func Watcher(v *events.Watcher) func(*options) error {
+	return func(o *options) error { return o.setWatcher(v) }
+}
+func (o *options) setWatcher(v *events.Watcher) error {
+	o.watcher = v
+	return nil
+}
+
+// Auth returns the authenticator of HTTP requests. Without it, all requests
+// are allowed.
+func Auth(v *auth.Authenticator) func(*options) error {
+	return func(o *options) error { return o.setAuth(v) }
+}
+func (o *options) setAuth(v *auth.Authenticator) error {
+	o.auth = v
+	return nil
+}
+
+// Types returns the node types to list, e.g. `inputs` or `leds`.
+func Types(v []string) func(*options) error {
+	return func(o *options) error { return o.setTypes(v) }
+}
+func (o *options) setTypes(v []string) error {
+	for _, t := range v {
+		if _, ok := types[t]; !ok {
+			return fmt.Errorf("unknown type %q; want inputs, outputs, or leds", t)
+		}
+	}
+	o.types = v
+	return nil
+}
+
+// Properties returns the properties to list.
+func Properties(v []string) func(*options) error {
+	return func(o *options) error { return o.setProperties(v) }
+}
+func (o *options) setProperties(v []string) error {
+	o.props = v
+	return nil
+}
+
+// Addresses returns the addresses to list.
+func Addresses(v []string) func(*options) error {
+	return func(o *options) error { return o.setAddresses(v) }
+}
+func (o *options) setAddresses(v []string) error {
+	o.addrs = v
+	return nil
+}
+
+// SortBy returns the column to sort by. Prefix the column with `-` to sort in
+// descending order.
+func SortBy(v string) func(*options) error {
+	return func(o *options) error { return o.setSortBy(v) }
+}
+func (o *options) setSortBy(v string) error {
+	o.sort = v
+	return nil
+}
+
+// Format returns the output format, e.g. `table` or `json`.
+func Format(v string) func(*options) error {
+	return func(o *options) error { return o.setFormat(v) }
+}
+func (o *options) setFormat(v string) error {
+	if !validFormat(v) {
+		return fmt.Errorf("unsupported format %q; want one of %s", v, strings.Join(Formats(), ", "))
+	}
+	o.format = v
+	return nil
+}
@@ -1,9 +1,54 @@
 package handlers
 
-import "testing"
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kward/avid-s3l/carbonio/devices"
+	"github.com/kward/avid-s3l/carbonio/devices/devicestest"
+)
 
 func TestHandlers(t *testing.T) {
 	if _, err := NewHandlers(nil); err == nil {
 		t.Errorf("expected an error")
 	}
 }
+
+func newDevice(t *testing.T) (devices.Device, func()) {
+	dir, err := ioutil.TempDir("", "handlers")
+	if err != nil {
+		t.Fatalf("error creating temp dir; %s", err)
+	}
+	d, err := devicestest.NewStage16(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("error instantiating Stage16; %s", err)
+	}
+	return d, func() { os.RemoveAll(dir) }
+}
+
+// newServer returns a test server of the API of a new device.
+func newServer(t *testing.T) (*Handlers, *httptest.Server, func()) {
+	d, cleanup := newDevice(t)
+	dir, err := ioutil.TempDir("", "snapshots")
+	if err != nil {
+		cleanup()
+		t.Fatalf("error creating temp dir; %s", err)
+	}
+	h, err := NewHandlers(d, SnapshotDir(dir))
+	if err != nil {
+		cleanup()
+		t.Fatalf("unexpected error; %s", err)
+	}
+	r := mux.NewRouter()
+	h.RegisterAPI(r)
+	srv := httptest.NewServer(r)
+	return h, srv, func() {
+		srv.Close()
+		os.RemoveAll(dir)
+		cleanup()
+	}
+}
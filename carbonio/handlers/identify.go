@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/kward/avid-s3l/carbonio/address"
+)
+
+// identifyInterval is the time between LED toggles while identifying.
+const identifyInterval = 250 * time.Millisecond
+
+// IdentifyCommand flashes the LEDs of the device for the given duration, making
+// it easier to recognize which device is being controlled. The LEDs are
+// restored to their original states afterwards.
+func (h *Handlers) IdentifyCommand(w io.Writer, d time.Duration) error {
+	ts, err := h.resolve([]string{address.LEDRoot}, []string{address.StateProperty})
+	if err != nil {
+		return err
+	}
+	orig := map[*address.Target]interface{}{}
+	for _, t := range ts {
+		v, err := t.Value()
+		if err != nil {
+			return err
+		}
+		orig[t] = v
+	}
+	if h.opts.dryRun {
+		_, err := fmt.Fprintf(w, "would flash %d LEDs for %s\n", len(ts), d)
+		return err
+	}
+
+	fmt.Fprintf(w, "flashing %d LEDs for %s\n", len(ts), d)
+	var flashErr error
+	on := true
+	for end := time.Now().Add(d); time.Now().Before(end); on = !on {
+		state := "Off"
+		if on {
+			state = "On"
+		}
+		for _, t := range ts {
+			if err := t.SetValue(state); err != nil {
+				flashErr = err
+				break
+			}
+		}
+		if flashErr != nil {
+			break
+		}
+		time.Sleep(identifyInterval)
+	}
+
+	// Always restore the original states.
+	for _, t := range ts {
+		if err := t.SetValue(orig[t]); err != nil && flashErr == nil {
+			flashErr = err
+		}
+	}
+	return flashErr
+}
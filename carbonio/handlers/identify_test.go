@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestIdentifyCommand(t *testing.T) {
+	d, cleanup := newDevice(t)
+	defer cleanup()
+	h, err := NewHandlers(d)
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	if err := h.SetCommand(&bytes.Buffer{}, []string{"led/status=alert"}, nil); err != nil {
+		t.Fatalf("SetCommand() unexpected error; %s", err)
+	}
+	if err := h.IdentifyCommand(&bytes.Buffer{}, 10*time.Millisecond); err != nil {
+		t.Fatalf("IdentifyCommand() unexpected error; %s", err)
+	}
+
+	// The LEDs should be restored.
+	buf := &bytes.Buffer{}
+	if err := h.GetCommand(buf, []string{"led"}, nil); err != nil {
+		t.Fatalf("GetCommand() unexpected error; %s", err)
+	}
+	if got, want := buf.String(), "ADDRESS          VALUE\nled/power/state  Off\nled/status/state Alert\nled/mute/state   Off\n"; got != want {
+		t.Errorf("LEDs =\n%s\nwant\n%s", got, want)
+	}
+}
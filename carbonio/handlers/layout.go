@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/kward/avid-s3l/carbonio/address"
+	"github.com/kward/avid-s3l/carbonio/layouts"
+)
+
+// layoutTitle is the title of the generated layouts.
+const layoutTitle = "carbonio"
+
+// LayoutCommand writes a control surface layout of the targets matched by the
+// addresses, all the inputs by default, in the layout format, e.g.
+// layouts.TouchOSC.
+func (h *Handlers) LayoutCommand(w io.Writer, format string, addrs []string) error {
+	if len(addrs) == 0 {
+		addrs = []string{address.InputRoot}
+	}
+	ts, err := h.resolve(addrs, nil)
+	if err != nil {
+		return err
+	}
+	l, err := layouts.New(layoutTitle, ts)
+	if err != nil {
+		return err
+	}
+	data, err := layouts.Encode(l, format)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("error writing layout; %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/kward/avid-s3l/carbonio/layouts"
+)
+
+func TestLayoutCommand(t *testing.T) {
+	d, cleanup := newDevice(t)
+	defer cleanup()
+	h, err := NewHandlers(d)
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+
+	for _, tc := range []struct {
+		desc   string
+		ok     bool
+		format string
+		addrs  []string
+	}{
+		{"all inputs", true, layouts.OpenStageControl, nil},
+		{"some inputs", true, layouts.TouchOSC, []string{"input/mic/1-8"}},
+		{"leds", false, layouts.TouchOSC, []string{"led"}},
+		{"unknown address", false, layouts.TouchOSC, []string{"input/mic/99"}},
+		{"unknown format", false, "lemur", nil},
+	} {
+		t.Run(fmt.Sprintf("LayoutCommand() %s", tc.desc), func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			err := h.LayoutCommand(buf, tc.format, tc.addrs)
+			if err == nil && !tc.ok {
+				t.Fatal("expected an error")
+			}
+			if err != nil {
+				if tc.ok {
+					t.Fatalf("unexpected error; %s", err)
+				}
+				return
+			}
+			if buf.Len() == 0 {
+				t.Error("empty layout")
+			}
+		})
+	}
+}
@@ -4,14 +4,12 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"log"
 	"net"
 	"net/http"
 
-	"github.com/kward/avid-s3l/carbonio/devices"
-	"github.com/kward/avid-s3l/carbonio/helpers"
-	"github.com/kward/tabulate/render"
-	"github.com/kward/tabulate/table"
+	"github.com/kward/avid-s3l/carbonio/api"
+	"github.com/kward/golib/errors"
+	"google.golang.org/grpc/codes"
 )
 
 const listTmpl = "html/list.tmpl"
@@ -20,23 +18,20 @@ func init() {
 	mustTemplate(listTmpl)
 }
 
-func (h *Handlers) ListCommand(w io.Writer) {
-	str, err := list(h.device, h.opts.raw)
+// ListCommand writes the listing of the device.
+func (h *Handlers) ListCommand(w io.Writer) error {
+	str, err := h.renderView(h.listView(), h.opts.format, api.ListKind)
 	if err != nil {
-		// TODO(2020-02-23) Add an error to `h` instead of exiting here.
-		helpers.Exit(fmt.Sprintf("error gathering list information; %s", err))
+		return err
 	}
-
-	_, err = io.WriteString(w, str)
-	if err != nil {
-		helpers.Exit(fmt.Sprintf("error writing list information; %s", err))
+	if _, err := io.WriteString(w, str); err != nil {
+		return fmt.Errorf("error writing list information; %s", err)
 	}
+	return nil
 }
 
+// ListHandler returns the list page.
 func (h *Handlers) ListHandler(w http.ResponseWriter, r *http.Request) {
-	buf := &bytes.Buffer{}
-	stts := http.StatusOK
-
 	data := struct {
 		Title string
 		Host  net.IP
@@ -46,93 +41,36 @@ func (h *Handlers) ListHandler(w http.ResponseWriter, r *http.Request) {
 		Host:  h.device.IP(),
 		Port:  h.opts.port,
 	}
-	if err := tmpls[listTmpl].Execute(io.Writer(buf), data); err != nil {
-		stts = http.StatusInternalServerError
-		w.WriteHeader(stts)
-		log.Printf("error executing template; %s", err)
-	}
-
-	l := 0
-	if stts == http.StatusOK {
-		var err error
-		l, err = w.Write(buf.Bytes())
-		if err != nil {
-			stts = http.StatusInternalServerError
-			w.WriteHeader(stts)
-		}
+	buf := &bytes.Buffer{}
+	if err := tmpls[listTmpl].Execute(buf, data); err != nil {
+		writeError(w, r, errors.Errorf(codes.Internal, "error executing template; %s", err))
+		return
 	}
-
-	helpers.CommonLogFormat(r, stts, l)
+	writeBody(w, htmlContentType, buf.Bytes())
 }
 
+// ListQueryHandler returns the listing in the format negotiated with the
+// client. The listing is configured with the `type`, `property`, `address`, and
+// `sort` query parameters.
 func (h *Handlers) ListQueryHandler(w http.ResponseWriter, r *http.Request) {
-	buf := &bytes.Buffer{}
-	stts := http.StatusOK
-
-	page, err := list(h.device, h.opts.raw)
-	if err != nil {
-		stts = http.StatusInternalServerError
-		w.WriteHeader(stts)
-		log.Printf("%s", err)
+	format := negotiate(r, TableFormat)
+	if format == "" {
+		writeError(w, r, notAcceptable())
+		return
 	}
-
-	if stts == http.StatusOK {
-		buf.WriteString(page)
-	}
-
-	l := 0
-	if stts == http.StatusOK {
-		l, err = w.Write(buf.Bytes())
-		if err != nil {
-			stts = http.StatusInternalServerError
-			w.WriteHeader(stts)
-		}
+	page, err := h.renderView(queryView(r.URL.Query(), h.listView()), format, api.ListKind)
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
-
-	helpers.CommonLogFormat(r, stts, l)
+	writeBody(w, contentTypes[format], []byte(page))
 }
 
-var boolToStr = map[bool]string{
-	true:  "On",
-	false: "Off",
-}
-
-func list(device devices.Device, asRaw bool) (string, error) {
-	lines := []string{"SIGNAL GAIN PAD PHANTOM"}
-
-	for i := 1; i <= device.NumMicInputs(); i++ {
-		s, err := device.MicInput(i)
-		if err != nil {
-			log.Printf("error accessing mic input %d; %s", i, err)
-			continue
-		}
-
-		if asRaw {
-			lines = append(lines, fmt.Sprintf("input/mic/%d %q %q %q", i,
-				s.Gain().Raw(), s.Pad().Raw(), s.Phantom().Raw()))
-			continue
-		}
-		gain, err := s.Gain().Value()
-		if err != nil {
-			log.Printf("error reading mic input %d gain; %s", i, err)
-		}
-		pad, err := s.Pad().IsEnabled()
-		if err != nil {
-			log.Printf("error read mic input %d pad; %s", i, err)
-		}
-		phantom, err := s.Phantom().IsEnabled()
-		if err != nil {
-			log.Printf("error read mic input %d phantom; %s", i, err)
-		}
-		lines = append(lines, fmt.Sprintf("input/mic/%d %d %s %s", i,
-			gain, boolToStr[pad], boolToStr[phantom]))
-	}
-
-	tbl, err := table.Split(lines, ifs, -1)
+// renderView applies the view, and renders the listing in the format.
+func (h *Handlers) renderView(v *view, format, kind string) (string, error) {
+	l, err := h.list(v)
 	if err != nil {
-		return "", fmt.Errorf("error instantiating a table; %s", err)
+		return "", err
 	}
-	rndr := &render.PlainRenderer{}
-	rndr.SetOFS(ofs)
-	return rndr.Render(tbl), nil
+	return l.render(format, kind, v.raw)
 }
@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/kward/avid-s3l/carbonio/events"
+	"github.com/kward/golib/errors"
+	"google.golang.org/grpc/codes"
+)
+
+const (
+	// liveBuffer is the number of events buffered for each streaming client.
+	// Clients that fall further behind are disconnected.
+	liveBuffer = 64
+	// liveWriteWait is the time allowed to write a message to a client.
+	liveWriteWait = 10 * time.Second
+	// livePongWait is the time allowed to read the next pong from a client.
+	livePongWait = 60 * time.Second
+	// livePingPeriod is the period of pings to a client. It is shorter than
+	// livePongWait, so that a pong is due before the read deadline.
+	livePingPeriod = livePongWait * 9 / 10
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+}
+
+// LiveHandler pushes the device state to a WebSocket client. The first message
+// is a `snapshot` event with all the values, followed by an event for each
+// value that changes. Events with an ID up to that of the snapshot are already
+// included in the snapshot.
+func (h *Handlers) LiveHandler(w http.ResponseWriter, r *http.Request) {
+	if h.opts.watcher == nil {
+		writeError(w, r, errors.Errorf(codes.Unavailable, "live updates are not available"))
+		return
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already responded to the client.
+		log.Printf("error upgrading to websocket; %s", err)
+		return
+	}
+	defer conn.Close()
+
+	// Subscribe before taking the snapshot, so that no change is missed.
+	bus := h.opts.watcher.Bus()
+	sub := bus.Subscribe(liveBuffer)
+	defer bus.Unsubscribe(sub)
+
+	// Read until the client goes away, handling pongs and close messages.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		conn.SetReadLimit(512)
+		conn.SetReadDeadline(time.Now().Add(livePongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(livePongWait))
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	snap, err := h.opts.watcher.Snapshot()
+	if err != nil {
+		log.Printf("error taking snapshot; %s", err)
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.CloseInternalServerErr, "error reading values"),
+			time.Now().Add(liveWriteWait))
+		return
+	}
+	conn.SetWriteDeadline(time.Now().Add(liveWriteWait))
+	if err := conn.WriteJSON(snap); err != nil {
+		return
+	}
+
+	ping := time.NewTicker(livePingPeriod)
+	defer ping.Stop()
+	for {
+		select {
+		case e, ok := <-sub.C:
+			conn.SetWriteDeadline(time.Now().Add(liveWriteWait))
+			if !ok {
+				// The client fell too far behind, and must resync.
+				conn.WriteMessage(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "too far behind"))
+				return
+			}
+			if e.ID <= snap.ID {
+				continue // Included in the snapshot.
+			}
+			if err := conn.WriteJSON(e); err != nil {
+				return
+			}
+		case <-ping.C:
+			conn.SetWriteDeadline(time.Now().Add(liveWriteWait))
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		case <-done:
+			return
+		case <-r.Context().Done():
+			// The server is shutting down.
+			conn.SetWriteDeadline(time.Now().Add(liveWriteWait))
+			conn.WriteMessage(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"))
+			return
+		}
+	}
+}
+
+// Server-Sent Events.
+const (
+	// sseRetry is the reconnection time that is suggested to clients.
+	sseRetry = 3 * time.Second
+	// sseKeepAlive is the period of comments that keep idle streams open
+	// through proxies.
+	sseKeepAlive = 15 * time.Second
+)
+
+// lastEventID returns the ID of the last event that the client received, from
+// the `Last-Event-ID` header that browsers send on reconnect, or the
+// `last_event_id` query parameter. Zero means none.
+func lastEventID(r *http.Request) (uint64, error) {
+	s := r.Header.Get("Last-Event-ID")
+	if s == "" {
+		s = r.URL.Query().Get("last_event_id")
+	}
+	if s == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, errors.Errorf(codes.InvalidArgument, "invalid last event ID %q", s)
+	}
+	return id, nil
+}
+
+// EventsHandler streams the changes of the device as Server-Sent Events, with
+// the event type as the SSE event name. A new stream starts with a `snapshot`
+// event holding all the values. A client that resumes with `Last-Event-ID`
+// instead receives the events it missed, if they are still in the backlog, or
+// a new snapshot otherwise. The `type` query parameters limit the streamed
+// event types.
+func (h *Handlers) EventsHandler(w http.ResponseWriter, r *http.Request) {
+	if h.opts.watcher == nil {
+		writeError(w, r, errors.Errorf(codes.Unavailable, "events are not available"))
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, r, errors.Errorf(codes.Internal, "streaming is not supported"))
+		return
+	}
+	id, err := lastEventID(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	want := map[string]bool{}
+	for _, t := range queryList(r, "type") {
+		switch t {
+		case events.ParameterType, events.LEDType, events.HealthType:
+			want[t] = true
+		default:
+			writeError(w, r, errors.Errorf(codes.InvalidArgument, "unknown event type %q; want %s, %s, or %s",
+				t, events.ParameterType, events.LEDType, events.HealthType))
+			return
+		}
+	}
+
+	bus := h.opts.watcher.Bus()
+	sub, missed, resumed := bus.SubscribeSince(id, liveBuffer)
+	defer bus.Unsubscribe(sub)
+	if !resumed || id == 0 {
+		snap, err := h.opts.watcher.Snapshot()
+		if err != nil {
+			writeError(w, r, errors.Errorf(codes.Unavailable, "error reading values; %s", err))
+			return
+		}
+		missed = []*events.Event{snap}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", sseRetry/time.Millisecond)
+
+	last := uint64(0) // ID of the last written event.
+	write := func(e *events.Event) error {
+		if e.ID <= last && e.Type != events.SnapshotType {
+			return nil // Already written, or included in the snapshot.
+		}
+		last = e.ID
+		if len(want) > 0 && !want[e.Type] && e.Type != events.SnapshotType {
+			return nil
+		}
+		data, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("error marshaling event; %s", err)
+		}
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	}
+	for _, e := range missed {
+		if err := write(e); err != nil {
+			return
+		}
+	}
+
+	keepAlive := time.NewTicker(sseKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case e, ok := <-sub.C:
+			if !ok {
+				return // Too far behind; the client resumes with Last-Event-ID.
+			}
+			if err := write(e); err != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/kward/avid-s3l/carbonio/address"
+	"github.com/kward/avid-s3l/carbonio/events"
+)
+
+// newLiveServer returns a test server of the API and live updates of a new
+// device.
+func newLiveServer(t *testing.T) (*httptest.Server, func()) {
+	d, cleanup := newDevice(t)
+	dir, err := ioutil.TempDir("", "snapshots")
+	if err != nil {
+		cleanup()
+		t.Fatalf("error creating temp dir; %s", err)
+	}
+	w := events.NewWatcher(events.NewBus(), address.NewDeviceResolver(d), time.Hour)
+	h, err := NewHandlers(d, SnapshotDir(dir), Watcher(w))
+	if err != nil {
+		cleanup()
+		t.Fatalf("unexpected error; %s", err)
+	}
+	r := mux.NewRouter()
+	r.HandleFunc("/ws", h.LiveHandler)
+	h.RegisterAPI(r)
+	srv := httptest.NewServer(r)
+	return srv, func() {
+		srv.Close()
+		os.RemoveAll(dir)
+		cleanup()
+	}
+}
+
+// patchGain sets the gain of input/mic/1 through the API.
+func patchGain(t *testing.T, srv *httptest.Server, gain int) {
+	req, err := http.NewRequest(http.MethodPatch, srv.URL+APIPrefix+"/values",
+		strings.NewReader(fmt.Sprintf(`{"values": {"input/mic/1/gain": %d}}`, gain)))
+	if err != nil {
+		t.Fatalf("error creating request; %s", err)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("error patching values; %s", err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("PATCH status = %d, want %d", res.StatusCode, http.StatusOK)
+	}
+}
+
+func TestLiveHandler(t *testing.T) {
+	srv, cleanup := newLiveServer(t)
+	defer cleanup()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(srv.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatalf("error dialing; %s", err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	snap := &events.Event{}
+	if err := conn.ReadJSON(snap); err != nil {
+		t.Fatalf("error reading snapshot; %s", err)
+	}
+	if snap.Type != events.SnapshotType {
+		t.Errorf("first event type = %q, want %q", snap.Type, events.SnapshotType)
+	}
+	const addr = "input/mic/1/gain"
+	if _, ok := snap.Values[addr]; !ok {
+		t.Fatalf("snapshot is missing %s", addr)
+	}
+
+	// Changes made through the server are pushed without waiting for a poll.
+	patchGain(t, srv, 42)
+
+	e := &events.Event{}
+	if err := conn.ReadJSON(e); err != nil {
+		t.Fatalf("error reading event; %s", err)
+	}
+	if e.Type != events.ParameterType || e.Address != addr || e.Value != float64(42) {
+		t.Errorf("event = %s %s %v, want %s %s 42", e.Type, e.Address, e.Value, events.ParameterType, addr)
+	}
+	if e.ID <= snap.ID {
+		t.Errorf("event ID %d is not after snapshot ID %d", e.ID, snap.ID)
+	}
+}
+
+func TestLiveHandlerUnavailable(t *testing.T) {
+	h, _, cleanup := newServer(t)
+	defer cleanup()
+	rec := httptest.NewRecorder()
+	h.LiveHandler(rec, httptest.NewRequest(http.MethodGet, "/ws", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusServiceUnavailable)
+	}
+}
+
+// readEvent reads the next Server-Sent Event, skipping other fields.
+func readEvent(t *testing.T, r *bufio.Reader) (string, *events.Event) {
+	name := ""
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("error reading stream; %s", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			e := &events.Event{}
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), e); err != nil {
+				t.Fatalf("error decoding event; %s", err)
+			}
+			return name, e
+		}
+	}
+}
+
+// getEvents opens an event stream, resuming after lastID if not zero.
+func getEvents(t *testing.T, srv *httptest.Server, lastID uint64) (*bufio.Reader, func()) {
+	req, err := http.NewRequest(http.MethodGet, srv.URL+APIPrefix+"/events", nil)
+	if err != nil {
+		t.Fatalf("error creating request; %s", err)
+	}
+	if lastID != 0 {
+		req.Header.Set("Last-Event-ID", fmt.Sprint(lastID))
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("error getting events; %s", err)
+	}
+	if got, want := res.Header.Get("Content-Type"), "text/event-stream"; got != want {
+		t.Fatalf("Content-Type = %q, want %q", got, want)
+	}
+	return bufio.NewReader(res.Body), func() { res.Body.Close() }
+}
+
+func TestEventsHandler(t *testing.T) {
+	srv, cleanup := newLiveServer(t)
+	defer cleanup()
+
+	r, done := getEvents(t, srv, 0)
+	name, snap := readEvent(t, r)
+	if name != events.SnapshotType || snap.Type != events.SnapshotType {
+		t.Fatalf("first event = %s, want %s", name, events.SnapshotType)
+	}
+	patchGain(t, srv, 42)
+	name, e := readEvent(t, r)
+	if name != events.ParameterType || e.Address != "input/mic/1/gain" || e.Value != float64(42) {
+		t.Errorf("event = %s %s %v, want %s input/mic/1/gain 42", name, e.Address, e.Value, events.ParameterType)
+	}
+	done()
+
+	// Changes made while disconnected are replayed on resume.
+	patchGain(t, srv, 43)
+	r, done = getEvents(t, srv, e.ID)
+	defer done()
+	name, e = readEvent(t, r)
+	if name != events.ParameterType || e.Value != float64(43) {
+		t.Errorf("resumed event = %s %v, want %s 43", name, e.Value, events.ParameterType)
+	}
+}
+
+func TestEventsHandlerErrors(t *testing.T) {
+	srv, cleanup := newLiveServer(t)
+	defer cleanup()
+	for _, tc := range []struct {
+		desc  string
+		query string
+		stts  int
+	}{
+		{"invalid type", "?type=bogus", http.StatusBadRequest},
+		{"invalid last event ID", "?last_event_id=x", http.StatusBadRequest},
+	} {
+		t.Run(fmt.Sprintf("EventsHandler() %s", tc.desc), func(t *testing.T) {
+			res, err := http.Get(srv.URL + APIPrefix + "/events" + tc.query)
+			if err != nil {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			res.Body.Close()
+			if res.StatusCode != tc.stts {
+				t.Errorf("status = %d, want %d", res.StatusCode, tc.stts)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/kward/avid-s3l/carbonio/api"
+	"github.com/kward/avid-s3l/carbonio/auth"
+)
+
+// OpenAPIVersion is the version of the OpenAPI specification of the document.
+const OpenAPIVersion = "3.0.3"
+
+// pathVarRE matches the gorilla/mux variables of a path, e.g. `{number:[0-9]+}`.
+var pathVarRE = regexp.MustCompile(`\{([^}:]+)(?::([^}]+))?\}`)
+
+// OpenAPIPath converts a gorilla/mux path template into an OpenAPI path, e.g.
+// `/a/{number:[0-9]+}` into `/a/{number}`.
+func OpenAPIPath(tmpl string) string {
+	return pathVarRE.ReplaceAllString(tmpl, "{$1}")
+}
+
+// OpenAPI returns the OpenAPI description of the API, generated from the
+// routes that are registered by RegisterAPI.
+func (h *Handlers) OpenAPI() map[string]interface{} {
+	s := &schemas{defs: map[string]interface{}{}, names: map[reflect.Type]string{}}
+	errResponse := map[string]interface{}{
+		"description": "Error",
+		"content":     jsonContent(s.of(reflect.TypeOf(&api.Error{}))),
+	}
+
+	paths := map[string]interface{}{}
+	for _, rt := range h.APIRoutes() {
+		// Path parameters.
+		params := []interface{}{}
+		for _, m := range pathVarRE.FindAllStringSubmatch(rt.Path, -1) {
+			schema := map[string]interface{}{"type": "string"}
+			if m[2] != "" {
+				schema["pattern"] = "^" + m[2] + "$"
+			}
+			params = append(params, map[string]interface{}{
+				"name":     m[1],
+				"in":       "path",
+				"required": true,
+				"schema":   schema,
+			})
+		}
+
+		ops := map[string]interface{}{}
+		for _, op := range rt.Ops {
+			o := map[string]interface{}{
+				"summary":   op.Summary,
+				"responses": map[string]interface{}{"default": errResponse},
+			}
+			if op.Role > auth.Viewer {
+				o["description"] = fmt.Sprintf("Requires the %s role, when authentication is enabled.", op.Role)
+			}
+			ps := append([]interface{}{}, params...)
+			for _, q := range op.Query {
+				ps = append(ps, map[string]interface{}{
+					"name":        q.Name,
+					"in":          "query",
+					"description": q.Description,
+					"schema":      map[string]interface{}{"type": "string"},
+				})
+			}
+			if len(ps) > 0 {
+				o["parameters"] = ps
+			}
+			if op.Request != nil {
+				o["requestBody"] = map[string]interface{}{
+					"required": true,
+					"content":  jsonContent(s.of(reflect.TypeOf(op.Request))),
+				}
+			}
+			status := op.Status
+			if status == 0 {
+				status = http.StatusOK
+			}
+			resp := map[string]interface{}{"description": http.StatusText(status)}
+			if op.Response != nil {
+				ct := op.ContentType
+				if ct == "" {
+					ct = contentTypes[JSONFormat]
+				}
+				resp["content"] = map[string]interface{}{
+					ct: map[string]interface{}{"schema": s.of(reflect.TypeOf(op.Response))},
+				}
+			}
+			o["responses"].(map[string]interface{})[fmt.Sprint(status)] = resp
+			ops[strings.ToLower(op.Method)] = o
+		}
+		paths[OpenAPIPath(rt.Path)] = ops
+	}
+
+	return map[string]interface{}{
+		"openapi": OpenAPIVersion,
+		"info": map[string]interface{}{
+			"title":       "carbonio",
+			"description": "Control of the Avid Carbon I/O device.",
+			"version":     fmt.Sprint(api.Version),
+		},
+		"paths": paths,
+		// Authentication is optional; see the auth package.
+		"security": []interface{}{
+			map[string]interface{}{},
+			map[string]interface{}{"bearer": []string{}},
+			map[string]interface{}{"basic": []string{}},
+		},
+		"components": map[string]interface{}{
+			"schemas": s.defs,
+			"securitySchemes": map[string]interface{}{
+				"bearer": map[string]interface{}{"type": "http", "scheme": "bearer"},
+				"basic":  map[string]interface{}{"type": "http", "scheme": "basic"},
+			},
+		},
+	}
+}
+
+// OpenAPIHandler returns the OpenAPI description of the API.
+func (h *Handlers) OpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusOK, h.OpenAPI())
+}
+
+func jsonContent(schema interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"application/json": map[string]interface{}{"schema": schema},
+	}
+}
+
+// schemas generates JSON schemas of Go types, using their `json` field tags.
+// Structs are defined once, and referenced by name.
+type schemas struct {
+	defs  map[string]interface{}
+	names map[reflect.Type]string
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// of returns the schema of the type.
+func (s *schemas) of(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return s.of(t.Elem())
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": s.of(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": s.of(t.Elem())}
+	case reflect.Struct:
+		if t == timeType {
+			return map[string]interface{}{"type": "string", "format": "date-time"}
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + s.define(t)}
+	}
+	return map[string]interface{}{} // Any value.
+}
+
+// define the schema of a struct, and return its name.
+func (s *schemas) define(t reflect.Type) string {
+	if name, ok := s.names[t]; ok {
+		return name
+	}
+	name := t.Name()
+	if _, ok := s.defs[name]; ok {
+		// Qualify the name with the package, e.g. `SnapshotsList`.
+		pkg := t.PkgPath()[strings.LastIndex(t.PkgPath(), "/")+1:]
+		name = strings.Title(pkg) + name
+	}
+	s.names[t] = name
+	s.defs[name] = nil // Reserve the name, for recursive types.
+
+	props := map[string]interface{}{}
+	required := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue // Unexported.
+		}
+		tag := strings.Split(f.Tag.Get("json"), ",")
+		if tag[0] == "-" {
+			continue
+		}
+		fname := tag[0]
+		if fname == "" {
+			fname = f.Name
+		}
+		props[fname] = s.of(f.Type)
+		omitempty := false
+		for _, opt := range tag[1:] {
+			omitempty = omitempty || opt == "omitempty"
+		}
+		if !omitempty {
+			required = append(required, fname)
+		}
+	}
+	def := map[string]interface{}{"type": "object", "properties": props}
+	if len(required) > 0 {
+		def["required"] = required
+	}
+	s.defs[name] = def
+	return name
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+// TestRemote verifies that remote commands produce the same output as local
+// commands, using two devices with the same initial state.
+func TestRemote(t *testing.T) {
+	local, _, cleanup := newServer(t)
+	defer cleanup()
+	_, srv, cleanup := newServer(t)
+	defer cleanup()
+	remote, err := NewHandlers(nil, Host(srv.URL))
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+
+	for _, tc := range []struct {
+		desc string
+		cmd  func(h *Handlers, w *bytes.Buffer) error
+	}{
+		{"set", func(h *Handlers, w *bytes.Buffer) error {
+			return h.SetCommand(w, []string{"input/mic/1-2/phantom=on", "input/mic/1/gain=40"}, nil)
+		}},
+		{"get", func(h *Handlers, w *bytes.Buffer) error {
+			return h.GetCommand(w, []string{"input/mic/1", "led"}, nil)
+		}},
+		{"list", func(h *Handlers, w *bytes.Buffer) error {
+			h.ListCommand(w)
+			return nil
+		}},
+		{"status", func(h *Handlers, w *bytes.Buffer) error {
+			h.StatusCommand(w)
+			return nil
+		}},
+		{"snap", func(h *Handlers, w *bytes.Buffer) error {
+			return h.SnapCommand(w, []string{"input@a"}, nil)
+		}},
+		{"snap show", func(h *Handlers, w *bytes.Buffer) error {
+			return h.SnapShowCommand(w, "@a")
+		}},
+		{"rollback", func(h *Handlers, w *bytes.Buffer) error {
+			if err := h.SetCommand(&bytes.Buffer{}, []string{"input/mic/1/gain=20"}, nil); err != nil {
+				return err
+			}
+			return h.RollbackCommand(w, "@a")
+		}},
+		{"destroy", func(h *Handlers, w *bytes.Buffer) error {
+			return h.DestroyCommand(w, "@a")
+		}},
+	} {
+		t.Run(fmt.Sprintf("remote %s", tc.desc), func(t *testing.T) {
+			want := &bytes.Buffer{}
+			if err := tc.cmd(local, want); err != nil {
+				t.Fatalf("local unexpected error; %s", err)
+			}
+			got := &bytes.Buffer{}
+			if err := tc.cmd(remote, got); err != nil {
+				t.Fatalf("remote unexpected error; %s", err)
+			}
+			if got.String() != want.String() {
+				t.Errorf("remote output =\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/kward/avid-s3l/carbonio/address"
+	"github.com/kward/avid-s3l/carbonio/api"
+	"github.com/kward/golib/errors"
+	"google.golang.org/grpc/codes"
+)
+
+// APIPrefix is the path prefix of the versioned HTTP API.
+const APIPrefix = "/api/v1"
+
+// DeviceHandler describes the device.
+func (h *Handlers) DeviceHandler(w http.ResponseWriter, r *http.Request) {
+	doc := &api.Device{
+		Version:   api.Version,
+		Kind:      api.DeviceKind,
+		IP:        h.device.IP().String(),
+		MicInputs: h.device.NumMicInputs(),
+		LEDs:      []string{},
+		Links: map[string]string{
+			"signals":   APIPrefix + "/signals",
+			"leds":      APIPrefix + "/leds",
+			"targets":   APIPrefix + "/targets",
+			"values":    APIPrefix + "/values",
+			"snapshots": APIPrefix + "/snapshots",
+		},
+	}
+	ts, err := h.resolve([]string{address.LEDRoot}, nil)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	for _, n := range address.Nodes(ts) {
+		doc.LEDs = append(doc.LEDs, n.Address.Base())
+	}
+	writeJSON(w, r, http.StatusOK, doc)
+}
+
+// SignalsHandler lists the signals. The listing is configured with the
+// `property`, `address`, and `sort` query parameters, as for /list_query.
+func (h *Handlers) SignalsHandler(w http.ResponseWriter, r *http.Request) {
+	// TODO(2020-03-01) Add outputs once they are supported.
+	h.nodesHandler(w, r, &view{types: []string{"inputs"}}, api.SignalsKind)
+}
+
+// LEDsHandler lists the LEDs.
+func (h *Handlers) LEDsHandler(w http.ResponseWriter, r *http.Request) {
+	h.nodesHandler(w, r, &view{types: []string{"leds"}}, api.LEDsKind)
+}
+
+func (h *Handlers) nodesHandler(w http.ResponseWriter, r *http.Request, def *view, kind string) {
+	v := queryView(r.URL.Query(), def)
+	v.types = def.types // The resource determines the type.
+	l, err := h.list(v)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, l.document(kind, false))
+}
+
+// nodeAddress returns the address of the signal or LED named by the route
+// variables. Signals are named by `direction`, `type`, and `number`, and LEDs
+// by `name`.
+func nodeAddress(r *http.Request) (address.Address, error) {
+	vars := mux.Vars(r)
+	var a address.Address
+	var err error
+	if name, ok := vars["name"]; ok {
+		a, err = address.Parse(address.LEDRoot + address.Separator + name)
+	} else {
+		switch vars["direction"] {
+		case address.InputRoot, address.OutputRoot:
+		default:
+			return a, errors.Errorf(codes.NotFound, "unknown signal direction %q; want %s or %s", vars["direction"], address.InputRoot, address.OutputRoot)
+		}
+		a, err = address.Parse(strings.Join([]string{vars["direction"], vars["type"], vars["number"]}, address.Separator))
+	}
+	if err != nil {
+		return a, err
+	}
+	if !a.IsConcrete() {
+		return a, errors.Errorf(codes.InvalidArgument, "%s does not name a single signal or LED", a)
+	}
+	return a, nil
+}
+
+// node returns the item of a single signal or LED.
+func (h *Handlers) node(a address.Address) (*api.Item, error) {
+	l, err := h.list(&view{addrs: []string{a.String()}})
+	if err != nil {
+		return nil, err
+	}
+	items := l.document("", false).Items
+	if len(items) != 1 || items[0].Address != a.String() {
+		return nil, errors.Errorf(codes.NotFound, "%s not found", a)
+	}
+	return items[0], nil
+}
+
+// NodeHandler gets (GET) or changes (PATCH, PUT) a single signal or LED. The
+// body of a change is an item with the new property values. A PUT must include
+// all the editable properties; a PATCH may include any of them. The changes
+// are applied as a single transaction.
+func (h *Handlers) NodeHandler(w http.ResponseWriter, r *http.Request) {
+	a, err := nodeAddress(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	item, err := h.node(a)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if r.Method == http.MethodGet {
+		writeJSON(w, r, http.StatusOK, item)
+		return
+	}
+
+	body := &api.Item{}
+	dec := json.NewDecoder(r.Body)
+	dec.UseNumber()
+	if err := dec.Decode(body); err != nil {
+		writeError(w, r, errors.Errorf(codes.InvalidArgument, "error decoding item; %s", err))
+		return
+	}
+	if body.Address != "" && body.Address != a.String() {
+		writeError(w, r, errors.Errorf(codes.InvalidArgument, "item address %s does not match %s", body.Address, a))
+		return
+	}
+	values := map[string]interface{}{}
+	for p, v := range body.Properties {
+		if _, ok := item.Properties[p]; !ok {
+			writeError(w, r, errors.Errorf(codes.InvalidArgument, "%s has no property %q", a, p))
+			return
+		}
+		values[a.String()+address.Separator+p] = v
+	}
+	if r.Method == http.MethodPut {
+		ts, err := h.resolve([]string{a.String()}, nil)
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		for _, t := range ts {
+			if _, ok := body.Properties[t.Property.Name]; !ok && t.Property.Editable {
+				writeError(w, r, errors.Errorf(codes.InvalidArgument, "missing property %q; a PUT must include all editable properties", t.Property.Name))
+				return
+			}
+		}
+	}
+	if err := h.setValues(r, values); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if item, err = h.node(a); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeJSON(w, r, http.StatusOK, item)
+}
+
+// ParameterHandler gets (GET) or changes (PUT) a single parameter of a signal
+// or LED, e.g. /api/v1/signals/input/mic/1/gain. The body of a change is an
+// api.Value.
+func (h *Handlers) ParameterHandler(w http.ResponseWriter, r *http.Request) {
+	n, err := nodeAddress(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	a, err := n.Join(mux.Vars(r)["property"])
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if !a.IsConcrete() {
+		writeError(w, r, errors.Errorf(codes.InvalidArgument, "%s does not name a single parameter", a))
+		return
+	}
+
+	if r.Method == http.MethodPut {
+		body := &api.Value{}
+		dec := json.NewDecoder(r.Body)
+		dec.UseNumber()
+		if err := dec.Decode(body); err != nil {
+			writeError(w, r, errors.Errorf(codes.InvalidArgument, "error decoding value; %s", err))
+			return
+		}
+		if err := h.setValues(r, map[string]interface{}{a.String(): body.Value}); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+
+	ts, err := h.resolve([]string{a.String()}, nil)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	t := ts[0]
+	v, err := t.Value()
+	if err != nil {
+		writeError(w, r, errors.Errorf(codes.Internal, "%s", err))
+		return
+	}
+	writeJSON(w, r, http.StatusOK, apiTarget(t, v))
+}
+
+// setValues writes the values, keyed by address, as a single transaction, if
+// the principal of the request may change them.
+func (h *Handlers) setValues(r *http.Request, values map[string]interface{}) error {
+	cs, err := h.valueChanges(values)
+	if err != nil {
+		return err
+	}
+	if err := h.authorizeChanges(r, cs); err != nil {
+		return err
+	}
+	_, err = h.transact(cs, false)
+	return err
+}
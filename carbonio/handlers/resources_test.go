@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestAPIResources(t *testing.T) {
+	_, srv, cleanup := newServer(t)
+	defer cleanup()
+
+	for _, tc := range []struct {
+		desc   string
+		method string
+		path   string
+		body   string
+		status int
+		// Expected value at the key path of the JSON response, if any.
+		key, want string
+	}{
+		{"device", "GET", "/api/v1/device", "", http.StatusOK, "mic_inputs", "16"},
+		{"signals", "GET", "/api/v1/signals?address=input/mic/1-2", "", http.StatusOK, "items.1.address", "input/mic/2"},
+		{"signal", "GET", "/api/v1/signals/input/mic/3", "", http.StatusOK, "properties.gain", "10"},
+		{"signal patch", "PATCH", "/api/v1/signals/input/mic/3", `{"properties": {"gain": 30, "phantom": true}}`, http.StatusOK, "properties.phantom", "true"},
+		{"signal put", "PUT", "/api/v1/signals/input/mic/3", `{"properties": {"gain": 20, "pad": true, "phantom": false}}`, http.StatusOK, "properties.gain", "20"},
+		{"signal put partial", "PUT", "/api/v1/signals/input/mic/3", `{"properties": {"gain": 20}}`, http.StatusBadRequest, "error.code", "InvalidArgument"},
+		{"parameter", "GET", "/api/v1/signals/input/mic/3/pad", "", http.StatusOK, "value", "true"},
+		{"parameter put", "PUT", "/api/v1/signals/input/mic/3/gain", `{"value": 50}`, http.StatusOK, "value", "50"},
+		{"leds", "GET", "/api/v1/leds", "", http.StatusOK, "items.0.address", "led/power"},
+		{"led put", "PUT", "/api/v1/leds/status/state", `{"value": "Alert"}`, http.StatusOK, "value", "Alert"},
+
+		// Errors.
+		{"out of range", "PUT", "/api/v1/signals/input/mic/3/gain", `{"value": 99}`, http.StatusBadRequest, "error.code", "OutOfRange"},
+		{"invalid value", "PATCH", "/api/v1/signals/input/mic/3", `{"properties": {"pad": "maybe"}}`, http.StatusBadRequest, "", ""},
+		{"unknown property", "PATCH", "/api/v1/signals/input/mic/3", `{"properties": {"trim": 1}}`, http.StatusBadRequest, "", ""},
+		{"unknown signal", "GET", "/api/v1/signals/input/mic/99", "", http.StatusNotFound, "error.code", "NotFound"},
+		{"range", "GET", "/api/v1/signals/input/mic/1-4", "", http.StatusNotFound, "", ""},
+		{"output", "GET", "/api/v1/signals/output/line/1", "", http.StatusNotImplemented, "error.code", "Unimplemented"},
+		{"bad json", "PATCH", "/api/v1/leds/mute", `{`, http.StatusBadRequest, "", ""},
+		{"method", "DELETE", "/api/v1/leds/mute", "", http.StatusMethodNotAllowed, "", ""},
+		{"unknown resource", "GET", "/api/v1/nothing", "", http.StatusNotFound, "error.code", "NotFound"},
+	} {
+		t.Run(fmt.Sprintf("%s %s", tc.method, tc.desc), func(t *testing.T) {
+			req, err := http.NewRequest(tc.method, srv.URL+tc.path, strings.NewReader(tc.body))
+			if err != nil {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			defer resp.Body.Close()
+			data, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			if got, want := resp.StatusCode, tc.status; got != want {
+				t.Errorf("status = %d, want %d\n%s", got, want, data)
+			}
+			if got, want := resp.Header.Get("Content-Type"), "application/json"; got != want {
+				t.Errorf("Content-Type = %s, want %s", got, want)
+			}
+			if tc.key == "" {
+				return
+			}
+			var doc interface{}
+			if err := json.Unmarshal(data, &doc); err != nil {
+				t.Fatalf("error decoding %s; %s", data, err)
+			}
+			if got, want := lookup(doc, tc.key), tc.want; got != want {
+				t.Errorf("%s = %s, want %s", tc.key, got, want)
+			}
+		})
+	}
+}
+
+// lookup returns the value at a dot separated key path of a decoded JSON
+// document, e.g. `items.0.address`.
+func lookup(doc interface{}, key string) string {
+	for _, k := range strings.Split(key, ".") {
+		switch d := doc.(type) {
+		case map[string]interface{}:
+			doc = d[k]
+		case []interface{}:
+			var i int
+			if _, err := fmt.Sscan(k, &i); err != nil || i >= len(d) {
+				return ""
+			}
+			doc = d[i]
+		default:
+			return ""
+		}
+	}
+	return fmt.Sprint(doc)
+}
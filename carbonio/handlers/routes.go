@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/kward/avid-s3l/carbonio/api"
+	"github.com/kward/avid-s3l/carbonio/auth"
+	"github.com/kward/avid-s3l/carbonio/events"
+	"github.com/kward/avid-s3l/carbonio/snapshots"
+	"github.com/kward/golib/errors"
+	"google.golang.org/grpc/codes"
+)
+
+// Route of the HTTP API. The routes are used both to register the handlers,
+// and to generate the OpenAPI description of the API.
+type Route struct {
+	// Path of the route, with gorilla/mux variables, e.g. `/api/v1/leds/{name}`.
+	Path string
+	Ops  []*Op
+}
+
+// Op is an operation of a route, i.e. the handling of a single method.
+type Op struct {
+	Method  string
+	Handler http.HandlerFunc
+	Summary string
+	// Query holds the query parameters.
+	Query []*Param
+	// Request is an example value of the request body, if any.
+	Request interface{}
+	// Response is an example value of a successful response body, if any.
+	Response interface{}
+	// Status of a successful response. Defaults to 200.
+	Status int
+	// ContentType of a successful response. Defaults to JSON.
+	ContentType string
+	// Role required for the operation. Defaults to viewer. Changes of values
+	// may require a higher role, depending on the changed values.
+	Role auth.Role
+}
+
+// Param describes a query parameter. All parameters are strings.
+type Param struct {
+	Name        string
+	Description string
+}
+
+// Query parameters.
+var (
+	addressParam  = &Param{"address", "addresses to match; repeated or comma separated"}
+	propertyParam = &Param{"property", "properties to include; repeated or comma separated"}
+	sortParam     = &Param{"sort", "column to sort by; prefix with `-` for descending order"}
+	dryRunParam   = &Param{"dry_run", "when true, nothing is changed"}
+	typeParam     = &Param{"type", "event types to stream (parameter, led, health); repeated or comma separated"}
+	lastIDParam   = &Param{"last_event_id", "resume after this event ID, like the Last-Event-ID header"}
+)
+
+// Paths of the signal and LED resources.
+const (
+	signalPath = APIPrefix + "/signals/{direction}/{type}/{number:[0-9]+}"
+	ledPath    = APIPrefix + "/leds/{name}"
+)
+
+// APIRoutes returns the routes of the versioned HTTP API.
+func (h *Handlers) APIRoutes() []*Route {
+	nodeOps := func(what string) []*Op {
+		return []*Op{
+			{Method: http.MethodGet, Handler: h.NodeHandler, Summary: "Get " + what + ".",
+				Response: &api.Item{}},
+			{Method: http.MethodPatch, Handler: h.NodeHandler, Summary: "Change some properties of " + what + ".",
+				Request: &api.Item{}, Response: &api.Item{}, Role: auth.Operator},
+			{Method: http.MethodPut, Handler: h.NodeHandler, Summary: "Change all the editable properties of " + what + ".",
+				Request: &api.Item{}, Response: &api.Item{}, Role: auth.Operator},
+		}
+	}
+	parameterOps := func(what string) []*Op {
+		return []*Op{
+			{Method: http.MethodGet, Handler: h.ParameterHandler, Summary: "Get a parameter of " + what + ".",
+				Response: &api.Target{}},
+			{Method: http.MethodPut, Handler: h.ParameterHandler, Summary: "Change a parameter of " + what + ".",
+				Request: &api.Value{}, Response: &api.Target{}, Role: auth.Operator},
+		}
+	}
+
+	return []*Route{
+		{APIPrefix + "/openapi.json", []*Op{
+			{Method: http.MethodGet, Handler: h.OpenAPIHandler, Summary: "Get the OpenAPI description of the API.",
+				Response: map[string]interface{}{}},
+		}},
+		{APIPrefix + "/device", []*Op{
+			{Method: http.MethodGet, Handler: h.DeviceHandler, Summary: "Get the device.",
+				Response: &api.Device{}},
+		}},
+		{APIPrefix + "/signals", []*Op{
+			{Method: http.MethodGet, Handler: h.SignalsHandler, Summary: "List the signals.",
+				Query: []*Param{propertyParam, addressParam, sortParam}, Response: &api.List{}},
+		}},
+		{signalPath, nodeOps("a signal")},
+		{signalPath + "/{property}", parameterOps("a signal")},
+		{APIPrefix + "/leds", []*Op{
+			{Method: http.MethodGet, Handler: h.LEDsHandler, Summary: "List the LEDs.",
+				Query: []*Param{propertyParam, addressParam, sortParam}, Response: &api.List{}},
+		}},
+		{ledPath, nodeOps("an LED")},
+		{ledPath + "/{property}", parameterOps("an LED")},
+		{APIPrefix + "/targets", []*Op{
+			{Method: http.MethodGet, Handler: h.TargetsHandler, Summary: "Describe the addressable values.",
+				Query: []*Param{addressParam, propertyParam}, Response: &api.Targets{}},
+		}},
+		{APIPrefix + "/values", []*Op{
+			{Method: http.MethodGet, Handler: h.ValuesHandler, Summary: "Get values by address.",
+				Query: []*Param{addressParam, propertyParam}, Response: &api.Values{}},
+			{Method: http.MethodPatch, Handler: h.PatchValuesHandler, Summary: "Change values by address, as a single transaction.",
+				Query: []*Param{dryRunParam}, Request: &api.Values{}, Response: &api.Changes{}, Role: auth.Operator},
+		}},
+		{APIPrefix + "/events", []*Op{
+			{Method: http.MethodGet, Handler: h.EventsHandler, Summary: "Stream the changes of the device as Server-Sent Events.",
+				Query: []*Param{typeParam, lastIDParam}, Response: &events.Event{}, ContentType: "text/event-stream"},
+		}},
+		{APIPrefix + "/snapshots", []*Op{
+			{Method: http.MethodGet, Handler: h.SnapshotsHandler, Summary: "List the snapshots.",
+				Response: &snapshots.List{}},
+		}},
+		{APIPrefix + "/snapshots/{name}", []*Op{
+			{Method: http.MethodGet, Handler: h.SnapshotHandler, Summary: "Get a snapshot.",
+				Response: &snapshots.Snapshot{}},
+			{Method: http.MethodPut, Handler: h.SnapshotHandler, Summary: "Save a snapshot.",
+				Request: &snapshots.Snapshot{}, Response: &snapshots.Snapshot{}, Role: auth.Admin},
+			{Method: http.MethodDelete, Handler: h.SnapshotHandler, Summary: "Delete a snapshot.",
+				Status: http.StatusNoContent, Role: auth.Admin},
+		}},
+	}
+}
+
+// RegisterAPI registers the routes of the versioned HTTP API with the router.
+// The role of each operation is enforced, but requests must already be
+// authenticated (see Authenticate). Unknown resources and methods get JSON
+// error responses.
+func (h *Handlers) RegisterAPI(r *mux.Router) {
+	sr := r.PathPrefix(APIPrefix).Subrouter()
+	for _, rt := range h.APIRoutes() {
+		for _, op := range rt.Ops {
+			sr.HandleFunc(strings.TrimPrefix(rt.Path, APIPrefix), h.requireRole(op.Role, op.Handler)).Methods(op.Method)
+		}
+	}
+	sr.NotFoundHandler = http.HandlerFunc(apiNotFoundHandler)
+	sr.MethodNotAllowedHandler = http.HandlerFunc(apiMethodNotAllowedHandler)
+}
+
+func apiNotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	writeError(w, r, errors.Errorf(codes.NotFound, "unknown resource %s", r.URL.Path))
+}
+
+func apiMethodNotAllowedHandler(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, r, http.StatusMethodNotAllowed, &api.Error{Error: &api.ErrorDetail{
+		Code:    codes.Unimplemented.String(),
+		Message: fmt.Sprintf("method %s is not allowed for %s", r.Method, r.URL.Path),
+	}})
+}
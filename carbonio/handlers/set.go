@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"github.com/kward/avid-s3l/carbonio/changes"
+	"github.com/kward/golib/errors"
+	"google.golang.org/grpc/codes"
+)
+
+// assignment of a value to the targets matched by an address.
+type assignment struct {
+	addr  string
+	prop  string // Optional.
+	value string
+}
+
+// parseAssignments parses `address=value` arguments. Arguments without a value
+// are combined with each of the `property=value` properties.
+func parseAssignments(args, props []string) ([]assignment, error) {
+	as := []assignment{}
+	for _, arg := range args {
+		if i := strings.Index(arg, "="); i >= 0 {
+			as = append(as, assignment{arg[:i], "", arg[i+1:]})
+			continue
+		}
+		if len(props) == 0 {
+			return nil, errors.Errorf(codes.InvalidArgument, "missing value for %s", arg)
+		}
+		for _, p := range props {
+			i := strings.Index(p, "=")
+			if i < 0 {
+				return nil, errors.Errorf(codes.InvalidArgument, "invalid property %q; want property=value", p)
+			}
+			as = append(as, assignment{arg, p[:i], p[i+1:]})
+		}
+	}
+	if len(as) == 0 {
+		return nil, errors.Errorf(codes.InvalidArgument, "nothing to set")
+	}
+	return as, nil
+}
+
+// SetCommand writes new values to the targets matched by the assignments. The
+// assignments are given as `address=value` arguments, or as addresses that are
+// combined with `property=value` properties. The changes are applied as a
+// single transaction (see the changes package).
+func (h *Handlers) SetCommand(w io.Writer, args, props []string) error {
+	as, err := parseAssignments(args, props)
+	if err != nil {
+		return err
+	}
+
+	// Resolve and validate all the values before changing anything.
+	cs := []*changes.Change{}
+	idx := map[string]*changes.Change{}
+	for _, a := range as {
+		var props []string
+		if a.prop != "" {
+			props = []string{a.prop}
+		}
+		ts, err := h.resolve([]string{a.addr}, props)
+		if err != nil {
+			return err
+		}
+		for _, t := range ts {
+			v, err := t.Property.Parse(a.value)
+			if err != nil {
+				return errors.Errorf(errors.Code(err), "%s; %s", t.Address, errors.ErrorDesc(err))
+			}
+			if c, ok := idx[t.Address.String()]; ok {
+				c.To = v // The last assignment wins.
+				continue
+			}
+			c, err := changes.New(t, v)
+			if err != nil {
+				return err
+			}
+			idx[t.Address.String()] = c
+			cs = append(cs, c)
+		}
+	}
+	return h.apply(w, cs)
+}
+
+// apply the changes, and write a table of the results.
+func (h *Handlers) apply(w io.Writer, cs []*changes.Change) error {
+	var status map[*changes.Change]string
+	var applyErr error
+	if h.client != nil {
+		status, applyErr = h.remoteTransact(cs)
+	} else {
+		status, applyErr = h.transact(cs, h.opts.dryRun)
+	}
+	if status == nil {
+		return applyErr
+	}
+
+	lines := []string{"ADDRESS FROM TO STATUS"}
+	for _, c := range changes.Order(cs) {
+		p := c.Target.Property
+		lines = append(lines, fmt.Sprintf("%s %s %s %s", c.Target.Address, p.Format(c.From), p.Format(c.To), status[c]))
+	}
+	str, err := renderPlain(lines)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, str); err != nil {
+		return fmt.Errorf("error writing results; %s", err)
+	}
+	return applyErr
+}
+
+// transact applies the changes as a single transaction, and returns the status
+// of each change. Unchanged values are reported, but not written. On failure of
+// the transaction, the statuses are returned together with the error.
+func (h *Handlers) transact(cs []*changes.Change, dryRun bool) (map[*changes.Change]string, error) {
+	status := map[*changes.Change]string{}
+	todo := []*changes.Change{}
+	for _, c := range cs {
+		switch {
+		case c.From == c.To:
+			status[c] = "unchanged"
+		case dryRun:
+			status[c] = "dry-run"
+		default:
+			todo = append(todo, c)
+		}
+	}
+	if len(todo) == 0 {
+		return status, nil
+	}
+
+	applied, err := changes.Apply(todo)
+	h.changed()
+	switch e := err.(type) {
+	case nil:
+		for _, c := range applied {
+			status[c] = "ok"
+		}
+	case *changes.Error:
+		for _, c := range todo {
+			status[c] = "skipped"
+		}
+		for _, c := range e.Reverted {
+			status[c] = "reverted"
+		}
+		status[e.Failed] = "FAILED"
+		return status, errors.Errorf(codes.Aborted, "%s", e)
+	default:
+		return nil, err
+	}
+	return status, nil
+}
+
+// changed notifies the watcher, if any, that values have changed.
+func (h *Handlers) changed() {
+	if h.opts.watcher == nil {
+		return
+	}
+	if err := h.opts.watcher.Poll(); err != nil {
+		log.Printf("error polling values; %s", err)
+	}
+}
+
+// remoteTransact applies the changes as a single transaction on the remote
+// server, and returns the status of each change.
+func (h *Handlers) remoteTransact(cs []*changes.Change) (map[*changes.Change]string, error) {
+	values := map[string]interface{}{}
+	for _, c := range cs {
+		values[c.Target.Address.String()] = c.To
+	}
+	items, err := h.client.SetValues(values, h.opts.dryRun)
+	if len(items) == 0 {
+		return nil, err
+	}
+	byAddr := map[string]string{}
+	for _, item := range items {
+		byAddr[item.Address] = item.Status
+	}
+	status := map[*changes.Change]string{}
+	for _, c := range cs {
+		status[c] = byAddr[c.Target.Address.String()]
+	}
+	return status, err
+}
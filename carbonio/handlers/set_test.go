@@ -0,0 +1,97 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestParseAssignments(t *testing.T) {
+	for _, tc := range []struct {
+		desc string
+		ok   bool
+
+		args, props []string
+		want        []assignment
+	}{
+		// Valid assignments.
+		{"address", true, []string{"input/mic/1/gain=30"}, nil,
+			[]assignment{{"input/mic/1/gain", "", "30"}}},
+		{"properties", true, []string{"input/mic/1-4"}, []string{"gain=30", "pad=on"},
+			[]assignment{{"input/mic/1-4", "gain", "30"}, {"input/mic/1-4", "pad", "on"}}},
+		{"mixed", true, []string{"led/status=on", "input/mic/2"}, []string{"pad=off"},
+			[]assignment{{"led/status", "", "on"}, {"input/mic/2", "pad", "off"}}},
+
+		// Invalid assignments.
+		{desc: "missing value", args: []string{"input/mic/1/gain"}},
+		{desc: "invalid property", args: []string{"input/mic/1"}, props: []string{"gain"}},
+		{desc: "nothing"},
+	} {
+		t.Run(fmt.Sprintf("parseAssignments() %s", tc.desc), func(t *testing.T) {
+			as, err := parseAssignments(tc.args, tc.props)
+			if err != nil && tc.ok {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			if err == nil && !tc.ok {
+				t.Fatal("expected an error")
+			}
+			if !tc.ok {
+				return
+			}
+			if got, want := fmt.Sprint(as), fmt.Sprint(tc.want); got != want {
+				t.Errorf("= %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestSetCommand(t *testing.T) {
+	d, cleanup := newDevice(t)
+	defer cleanup()
+
+	for _, tc := range []struct {
+		desc   string
+		ok     bool
+		dryRun bool
+
+		args, props []string
+		addr, value string // Value to verify with get.
+	}{
+		// Valid assignments.
+		{"gain", true, false, []string{"input/mic/1/gain=30"}, nil, "input/mic/1/gain", "30"},
+		{"gain dry run", true, true, []string{"input/mic/1/gain=40"}, nil, "input/mic/1/gain", "30"},
+		{"phantoms", true, false, []string{"input/mic/1-3"}, []string{"phantom=on"}, "input/mic/2/phantom", "On"},
+		{"led", true, false, []string{"led/status=alert"}, nil, "led/status", "Alert"},
+
+		// Invalid assignments. Nothing should change.
+		{desc: "out of range", args: []string{"input/mic/1/gain=99"}, addr: "input/mic/1/gain", value: "30"},
+		{desc: "partly invalid", args: []string{"input/mic/1/gain=50", "led/mute=alert"}, addr: "input/mic/1/gain", value: "30"},
+		{desc: "unknown address", args: []string{"input/mic/99/gain=50"}, addr: "input/mic/1/gain", value: "30"},
+	} {
+		t.Run(fmt.Sprintf("SetCommand() %s", tc.desc), func(t *testing.T) {
+			h, err := NewHandlers(d, DryRun(tc.dryRun))
+			if err != nil {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			err = h.SetCommand(&bytes.Buffer{}, tc.args, tc.props)
+			if err != nil && tc.ok {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			if err == nil && !tc.ok {
+				t.Fatal("expected an error")
+			}
+
+			ts, err := h.resolve([]string{tc.addr}, nil)
+			if err != nil {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			v, err := ts[0].Value()
+			if err != nil {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			if got, want := ts[0].Property.Format(v), tc.value; got != want {
+				t.Errorf("%s = %s, want %s", tc.addr, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/kward/avid-s3l/carbonio/address"
+	"github.com/kward/avid-s3l/carbonio/snapshots"
+	"github.com/kward/golib/errors"
+	"google.golang.org/grpc/codes"
+)
+
+// parseSnapName parses a `@name` snapshot argument.
+func parseSnapName(arg string) (string, error) {
+	if !strings.HasPrefix(arg, snapshots.Prefix) {
+		return "", errors.Errorf(codes.InvalidArgument, "invalid snapshot %q; want %sname", arg, snapshots.Prefix)
+	}
+	name := strings.TrimPrefix(arg, snapshots.Prefix)
+	if err := snapshots.ValidName(name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// parseSnapArgs parses `[address]...@name` arguments into the addresses and the
+// snapshot name. The name is given once, either on its own or attached to an
+// address, e.g. `input@pre-show`. Without addresses, the whole device is
+// snapshotted.
+func parseSnapArgs(args []string) ([]string, string, error) {
+	addrs := []string{}
+	name := ""
+	for _, arg := range args {
+		i := strings.Index(arg, snapshots.Prefix)
+		if i < 0 {
+			addrs = append(addrs, arg)
+			continue
+		}
+		if name != "" {
+			return nil, "", errors.Errorf(codes.InvalidArgument, "more than one snapshot name given")
+		}
+		var err error
+		if name, err = parseSnapName(arg[i:]); err != nil {
+			return nil, "", err
+		}
+		if i > 0 {
+			addrs = append(addrs, arg[:i])
+		}
+	}
+	if name == "" {
+		return nil, "", errors.Errorf(codes.InvalidArgument, "missing snapshot name; want [address]...%sname", snapshots.Prefix)
+	}
+	if len(addrs) == 0 {
+		addrs = []string{address.Wildcard}
+	}
+	return addrs, name, nil
+}
+
+// SnapCommand takes a snapshot of the targets matched by the addresses,
+// optionally limited to the named properties. An existing snapshot of the same
+// name is replaced.
+func (h *Handlers) SnapCommand(w io.Writer, args, props []string) error {
+	addrs, name, err := parseSnapArgs(args)
+	if err != nil {
+		return err
+	}
+	ts, err := h.resolve(addrs, props)
+	if err != nil {
+		return err
+	}
+	s, err := snapshots.Take(name, addrs, ts)
+	if err != nil {
+		return err
+	}
+	if h.opts.dryRun {
+		_, err = fmt.Fprintf(w, "would snapshot %d values to %s%s\n", len(s.Values), snapshots.Prefix, name)
+		return err
+	}
+	if err := h.snapshots.Save(s); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "snapshotted %d values to %s%s\n", len(s.Values), snapshots.Prefix, name)
+	return err
+}
+
+// SnapListCommand writes the list of snapshots.
+func (h *Handlers) SnapListCommand(w io.Writer) error {
+	ss, err := h.snapshots.List()
+	if err != nil {
+		return err
+	}
+	lines := []string{"NAME CREATED VALUES ADDRESSES"}
+	for _, s := range ss {
+		lines = append(lines, fmt.Sprintf("%s%s %s %d %s",
+			snapshots.Prefix, s.Name, s.Created.Local().Format(time.RFC3339), len(s.Values), strings.Join(s.Addresses, ",")))
+	}
+	return writePlain(w, lines)
+}
+
+// SnapShowCommand writes the values of the named snapshot.
+func (h *Handlers) SnapShowCommand(w io.Writer, arg string) error {
+	s, err := h.loadSnapshot(arg)
+	if err != nil {
+		return err
+	}
+	ts, err := h.snapshotTargets(s)
+	if err != nil {
+		return err
+	}
+	lines := []string{"ADDRESS VALUE"}
+	for _, k := range s.Keys() {
+		lines = append(lines, fmt.Sprintf("%s %s", k, formatValue(ts[k], s.Values[k])))
+	}
+	return writePlain(w, lines)
+}
+
+// SnapDiffCommand writes the differences between the live values and the named
+// snapshot.
+func (h *Handlers) SnapDiffCommand(w io.Writer, arg string) error {
+	s, err := h.loadSnapshot(arg)
+	if err != nil {
+		return err
+	}
+	ts, err := h.snapshotTargets(s)
+	if err != nil {
+		return err
+	}
+	lines := []string{"ADDRESS LIVE SNAPSHOT"}
+	for _, k := range s.Keys() {
+		t, ok := ts[k]
+		if !ok {
+			lines = append(lines, fmt.Sprintf("%s - %s", k, formatValue(nil, s.Values[k])))
+			continue
+		}
+		live, err := t.Value()
+		if err != nil {
+			return err
+		}
+		want, err := t.Property.Normalize(s.Values[k])
+		if err != nil {
+			return errors.Errorf(errors.Code(err), "%s; %s", k, errors.ErrorDesc(err))
+		}
+		if live == want {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("%s %s %s", k, t.Property.Format(live), t.Property.Format(want)))
+	}
+	return writePlain(w, lines)
+}
+
+// RollbackCommand rolls the device back to the named snapshot. The rollback is
+// applied as a single transaction.
+func (h *Handlers) RollbackCommand(w io.Writer, arg string) error {
+	s, err := h.loadSnapshot(arg)
+	if err != nil {
+		return err
+	}
+	ts, err := h.resolve(s.Addresses, nil)
+	if err != nil {
+		return err
+	}
+	cs, err := s.Changes(ts)
+	if err != nil {
+		return err
+	}
+	if len(cs) == 0 {
+		_, err := fmt.Fprintf(w, "already at %s%s\n", snapshots.Prefix, s.Name)
+		return err
+	}
+	return h.apply(w, cs)
+}
+
+// DestroyCommand deletes the named snapshot.
+func (h *Handlers) DestroyCommand(w io.Writer, arg string) error {
+	name, err := parseSnapName(arg)
+	if err != nil {
+		return err
+	}
+	if h.opts.dryRun {
+		if _, err := h.snapshots.Load(name); err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "would destroy %s%s\n", snapshots.Prefix, name)
+		return err
+	}
+	if err := h.snapshots.Delete(name); err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "destroyed %s%s\n", snapshots.Prefix, name)
+	return err
+}
+
+// loadSnapshot loads the snapshot named by a `@name` argument.
+func (h *Handlers) loadSnapshot(arg string) (*snapshots.Snapshot, error) {
+	name, err := parseSnapName(arg)
+	if err != nil {
+		return nil, err
+	}
+	return h.snapshots.Load(name)
+}
+
+// snapshotTargets returns the live targets of the snapshot, by address.
+func (h *Handlers) snapshotTargets(s *snapshots.Snapshot) (map[string]*address.Target, error) {
+	ts, err := h.resolve(s.Addresses, nil)
+	if err != nil {
+		return nil, err
+	}
+	m := map[string]*address.Target{}
+	for _, t := range ts {
+		m[t.Address.String()] = t
+	}
+	return m, nil
+}
+
+// formatValue formats a snapshot value using the property of the target, if
+// known.
+func formatValue(t *address.Target, v interface{}) string {
+	if t == nil {
+		return fmt.Sprintf("%v", v)
+	}
+	n, err := t.Property.Normalize(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return t.Property.Format(n)
+}
+
+// writePlain writes the lines as a plain table.
+func writePlain(w io.Writer, lines []string) error {
+	str, err := renderPlain(lines)
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(w, str); err != nil {
+		return fmt.Errorf("error writing table; %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseSnapArgs(t *testing.T) {
+	for _, tc := range []struct {
+		desc string
+		ok   bool
+
+		args  []string
+		addrs []string
+		name  string
+	}{
+		{"name only", true, []string{"@pre"}, []string{"*"}, "pre"},
+		{"attached", true, []string{"input@pre"}, []string{"input"}, "pre"},
+		{"separate", true, []string{"input/mic/1-4", "led", "@pre"}, []string{"input/mic/1-4", "led"}, "pre"},
+		{desc: "missing name", args: []string{"input"}},
+		{desc: "two names", args: []string{"@a", "@b"}},
+		{desc: "invalid name", args: []string{"@a/b"}},
+	} {
+		t.Run(fmt.Sprintf("parseSnapArgs() %s", tc.desc), func(t *testing.T) {
+			addrs, name, err := parseSnapArgs(tc.args)
+			if err != nil && tc.ok {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			if err == nil && !tc.ok {
+				t.Fatal("expected an error")
+			}
+			if !tc.ok {
+				return
+			}
+			if got, want := fmt.Sprint(addrs), fmt.Sprint(tc.addrs); got != want {
+				t.Errorf("addresses = %s, want %s", got, want)
+			}
+			if got, want := name, tc.name; got != want {
+				t.Errorf("name = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestRollbackCommand(t *testing.T) {
+	d, cleanup := newDevice(t)
+	defer cleanup()
+	dir, err := ioutil.TempDir("", "snapshots")
+	if err != nil {
+		t.Fatalf("error creating temp dir; %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	h, err := NewHandlers(d, SnapshotDir(dir))
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	set := func(args ...string) {
+		if err := h.SetCommand(&bytes.Buffer{}, args, nil); err != nil {
+			t.Fatalf("SetCommand() unexpected error; %s", err)
+		}
+	}
+	get := func(addr string) string {
+		buf := &bytes.Buffer{}
+		if err := h.GetCommand(buf, []string{addr}, nil); err != nil {
+			t.Fatalf("GetCommand() unexpected error; %s", err)
+		}
+		return strings.Fields(buf.String())[3]
+	}
+
+	set("input/mic/1/gain=40", "input/mic/1/phantom=on")
+	if err := h.SnapCommand(&bytes.Buffer{}, []string{"@a"}, nil); err != nil {
+		t.Fatalf("SnapCommand() unexpected error; %s", err)
+	}
+	set("input/mic/1/gain=20", "input/mic/1/phantom=off", "led/status=on")
+
+	buf := &bytes.Buffer{}
+	if err := h.SnapDiffCommand(buf, "@a"); err != nil {
+		t.Fatalf("SnapDiffCommand() unexpected error; %s", err)
+	}
+	if got, want := strings.Count(buf.String(), "\n"), 4; got != want {
+		t.Errorf("SnapDiffCommand() = %d lines, want %d\n%s", got, want, buf)
+	}
+
+	if err := h.RollbackCommand(&bytes.Buffer{}, "@a"); err != nil {
+		t.Fatalf("RollbackCommand() unexpected error; %s", err)
+	}
+	for addr, want := range map[string]string{
+		"input/mic/1/gain":    "40",
+		"input/mic/1/phantom": "On",
+		"led/status":          "Off",
+	} {
+		if got := get(addr); got != want {
+			t.Errorf("%s = %s, want %s", addr, got, want)
+		}
+	}
+
+	if err := h.DestroyCommand(&bytes.Buffer{}, "@a"); err != nil {
+		t.Fatalf("DestroyCommand() unexpected error; %s", err)
+	}
+	if err := h.RollbackCommand(&bytes.Buffer{}, "@a"); err == nil {
+		t.Error("RollbackCommand() expected an error for a destroyed snapshot")
+	}
+}
@@ -4,93 +4,67 @@ import (
 	"bytes"
 	"fmt"
 	"io"
-	"log"
 	"net/http"
 
-	"github.com/kward/avid-s3l/carbonio/devices"
-	"github.com/kward/avid-s3l/carbonio/helpers"
-	"github.com/kward/avid-s3l/carbonio/leds"
-	"github.com/kward/tabulate/render"
-	"github.com/kward/tabulate/table"
+	"github.com/kward/avid-s3l/carbonio/api"
+	"github.com/kward/golib/errors"
+	"google.golang.org/grpc/codes"
 )
 
 const statusTmpl = "html/status.tmpl"
 
+// htmlFormat is the default format of the status page.
+const htmlFormat = "html"
+
 func init() {
 	mustTemplate(statusTmpl)
 }
 
-func (h *Handlers) StatusCommand(w io.Writer) {
-	str, err := status(h.device, h.opts.raw)
+// StatusCommand writes the status of the device.
+func (h *Handlers) StatusCommand(w io.Writer) error {
+	str, err := h.renderView(h.statusView(), h.opts.format, api.StatusKind)
 	if err != nil {
-		helpers.Exit(fmt.Sprintf("error gathering status information; %s", err))
+		return err
 	}
-
-	_, err = io.WriteString(w, str)
-	if err != nil {
-		helpers.Exit(fmt.Sprintf("error writing status information; %s", err))
+	if _, err := io.WriteString(w, str); err != nil {
+		return fmt.Errorf("error writing status information; %s", err)
 	}
+	return nil
 }
 
+// StatusHandler returns the status page, or the status in the format
+// negotiated with the client.
 func (h *Handlers) StatusHandler(w http.ResponseWriter, r *http.Request) {
-	stts := http.StatusOK
-	buf := &bytes.Buffer{}
-
-	str, err := status(h.device, h.opts.raw)
-	if err != nil {
-		stts = http.StatusInternalServerError
-		w.WriteHeader(stts)
-		log.Printf("%s", err)
+	format := negotiate(r, htmlFormat)
+	if format == "" {
+		writeError(w, r, notAcceptable())
+		return
 	}
-
-	if stts == http.StatusOK {
-		data := struct {
-			Title    string
-			Contents string
-		}{
-			Title:    "Status",
-			Contents: str,
-		}
-		err = tmpls[statusTmpl].Execute(io.Writer(buf), data)
-		if err != nil {
-			stts = http.StatusInternalServerError
-			w.WriteHeader(stts)
-			log.Printf("error executing template; %s", err)
-		}
+	renderFormat := format
+	if format == htmlFormat {
+		renderFormat = TableFormat
 	}
-
-	l := 0
-	if stts == http.StatusOK {
-		l, err = w.Write(buf.Bytes())
-		if err != nil {
-			stts = http.StatusInternalServerError
-			w.WriteHeader(stts)
-		}
+	str, err := h.renderView(h.statusView(), renderFormat, api.StatusKind)
+	if err != nil {
+		writeError(w, r, err)
+		return
 	}
-
-	helpers.CommonLogFormat(r, stts, l)
-}
-
-func status(device devices.Device, asRaw bool) (string, error) {
-	lines := []string{"LED STATUS"}
-
-	for _, led := range []*leds.LED{
-		device.LEDs().Power(),
-		device.LEDs().Status(),
-		device.LEDs().Mute(),
-	} {
-		state, err := led.State()
-		if err != nil {
-			log.Printf("error reading %s led state; %s", led.Name(), err)
-		}
-		lines = append(lines, fmt.Sprintf("%s %s", led.Name(), state))
+	if format != htmlFormat {
+		writeBody(w, contentTypes[format], []byte(str))
+		return
 	}
 
-	tbl, err := table.Split(lines, ifs, -1)
-	if err != nil {
-		return "", fmt.Errorf("failure creating table; %s", err)
+	data := struct {
+		Title    string
+		Contents string
+	}{
+		Title:    "Status",
+		Contents: str,
+	}
+	buf := &bytes.Buffer{}
+	if err := tmpls[statusTmpl].Execute(buf, data); err != nil {
+		writeError(w, r, errors.Errorf(codes.Internal, "error executing template; %s", err))
+		return
 	}
-	rndr := &render.PlainRenderer{}
-	rndr.SetOFS(ofs)
-	return rndr.Render(tbl), nil
+	writeBody(w, htmlContentType, buf.Bytes())
 }
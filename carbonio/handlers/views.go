@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/kward/avid-s3l/carbonio/address"
+	"github.com/kward/golib/errors"
+	"google.golang.org/grpc/codes"
+)
+
+// view selects the rows and columns of a listing.
+type view struct {
+	types []string // Node types, e.g. `inputs` or `leds`.
+	props []string // Property columns.
+	addrs []string // Address filters.
+	sort  string   // Sort column, optionally prefixed with `-` for descending.
+	raw   bool
+}
+
+// types maps the supported view types to their address roots.
+var types = map[string]string{
+	"input":   address.InputRoot,
+	"inputs":  address.InputRoot,
+	"output":  address.OutputRoot,
+	"outputs": address.OutputRoot,
+	"led":     address.LEDRoot,
+	"leds":    address.LEDRoot,
+}
+
+// listView returns the view configured by the options. Without types or
+// addresses, all inputs are listed.
+func (h *Handlers) listView() *view {
+	v := &view{
+		types: h.opts.types,
+		props: h.opts.props,
+		addrs: h.opts.addrs,
+		sort:  h.opts.sort,
+		raw:   h.opts.raw,
+	}
+	if len(v.types) == 0 && len(v.addrs) == 0 {
+		v.types = []string{"inputs"}
+	}
+	return v
+}
+
+// statusView returns the view of the device status, i.e. the LEDs.
+func (h *Handlers) statusView() *view {
+	return &view{
+		types: []string{"leds"},
+		raw:   h.opts.raw,
+	}
+}
+
+// queryView returns a view configured by URL query parameters, using `v` for
+// the defaults. Multiple values are given either by repeating a parameter, or
+// as a comma separated list.
+func queryView(q url.Values, v *view) *view {
+	split := func(key string, def []string) []string {
+		vs, ok := q[key]
+		if !ok {
+			return def
+		}
+		strs := []string{}
+		for _, s := range vs {
+			for _, str := range strings.Split(s, ",") {
+				if str != "" {
+					strs = append(strs, str)
+				}
+			}
+		}
+		return strs
+	}
+	w := &view{
+		types: split("type", v.types),
+		props: split("property", v.props),
+		addrs: split("address", v.addrs),
+		sort:  v.sort,
+		raw:   v.raw,
+	}
+	if s := q.Get("sort"); s != "" {
+		w.sort = s
+	}
+	if len(w.addrs) > 0 && len(q["type"]) == 0 {
+		w.types = nil // Addresses take the place of the default types.
+	}
+	return w
+}
+
+// listing is the result of applying a view to a device.
+type listing struct {
+	title string              // Title of the name column.
+	props []*address.Property // Columns.
+	rows  []*row
+}
+
+// row of a listing.
+type row struct {
+	node   *address.Node
+	values map[string]interface{} // Values by property name.
+	errs   map[string]error       // Read errors by property name.
+}
+
+// value returns the formatted value of the property.
+func (r *row) value(p *address.Property, raw bool) string {
+	t := r.node.Target(p.Name)
+	if t == nil {
+		return "-"
+	}
+	if raw {
+		return fmt.Sprintf("%q", t.Raw())
+	}
+	return p.Format(r.values[p.Name])
+}
+
+// list applies the view to the device.
+func (h *Handlers) list(v *view) (*listing, error) {
+	// Determine the matching targets.
+	roots := map[string]bool{}
+	patterns := append([]string{}, v.addrs...)
+	for _, t := range v.types {
+		root, ok := types[t]
+		if !ok {
+			return nil, errors.Errorf(codes.InvalidArgument, "unknown type %q; want inputs, outputs, or leds", t)
+		}
+		roots[root] = true
+		if len(v.addrs) == 0 {
+			patterns = append(patterns, root)
+		}
+	}
+	ts, err := h.resolve(patterns, v.props)
+	if err != nil {
+		return nil, err
+	}
+	if len(v.addrs) > 0 && len(roots) > 0 {
+		fts := []*address.Target{}
+		for _, t := range ts {
+			if roots[t.Address.Segment(0)] {
+				fts = append(fts, t)
+			}
+		}
+		ts = fts
+	}
+
+	// Determine the columns.
+	l := &listing{title: "NAME"}
+	seen := map[string]bool{}
+	nodeRoots := map[string]bool{}
+	for _, t := range ts {
+		nodeRoots[t.Address.Segment(0)] = true
+		if seen[t.Property.Name] {
+			continue
+		}
+		seen[t.Property.Name] = true
+		l.props = append(l.props, t.Property)
+	}
+	if len(v.props) > 0 {
+		// Order the columns as requested.
+		byName := map[string]*address.Property{}
+		for _, p := range l.props {
+			byName[p.Name] = p
+		}
+		l.props = nil
+		for _, name := range v.props {
+			if p, ok := byName[name]; ok {
+				l.props = append(l.props, p)
+				delete(byName, name)
+			}
+		}
+	}
+	if len(nodeRoots) == 1 {
+		switch {
+		case nodeRoots[address.InputRoot], nodeRoots[address.OutputRoot]:
+			l.title = "SIGNAL"
+		case nodeRoots[address.LEDRoot]:
+			l.title = "LED"
+		}
+	}
+
+	// Read the values.
+	for _, n := range address.Nodes(ts) {
+		r := &row{node: n, values: map[string]interface{}{}, errs: map[string]error{}}
+		for _, t := range n.Targets {
+			if v.raw {
+				continue // Raw values are the most recently read values.
+			}
+			val, err := t.Value()
+			if err != nil {
+				log.Printf("%s", err)
+				r.errs[t.Property.Name] = err
+				continue
+			}
+			r.values[t.Property.Name] = val
+		}
+		l.rows = append(l.rows, r)
+	}
+
+	if err := l.sort(v.sort); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// sort the listing rows by the named column. Rows are in address order by
+// default.
+func (l *listing) sort(col string) error {
+	desc := strings.HasPrefix(col, "-")
+	col = strings.TrimPrefix(col, "-")
+	var less func(a, b *row) bool
+	switch col {
+	case "":
+		return nil
+	case "name", "address":
+		less = func(a, b *row) bool { return addressLess(a.node.Address, b.node.Address) }
+	default:
+		known := false
+		for _, p := range l.props {
+			known = known || p.Name == col
+		}
+		if !known {
+			return errors.Errorf(codes.InvalidArgument, "unable to sort by unknown column %q", col)
+		}
+		less = func(a, b *row) bool { return valueLess(a.values[col], b.values[col]) }
+	}
+	sort.SliceStable(l.rows, func(i, j int) bool {
+		if desc {
+			return less(l.rows[j], l.rows[i])
+		}
+		return less(l.rows[i], l.rows[j])
+	})
+	return nil
+}
+
+// addressLess orders addresses by segment, with numbers in numeric order.
+func addressLess(a, b address.Address) bool {
+	for i := 0; i < a.Len() && i < b.Len(); i++ {
+		m, mok := a.Number(i)
+		n, nok := b.Number(i)
+		if mok && nok {
+			if m != n {
+				return m < n
+			}
+			continue
+		}
+		if a.Segment(i) != b.Segment(i) {
+			return a.Segment(i) < b.Segment(i)
+		}
+	}
+	return a.Len() < b.Len()
+}
+
+// valueLess orders values of the same kind. Missing values sort first.
+func valueLess(a, b interface{}) bool {
+	switch x := a.(type) {
+	case nil:
+		return b != nil
+	case int:
+		y, ok := b.(int)
+		return ok && x < y
+	case bool:
+		y, ok := b.(bool)
+		return ok && !x && y
+	case string:
+		y, ok := b.(string)
+		return ok && x < y
+	}
+	return false
+}
+
+// plain renders the listing as a plain text table.
+func (l *listing) plain(raw bool) (string, error) {
+	header := []string{l.title}
+	for _, p := range l.props {
+		header = append(header, strings.ToUpper(p.Name))
+	}
+	lines := []string{strings.Join(header, ifs)}
+	for _, r := range l.rows {
+		fields := []string{r.node.Address.String()}
+		for _, p := range l.props {
+			fields = append(fields, r.value(p, raw))
+		}
+		lines = append(lines, strings.Join(fields, ifs))
+	}
+	return renderPlain(lines)
+}
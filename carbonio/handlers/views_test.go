@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestList(t *testing.T) {
+	d, cleanup := newDevice(t)
+	defer cleanup()
+
+	h, err := NewHandlers(d)
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	if err := h.SetCommand(&strings.Builder{}, []string{"input/mic/2/gain=40", "input/mic/3/gain=20"}, nil); err != nil {
+		t.Fatalf("error setting up test; %s", err)
+	}
+
+	for _, tc := range []struct {
+		desc string
+		ok   bool
+
+		view  *view
+		title string
+		props string // Comma separated property names.
+		rows  string // Comma separated node addresses.
+	}{
+		// Valid views.
+		{"default", true, h.listView(), "SIGNAL", "gain,pad,phantom", ""},
+		{"leds", true, &view{types: []string{"leds"}}, "LED", "state", "led/power,led/status,led/mute"},
+		{"mixed", true, &view{types: []string{"leds", "inputs"}, addrs: []string{"input/mic/1", "led/mute"}},
+			"NAME", "gain,pad,phantom,state", "input/mic/1,led/mute"},
+		{"filtered types", true, &view{types: []string{"leds"}, addrs: []string{"input/mic/1", "led/mute"}},
+			"LED", "state", "led/mute"},
+		{"properties", true, &view{props: []string{"phantom", "gain"}, addrs: []string{"input/mic/1-3"}},
+			"SIGNAL", "phantom,gain", "input/mic/1,input/mic/2,input/mic/3"},
+		{"sorted", true, &view{props: []string{"gain"}, addrs: []string{"input/mic/1-3"}, sort: "-gain"},
+			"SIGNAL", "gain", "input/mic/2,input/mic/3,input/mic/1"},
+
+		// Invalid views.
+		{desc: "unknown type", view: &view{types: []string{"bogus"}}},
+		{desc: "unknown sort", view: &view{types: []string{"leds"}, sort: "gain"}},
+		{desc: "unknown property", view: &view{types: []string{"leds"}, props: []string{"gain"}}},
+	} {
+		t.Run(fmt.Sprintf("list() %s", tc.desc), func(t *testing.T) {
+			l, err := h.list(tc.view)
+			if err != nil && tc.ok {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			if err == nil && !tc.ok {
+				t.Fatal("expected an error")
+			}
+			if !tc.ok {
+				return
+			}
+			if got, want := l.title, tc.title; got != want {
+				t.Errorf("title = %s, want %s", got, want)
+			}
+			props := []string{}
+			for _, p := range l.props {
+				props = append(props, p.Name)
+			}
+			if got, want := strings.Join(props, ","), tc.props; got != want {
+				t.Errorf("props = %s, want %s", got, want)
+			}
+			if tc.rows == "" {
+				return
+			}
+			rows := []string{}
+			for _, r := range l.rows {
+				rows = append(rows, r.node.Address.String())
+			}
+			if got, want := strings.Join(rows, ","), tc.rows; got != want {
+				t.Errorf("rows = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestQueryView(t *testing.T) {
+	def := &view{types: []string{"inputs"}, sort: "name"}
+	for _, tc := range []struct {
+		desc  string
+		query string
+		want  string
+	}{
+		{"defaults", "", "types=[inputs] props=[] addrs=[] sort=name"},
+		{"types", "type=leds,inputs", "types=[leds inputs] props=[] addrs=[] sort=name"},
+		{"repeated", "property=gain&property=pad&sort=-gain", "types=[inputs] props=[gain pad] addrs=[] sort=-gain"},
+		{"addresses", "address=led/status", "types=[] props=[] addrs=[led/status] sort=name"},
+	} {
+		t.Run(fmt.Sprintf("queryView() %s", tc.desc), func(t *testing.T) {
+			q, err := url.ParseQuery(tc.query)
+			if err != nil {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			v := queryView(q, def)
+			if got, want := fmt.Sprintf("types=%v props=%v addrs=%v sort=%s", v.types, v.props, v.addrs, v.sort), tc.want; got != want {
+				t.Errorf("= %s, want %s", got, want)
+			}
+		})
+	}
+}
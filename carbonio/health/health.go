@@ -0,0 +1,172 @@
+/*
+Package health checks whether the carbonio server is ready to serve requests.
+
+A Checker runs named checks, and reports the result of each. The checks of the
+device are built on the spi.Implementation interface, so that they cover every
+LED and signal of the device.
+
+The checker serves two endpoints:
+
+	/healthz  200 while the process is alive
+	/readyz   200 when all the checks pass, 503 otherwise
+*/
+package health
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kward/avid-s3l/carbonio/devices"
+	"github.com/kward/avid-s3l/carbonio/helpers"
+	"github.com/kward/avid-s3l/carbonio/spi"
+)
+
+// Statuses of checks and reports.
+const (
+	OK    = "ok"
+	Error = "error"
+)
+
+// Check is the result of a check.
+type Check struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+	// Duration of the check, in milliseconds.
+	DurationMS float64 `json:"duration_ms"`
+}
+
+// Report is the result of all the checks. Its status is OK only if all the
+// checks are.
+type Report struct {
+	Status string   `json:"status"`
+	Checks []*Check `json:"checks,omitempty"`
+}
+
+// Checker runs a set of checks. Checks may be added while it is in use.
+type Checker struct {
+	mu     sync.Mutex
+	names  []string
+	checks map[string]func() error
+}
+
+// NewChecker returns a checker without checks.
+func NewChecker() *Checker {
+	return &Checker{checks: map[string]func() error{}}
+}
+
+// Add a check, which returns an error when it fails. A check with the same
+// name is replaced.
+func (c *Checker) Add(name string, check func() error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.checks[name]; !ok {
+		c.names = append(c.names, name)
+	}
+	c.checks[name] = check
+}
+
+// Run all the checks, in the order they were added.
+func (c *Checker) Run() *Report {
+	c.mu.Lock()
+	names := append([]string{}, c.names...)
+	checks := map[string]func() error{}
+	for k, v := range c.checks {
+		checks[k] = v
+	}
+	c.mu.Unlock()
+
+	r := &Report{Status: OK, Checks: []*Check{}}
+	for _, name := range names {
+		start := time.Now()
+		err := checks[name]()
+		ch := &Check{
+			Name:       name,
+			Status:     OK,
+			DurationMS: float64(time.Since(start)) / float64(time.Millisecond),
+		}
+		if err != nil {
+			ch.Status = Error
+			ch.Error = err.Error()
+			r.Status = Error
+		}
+		r.Checks = append(r.Checks, ch)
+	}
+	return r
+}
+
+// DeviceCheck returns a check that the device was detected, and has LEDs and
+// signals.
+func DeviceCheck(d devices.Device) func() error {
+	return func() error {
+		if d == nil {
+			return fmt.Errorf("no device was detected")
+		}
+		if d.IP() == nil {
+			return fmt.Errorf("the device has no IP")
+		}
+		if len(devices.Implementations(d)) == 0 {
+			return fmt.Errorf("the device has no LEDs or signals")
+		}
+		return nil
+	}
+}
+
+// SPICheck returns a check that the SPI files of the implementations are
+// readable.
+func SPICheck(impls []spi.Implementation) func() error {
+	return func() error {
+		if len(impls) == 0 {
+			return fmt.Errorf("no SPI implementations")
+		}
+		failed := []string{}
+		for _, impl := range impls {
+			if _, err := helpers.ReadFileFn()(impl.Path()); err != nil {
+				failed = append(failed, fmt.Sprintf("%s (%s)", impl.Name(), impl.Path()))
+			}
+		}
+		if len(failed) > 0 {
+			sort.Strings(failed)
+			return fmt.Errorf("%d of %d SPI files are unreadable: %s",
+				len(failed), len(impls), strings.Join(failed, ", "))
+		}
+		return nil
+	}
+}
+
+// Healthz reports that the process is alive. It runs no checks.
+func (c *Checker) Healthz(w http.ResponseWriter, r *http.Request) {
+	writeReport(w, http.StatusOK, &Report{Status: OK})
+}
+
+// Readyz runs the checks, and reports whether the server is ready to serve
+// requests.
+func (c *Checker) Readyz(w http.ResponseWriter, r *http.Request) {
+	rep := c.Run()
+	stts := http.StatusOK
+	if rep.Status != OK {
+		stts = http.StatusServiceUnavailable
+	}
+	writeReport(w, stts, rep)
+}
+
+func writeReport(w http.ResponseWriter, stts int, rep *Report) {
+	data, err := json.MarshalIndent(rep, "", "  ")
+	if err != nil {
+		log.Printf("error marshaling json; %s", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(stts)
+	if _, err := w.Write(append(data, '\n')); err != nil {
+		log.Printf("error writing response; %s", err)
+	}
+}
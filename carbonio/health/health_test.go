@@ -0,0 +1,114 @@
+package health
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/kward/avid-s3l/carbonio/devices"
+	"github.com/kward/avid-s3l/carbonio/devices/devicestest"
+)
+
+func TestChecker(t *testing.T) {
+	ok := func() error { return nil }
+	fail := func() error { return fmt.Errorf("broken") }
+	for _, tc := range []struct {
+		desc   string
+		checks []func() error
+		status string
+	}{
+		{"no checks", nil, OK},
+		{"passing", []func() error{ok, ok}, OK},
+		{"failing", []func() error{ok, fail}, Error},
+	} {
+		t.Run(fmt.Sprintf("Run() %s", tc.desc), func(t *testing.T) {
+			c := NewChecker()
+			for i, check := range tc.checks {
+				c.Add(fmt.Sprintf("check%d", i), check)
+			}
+			r := c.Run()
+			if r.Status != tc.status {
+				t.Errorf("status = %q, want %q", r.Status, tc.status)
+			}
+			if got, want := len(r.Checks), len(tc.checks); got != want {
+				t.Fatalf("checks = %d, want %d", got, want)
+			}
+			for i, ch := range r.Checks {
+				if got, want := ch.Name, fmt.Sprintf("check%d", i); got != want {
+					t.Errorf("check %d name = %q, want %q", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestAddReplaces(t *testing.T) {
+	c := NewChecker()
+	c.Add("a", func() error { return fmt.Errorf("broken") })
+	c.Add("a", func() error { return nil })
+	if r := c.Run(); r.Status != OK || len(r.Checks) != 1 {
+		t.Errorf("Run() = %+v, want one passing check", r)
+	}
+}
+
+func TestDeviceChecks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "health")
+	if err != nil {
+		t.Fatalf("error creating temp dir; %s", err)
+	}
+	defer os.RemoveAll(dir)
+	d, err := devicestest.NewStage16(dir)
+	if err != nil {
+		t.Fatalf("error instantiating Stage16; %s", err)
+	}
+	impls := devices.Implementations(d)
+
+	if err := DeviceCheck(d)(); err != nil {
+		t.Errorf("DeviceCheck() unexpected error; %s", err)
+	}
+	if err := DeviceCheck(nil)(); err == nil {
+		t.Errorf("DeviceCheck(nil) expected an error")
+	}
+	if err := SPICheck(impls)(); err != nil {
+		t.Errorf("SPICheck() unexpected error; %s", err)
+	}
+	if err := os.Remove(impls[0].Path()); err != nil {
+		t.Fatalf("error removing %s; %s", impls[0].Path(), err)
+	}
+	err = SPICheck(impls)()
+	if err == nil {
+		t.Fatalf("SPICheck() expected an error")
+	}
+	if want := fmt.Sprintf("1 of %d", len(impls)); !strings.Contains(err.Error(), want) {
+		t.Errorf("SPICheck() = %q, want %q", err, want)
+	}
+}
+
+func TestHandlers(t *testing.T) {
+	c := NewChecker()
+	c.Add("broken", func() error { return fmt.Errorf("broken") })
+	for _, tc := range []struct {
+		desc    string
+		handler http.HandlerFunc
+		status  int
+		body    string
+	}{
+		{"healthz", c.Healthz, http.StatusOK, `"status": "ok"`},
+		{"readyz", c.Readyz, http.StatusServiceUnavailable, `"error": "broken"`},
+	} {
+		t.Run(fmt.Sprintf("%s()", tc.desc), func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tc.handler(w, httptest.NewRequest(http.MethodGet, "/"+tc.desc, nil))
+			if w.Code != tc.status {
+				t.Errorf("status = %d, want %d", w.Code, tc.status)
+			}
+			if got := w.Body.String(); !strings.Contains(got, tc.body) {
+				t.Errorf("body = %s, want %s", got, tc.body)
+			}
+		})
+	}
+}
@@ -4,26 +4,10 @@ Package helpers provides code snippets that are used across the code base.
 package helpers
 
 import (
-	"fmt"
 	"io/ioutil"
-	"log"
-	"net/http"
 	"os"
-	"time"
 )
 
-// CommonLogFormat generates a log entry in the Common Log Format (CLF).
-//   "%h %l %u %t \"%r\" %>s %b"
-func CommonLogFormat(r *http.Request, status, length int) {
-	log.Printf("%s - - %s %q %d %d\n",
-		r.RemoteAddr, time.Now().Format("02/Jan/2006:15:04:05 -0700"), r.URL.Path, status, length)
-}
-
-func Exit(msg string) {
-	fmt.Println(msg)
-	os.Exit(1)
-}
-
 //-----------------------------------------------------------------------------
 // Helpers for testing.
 
@@ -0,0 +1,62 @@
+package helpers
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+)
+
+// ResponseRecorder records the status and size of a response, for middleware.
+// It supports the streaming of events, and the hijacking of WebSocket
+// connections.
+type ResponseRecorder struct {
+	http.ResponseWriter
+	// Status of the response, or zero if nothing was written yet.
+	Status int
+	// Bytes is the size of the written body.
+	Bytes int64
+}
+
+// NewResponseRecorder returns a recorder of the responses written to w.
+func NewResponseRecorder(w http.ResponseWriter) *ResponseRecorder {
+	return &ResponseRecorder{ResponseWriter: w}
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (r *ResponseRecorder) WriteHeader(status int) {
+	if r.Status == 0 {
+		r.Status = status
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Write implements http.ResponseWriter.
+func (r *ResponseRecorder) Write(b []byte) (int, error) {
+	if r.Status == 0 {
+		r.Status = http.StatusOK
+	}
+	n, err := r.ResponseWriter.Write(b)
+	r.Bytes += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher.
+func (r *ResponseRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker.
+func (r *ResponseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("hijacking is not supported")
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil && r.Status == 0 {
+		r.Status = http.StatusSwitchingProtocols
+	}
+	return conn, rw, err
+}
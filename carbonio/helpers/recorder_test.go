@@ -0,0 +1,27 @@
+package helpers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResponseRecorder(t *testing.T) {
+	rec := NewResponseRecorder(httptest.NewRecorder())
+	var w http.ResponseWriter = rec
+	if _, ok := w.(http.Flusher); !ok {
+		t.Errorf("ResponseRecorder is not an http.Flusher")
+	}
+	if _, ok := w.(http.Hijacker); !ok {
+		t.Errorf("ResponseRecorder is not an http.Hijacker")
+	}
+	w.WriteHeader(http.StatusAccepted)
+	w.WriteHeader(http.StatusOK) // Superfluous; ignored.
+	w.Write([]byte("hello"))
+	if rec.Status != http.StatusAccepted {
+		t.Errorf("Status = %d, want %d", rec.Status, http.StatusAccepted)
+	}
+	if rec.Bytes != 5 {
+		t.Errorf("Bytes = %d, want 5", rec.Bytes)
+	}
+}
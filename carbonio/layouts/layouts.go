@@ -0,0 +1,167 @@
+/*
+Package layouts generates control surface layouts of the OSC address space of
+a device, for OSC apps such as TouchOSC and Open Stage Control.
+
+A layout has a strip per signal, labelled with the name of the signal. Each
+editable Int property gets a fader of the range of the property, and each
+editable Bool property a toggle. The controls send to, and receive from, the
+OSC address of their value.
+*/
+package layouts
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/kward/avid-s3l/carbonio/address"
+	"github.com/kward/golib/errors"
+	"google.golang.org/grpc/codes"
+)
+
+// Kinds of controls.
+const (
+	Fader  = "fader"
+	Toggle = "toggle"
+)
+
+// Control is a fader or toggle of a value.
+type Control struct {
+	// Kind of the control, Fader or Toggle.
+	Kind string
+	// Address of the value, in OSC style, e.g. `/input/mic/1/gain`.
+	Address string
+	// Name of the property, e.g. `gain`.
+	Name string
+	// Unit of the value, if any, e.g. `dB`.
+	Unit string
+	// Min and Max values; 0 and 1 for toggles.
+	Min, Max int
+}
+
+// Label returns the label of the control, e.g. `Gain`.
+func (c *Control) Label() string { return strings.Title(c.Name) }
+
+// Strip groups the controls of a signal.
+type Strip struct {
+	// Address of the signal, e.g. `input/mic/1`.
+	Address string
+	// Label of the signal, e.g. `Mic input #1`.
+	Label    string
+	Controls []*Control
+}
+
+// Layout is a control surface of strips.
+type Layout struct {
+	Title  string
+	Strips []*Strip
+}
+
+// New returns the layout of the targets. Targets of other kinds, or which are
+// not editable, are left out.
+func New(title string, ts []*address.Target) (*Layout, error) {
+	l := &Layout{Title: title}
+	for _, n := range address.Nodes(ts) {
+		s := &Strip{Address: n.Address.String(), Label: n.Label}
+		for _, t := range n.Targets {
+			if c := control(t); c != nil {
+				s.Controls = append(s.Controls, c)
+			}
+		}
+		if len(s.Controls) > 0 {
+			l.Strips = append(l.Strips, s)
+		}
+	}
+	if len(l.Strips) == 0 {
+		return nil, errors.Errorf(codes.NotFound, "no faders or toggles match")
+	}
+	return l, nil
+}
+
+// control returns the control of a target, or nil.
+func control(t *address.Target) *Control {
+	p := t.Property
+	if !p.Editable {
+		return nil
+	}
+	c := &Control{Address: "/" + t.Address.String(), Name: p.Name, Unit: p.Unit}
+	switch p.Kind {
+	case address.Int:
+		c.Kind, c.Min, c.Max = Fader, p.Min, p.Max
+	case address.Bool:
+		c.Kind, c.Min, c.Max = Toggle, 0, 1
+	default:
+		return nil
+	}
+	return c
+}
+
+// Toggles returns the toggles of the strip.
+func (s *Strip) Toggles() []*Control { return s.controls(Toggle) }
+
+// Faders returns the faders of the strip.
+func (s *Strip) Faders() []*Control { return s.controls(Fader) }
+
+func (s *Strip) controls(kind string) []*Control {
+	cs := []*Control{}
+	for _, c := range s.Controls {
+		if c.Kind == kind {
+			cs = append(cs, c)
+		}
+	}
+	return cs
+}
+
+// Supported layout formats.
+const (
+	TouchOSC         = "touchosc"
+	OpenStageControl = "open-stage-control"
+)
+
+// encoders of the formats.
+var encoders = map[string]func(*Layout) ([]byte, error){
+	TouchOSC:         encodeTouchOSC,
+	OpenStageControl: encodeOpenStageControl,
+}
+
+// Formats returns the supported layout formats.
+func Formats() []string {
+	fs := []string{}
+	for f := range encoders {
+		fs = append(fs, f)
+	}
+	sort.Strings(fs)
+	return fs
+}
+
+// Encode returns the layout in the format.
+func Encode(l *Layout, format string) ([]byte, error) {
+	enc, ok := encoders[format]
+	if !ok {
+		return nil, errors.Errorf(codes.InvalidArgument, "unsupported layout format %q; want one of %s", format, strings.Join(Formats(), ", "))
+	}
+	return enc(l)
+}
+
+// Dimensions of the layouts, in pixels.
+const (
+	stripWidth   = 80
+	layoutHeight = 600
+	labelHeight  = 40
+	toggleHeight = 60
+	spacing      = 10
+)
+
+// Colors of the controls of properties, as RGB.
+var colors = map[string][3]float64{
+	"gain":    {0.25, 0.6, 1},
+	"pad":     {1, 0.75, 0},
+	"phantom": {1, 0.25, 0.25},
+}
+
+// color returns the color of the control.
+func (c *Control) color() [3]float64 {
+	if rgb, ok := colors[c.Name]; ok {
+		return rgb
+	}
+	return [3]float64{0.6, 0.6, 0.6}
+}
@@ -0,0 +1,215 @@
+package layouts
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/kward/avid-s3l/carbonio/address"
+	"github.com/kward/avid-s3l/carbonio/devices/devicestest"
+)
+
+// targets returns the targets of a test device matched by the address.
+func targets(t *testing.T, addr string) []*address.Target {
+	dir, err := ioutil.TempDir("", "layouts")
+	if err != nil {
+		t.Fatalf("error creating temp dir; %s", err)
+	}
+	defer os.RemoveAll(dir)
+	d, err := devicestest.NewStage16(dir)
+	if err != nil {
+		t.Fatalf("error instantiating Stage16; %s", err)
+	}
+	ts, err := address.Filter(address.Targets(d), address.MustParse(addr))
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	return ts
+}
+
+func TestNew(t *testing.T) {
+	for _, tc := range []struct {
+		desc   string
+		addr   string
+		ok     bool
+		strips int
+	}{
+		{"inputs", "input", true, 16},
+		{"one input", "input/mic/3", true, 1},
+		{"gains", "input/mic/*/gain", true, 16},
+		{"leds", "led", false, 0},
+	} {
+		t.Run(fmt.Sprintf("New() %s", tc.desc), func(t *testing.T) {
+			l, err := New("test", targets(t, tc.addr))
+			if err == nil && !tc.ok {
+				t.Fatal("expected an error")
+			}
+			if err != nil {
+				if tc.ok {
+					t.Fatalf("unexpected error; %s", err)
+				}
+				return
+			}
+			if got, want := len(l.Strips), tc.strips; got != want {
+				t.Errorf("strips = %d, want %d", got, want)
+			}
+		})
+	}
+
+	l, err := New("test", targets(t, "input/mic/1"))
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	s := l.Strips[0]
+	if got, want := s.Label, "Mic input #1"; got != want {
+		t.Errorf("Label = %q, want %q", got, want)
+	}
+	got := []string{}
+	for _, c := range s.Controls {
+		got = append(got, fmt.Sprintf("%s %s %s [%d:%d]", c.Kind, c.Address, c.Label(), c.Min, c.Max))
+	}
+	want := []string{
+		"fader /input/mic/1/gain Gain [10:60]",
+		"toggle /input/mic/1/pad Pad [0:1]",
+		"toggle /input/mic/1/phantom Phantom [0:1]",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Controls = %q, want %q", got, want)
+	}
+}
+
+func TestEncode(t *testing.T) {
+	l, err := New("test", targets(t, "input"))
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	for _, tc := range []struct {
+		desc   string
+		format string
+		ok     bool
+	}{
+		{"touchosc", TouchOSC, true},
+		{"open stage control", OpenStageControl, true},
+		{"unknown", "lemur", false},
+	} {
+		t.Run(fmt.Sprintf("Encode() %s", tc.desc), func(t *testing.T) {
+			data, err := Encode(l, tc.format)
+			if err == nil && !tc.ok {
+				t.Fatal("expected an error")
+			}
+			if err != nil {
+				if tc.ok {
+					t.Fatalf("unexpected error; %s", err)
+				}
+				return
+			}
+			again, err := Encode(l, tc.format)
+			if err != nil {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			if !bytes.Equal(data, again) {
+				t.Errorf("Encode() is not deterministic")
+			}
+		})
+	}
+}
+
+func TestEncodeTouchOSC(t *testing.T) {
+	l, err := New("test", targets(t, "input/mic/1-2"))
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	data, err := Encode(l, TouchOSC)
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("error decompressing; %s", err)
+	}
+	doc, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatalf("error decompressing; %s", err)
+	}
+
+	// The document is well-formed, and has a node per strip and control.
+	types := map[string]int{}
+	dec := xml.NewDecoder(bytes.NewReader(doc))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid XML; %s", err)
+		}
+		if se, ok := tok.(xml.StartElement); ok && se.Name.Local == "node" {
+			for _, a := range se.Attr {
+				if a.Name.Local == "type" {
+					types[a.Value]++
+				}
+			}
+		}
+	}
+	if got, want := fmt.Sprint(types), "map[BUTTON:4 FADER:2 GROUP:3 LABEL:2]"; got != want {
+		t.Errorf("nodes = %s, want %s", got, want)
+	}
+	for _, want := range []string{
+		"<value><![CDATA[/input/mic/2/gain]]></value>",
+		"<conversion>INTEGER</conversion><value><![CDATA[x]]></value><scaleMin>10</scaleMin><scaleMax>60</scaleMax>",
+		"<key><![CDATA[text]]></key><locked>0</locked><lockedDefaultCurrent>0</lockedDefaultCurrent><default><![CDATA[Mic input #2]]></default>",
+		"<key><![CDATA[buttonType]]></key><value>1</value>",
+	} {
+		if !bytes.Contains(doc, []byte(want)) {
+			t.Errorf("document does not contain %s", want)
+		}
+	}
+}
+
+func TestEncodeOpenStageControl(t *testing.T) {
+	l, err := New("test", targets(t, "input/mic/1"))
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	data, err := Encode(l, OpenStageControl)
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	session := &oscSession{}
+	if err := json.Unmarshal(data, session); err != nil {
+		t.Fatalf("invalid JSON; %s", err)
+	}
+	strip := session.Content.Widgets[0]
+	got := []string{}
+	var walk func(w *oscWidget)
+	walk = func(w *oscWidget) {
+		switch w.Type {
+		case "text":
+			got = append(got, fmt.Sprintf("text %v", w.Value))
+		case "button":
+			got = append(got, fmt.Sprintf("button %s %s %s %d:%d", w.Address, w.Label, w.Mode, *w.Off, *w.On))
+		case "fader":
+			got = append(got, fmt.Sprintf("fader %s %s [%d:%d] %s", w.Address, w.Label, w.Range.Min, w.Range.Max, w.TypeTags))
+		}
+		for _, c := range w.Widgets {
+			walk(c)
+		}
+	}
+	walk(strip)
+	want := []string{
+		"text Mic input #1",
+		"button /input/mic/1/pad Pad toggle 0:1",
+		"button /input/mic/1/phantom Phantom toggle 0:1",
+		"fader /input/mic/1/gain Gain [10:60] i",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("widgets = %q, want %q", got, want)
+	}
+}
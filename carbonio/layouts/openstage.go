@@ -0,0 +1,133 @@
+package layouts
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// oscWidget is a widget of an Open Stage Control session. Properties that are
+// left out take the defaults of Open Stage Control.
+type oscWidget struct {
+	Type     string       `json:"type"`
+	ID       string       `json:"id"`
+	Label    interface{}  `json:"label,omitempty"` // A string, or false for none.
+	Layout   string       `json:"layout,omitempty"`
+	Width    interface{}  `json:"width,omitempty"`
+	Height   interface{}  `json:"height,omitempty"`
+	Expand   bool         `json:"expand,omitempty"`
+	ColorWid string       `json:"colorWidget,omitempty"`
+	Address  string       `json:"address,omitempty"`
+	TypeTags string       `json:"typeTags,omitempty"`
+	Mode     string       `json:"mode,omitempty"`
+	On       *int         `json:"on,omitempty"`
+	Off      *int         `json:"off,omitempty"`
+	Range    *oscRange    `json:"range,omitempty"`
+	Steps    int          `json:"steps,omitempty"`
+	Decimals *int         `json:"decimals,omitempty"`
+	Unit     string       `json:"unit,omitempty"`
+	Value    interface{}  `json:"value,omitempty"`
+	Widgets  []*oscWidget `json:"widgets,omitempty"`
+}
+
+type oscRange struct {
+	Min int `json:"min"`
+	Max int `json:"max"`
+}
+
+// oscSession is an Open Stage Control session file.
+type oscSession struct {
+	CreatedWith string     `json:"createdWith"`
+	Version     string     `json:"version"`
+	Type        string     `json:"type"`
+	Content     *oscWidget `json:"content"`
+}
+
+// encodeOpenStageControl returns the layout as an Open Stage Control (1.x)
+// session. The values are sent as integers.
+func encodeOpenStageControl(l *Layout) ([]byte, error) {
+	root := &oscWidget{Type: "root", ID: "root", Layout: "horizontal"}
+	for _, s := range l.Strips {
+		root.Widgets = append(root.Widgets, openStageStrip(s))
+	}
+	data, err := json.MarshalIndent(&oscSession{
+		CreatedWith: "Open Stage Control",
+		Version:     "1.0.0",
+		Type:        "session",
+		Content:     root,
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+// openStageStrip returns the panel of a strip, with its label above its
+// toggles, above its faders.
+func openStageStrip(s *Strip) *oscWidget {
+	zero, one := 0, 1
+	p := &oscWidget{
+		Type:   "panel",
+		ID:     widgetID(s.Address),
+		Label:  false,
+		Layout: "vertical",
+		Width:  stripWidth,
+	}
+	p.Widgets = append(p.Widgets, &oscWidget{
+		Type:   "text",
+		ID:     widgetID(s.Address + "/label"),
+		Label:  false,
+		Height: labelHeight,
+		Value:  s.Label,
+	})
+	for _, c := range s.Toggles() {
+		p.Widgets = append(p.Widgets, &oscWidget{
+			Type:     "button",
+			ID:       widgetID(c.Address),
+			Label:    c.Label(),
+			Height:   toggleHeight,
+			ColorWid: cssColor(c.color()),
+			Address:  c.Address,
+			TypeTags: "i",
+			Mode:     "toggle",
+			On:       &one,
+			Off:      &zero,
+		})
+	}
+	row := &oscWidget{
+		Type:   "panel",
+		ID:     widgetID(s.Address + "/faders"),
+		Label:  false,
+		Layout: "horizontal",
+		Expand: true,
+	}
+	for _, c := range s.Faders() {
+		row.Widgets = append(row.Widgets, &oscWidget{
+			Type:     "fader",
+			ID:       widgetID(c.Address),
+			Label:    c.Label(),
+			Expand:   true,
+			ColorWid: cssColor(c.color()),
+			Address:  c.Address,
+			TypeTags: "i",
+			Range:    &oscRange{Min: c.Min, Max: c.Max},
+			Steps:    c.Max - c.Min + 1,
+			Decimals: &zero,
+			Unit:     c.Unit,
+		})
+	}
+	if len(row.Widgets) > 0 {
+		p.Widgets = append(p.Widgets, row)
+	}
+	return p
+}
+
+// widgetID returns the ID of the widget of an address, e.g. `input_mic_1_gain`.
+func widgetID(a string) string {
+	return strings.Replace(strings.TrimPrefix(a, "/"), "/", "_", -1)
+}
+
+// cssColor returns the CSS color of an RGB color.
+func cssColor(rgb [3]float64) string {
+	return fmt.Sprintf("rgb(%.0f, %.0f, %.0f)", rgb[0]*255, rgb[1]*255, rgb[2]*255)
+}
@@ -0,0 +1,181 @@
+package layouts
+
+import (
+	"bytes"
+	"compress/zlib"
+	"crypto/sha1"
+	"fmt"
+	"strings"
+)
+
+// encodeTouchOSC returns the layout as a TouchOSC (.tosc) document: zlib
+// compressed XML. The values are sent as integers, and the controls are
+// updated by the replies and pushes of the server.
+func encodeTouchOSC(l *Layout) ([]byte, error) {
+	x := &toscWriter{buf: &bytes.Buffer{}}
+	x.buf.WriteString("<?xml version='1.0' encoding='UTF-8'?>")
+	x.buf.WriteString("<lexml version='3'>")
+	x.startNode(l.Title, "GROUP")
+	x.properties(func() {
+		x.stringProperty("name", l.Title)
+		x.frameProperty(0, 0, stripWidth*len(l.Strips), layoutHeight)
+		x.colorProperty([3]float64{0, 0, 0})
+	})
+	x.buf.WriteString("<children>")
+	for i, s := range l.Strips {
+		x.strip(i, s)
+	}
+	x.buf.WriteString("</children>")
+	x.endNode()
+	x.buf.WriteString("</lexml>")
+
+	out := &bytes.Buffer{}
+	zw := zlib.NewWriter(out)
+	if _, err := zw.Write(x.buf.Bytes()); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// toscWriter writes the XML of TouchOSC nodes.
+type toscWriter struct {
+	buf *bytes.Buffer
+}
+
+// strip writes the group of the i-th strip, with its label above its toggles,
+// above its faders. Frames are relative to the parent node.
+func (x *toscWriter) strip(i int, s *Strip) {
+	x.startNode(s.Address, "GROUP")
+	x.properties(func() {
+		x.stringProperty("name", widgetID(s.Address))
+		x.frameProperty(i*stripWidth, 0, stripWidth, layoutHeight)
+		x.colorProperty([3]float64{0, 0, 0})
+	})
+	x.buf.WriteString("<children>")
+
+	x.startNode(s.Address+"/label", "LABEL")
+	x.properties(func() {
+		x.stringProperty("name", widgetID(s.Address)+"_label")
+		x.frameProperty(spacing/2, spacing/2, stripWidth-spacing, labelHeight)
+		x.colorProperty([3]float64{1, 1, 1})
+		x.boolProperty("background", false)
+		x.intProperty("textSize", 14)
+	})
+	x.values(func() { x.value("text", s.Label) })
+	x.endNode()
+
+	y := labelHeight + spacing
+	for _, c := range s.Toggles() {
+		x.control(c, "BUTTON", spacing/2, y, stripWidth-spacing, toggleHeight, func() {
+			x.intProperty("buttonType", 1) // Toggle on release.
+		})
+		y += toggleHeight + spacing
+	}
+	if fs := s.Faders(); len(fs) > 0 {
+		w := (stripWidth - spacing) / len(fs)
+		for j, c := range fs {
+			x.control(c, "FADER", spacing/2+j*w, y, w, layoutHeight-y-spacing, func() {
+				x.boolProperty("cursor", true)
+				x.intProperty("orientation", 0) // Upwards.
+			})
+		}
+	}
+
+	x.buf.WriteString("</children>")
+	x.endNode()
+}
+
+// control writes the node of a control, which sends its `x` value to the
+// address of the control, scaled to its range.
+func (x *toscWriter) control(c *Control, typ string, fx, fy, fw, fh int, props func()) {
+	x.startNode(c.Address, typ)
+	x.properties(func() {
+		x.stringProperty("name", widgetID(c.Address))
+		x.frameProperty(fx, fy, fw, fh)
+		x.colorProperty(c.color())
+		x.boolProperty("outline", true)
+		props()
+	})
+	x.values(func() {
+		x.value("x", "0")
+		x.value("touch", "false")
+	})
+	x.buf.WriteString("<messages><osc>")
+	x.buf.WriteString("<enabled>1</enabled><send>1</send><receive>1</receive><feedback>0</feedback>")
+	x.buf.WriteString("<connections>00001</connections>")
+	x.buf.WriteString("<triggers><trigger><var>" + cdata("x") + "</var><condition>ANY</condition></trigger></triggers>")
+	x.buf.WriteString("<path>")
+	x.partial("CONSTANT", "STRING", c.Address, 0, 1)
+	x.buf.WriteString("</path><arguments>")
+	x.partial("VALUE", "INTEGER", "x", c.Min, c.Max)
+	x.buf.WriteString("</arguments></osc></messages>")
+	x.endNode()
+}
+
+func (x *toscWriter) startNode(name, typ string) {
+	fmt.Fprintf(x.buf, "<node ID='%s' type='%s'>", nodeID(name), typ)
+}
+
+func (x *toscWriter) endNode() { x.buf.WriteString("</node>") }
+
+func (x *toscWriter) properties(fn func()) {
+	x.buf.WriteString("<properties>")
+	fn()
+	x.buf.WriteString("</properties>")
+}
+
+func (x *toscWriter) property(typ, key, value string) {
+	fmt.Fprintf(x.buf, "<property type='%s'><key>%s</key><value>%s</value></property>", typ, cdata(key), value)
+}
+
+func (x *toscWriter) stringProperty(key, v string) { x.property("s", key, cdata(v)) }
+
+func (x *toscWriter) intProperty(key string, v int) { x.property("i", key, fmt.Sprint(v)) }
+
+func (x *toscWriter) boolProperty(key string, v bool) {
+	if v {
+		x.property("b", key, "1")
+		return
+	}
+	x.property("b", key, "0")
+}
+
+func (x *toscWriter) frameProperty(fx, fy, fw, fh int) {
+	x.property("r", "frame", fmt.Sprintf("<x>%d</x><y>%d</y><w>%d</w><h>%d</h>", fx, fy, fw, fh))
+}
+
+func (x *toscWriter) colorProperty(rgb [3]float64) {
+	x.property("c", "color", fmt.Sprintf("<r>%g</r><g>%g</g><b>%g</b><a>1</a>", rgb[0], rgb[1], rgb[2]))
+}
+
+func (x *toscWriter) values(fn func()) {
+	x.buf.WriteString("<values>")
+	fn()
+	x.buf.WriteString("</values>")
+}
+
+func (x *toscWriter) value(key, def string) {
+	fmt.Fprintf(x.buf, "<value><key>%s</key><locked>0</locked><lockedDefaultCurrent>0</lockedDefaultCurrent><default>%s</default><defaultPull>0</defaultPull></value>",
+		cdata(key), cdata(def))
+}
+
+// partial writes a part of an OSC path or argument.
+func (x *toscWriter) partial(typ, conversion, value string, min, max int) {
+	fmt.Fprintf(x.buf, "<partial><type>%s</type><conversion>%s</conversion><value>%s</value><scaleMin>%d</scaleMin><scaleMax>%d</scaleMax></partial>",
+		typ, conversion, cdata(value), min, max)
+}
+
+// cdata returns the string as CDATA.
+func cdata(s string) string {
+	return "<![CDATA[" + strings.Replace(s, "]]>", "]]]]><![CDATA[>", -1) + "]]>"
+}
+
+// nodeID returns a stable UUID-like ID of a name, so that layouts of the same
+// addresses are identical.
+func nodeID(name string) string {
+	h := sha1.Sum([]byte(name))
+	return fmt.Sprintf("%x-%x-%x-%x-%x", h[0:4], h[4:6], h[6:8], h[8:10], h[10:16])
+}
@@ -12,6 +12,7 @@ package leds
 import (
 	"fmt"
 	"os"
+	"sort"
 
 	"github.com/kward/avid-s3l/carbonio/spi"
 )
@@ -122,6 +123,19 @@ func (l *LED) SetState(s State) error {
 	return l.spi.Write(v)
 }
 
+// States returns the supported states of the LED, ordered by SPI value.
+func (l *LED) States() []State {
+	ss := []State{}
+	for s := range l.states {
+		if s == testState {
+			continue
+		}
+		ss = append(ss, s)
+	}
+	sort.Slice(ss, func(i, j int) bool { return l.states[ss[i]] < l.states[ss[j]] })
+	return ss
+}
+
 // Initialize implements spi.Implementation.
 func (l *LED) Initialize() error { return l.SetState(Off) }
 
@@ -0,0 +1,316 @@
+/*
+Package metrics exports the metrics of the carbonio server in the Prometheus
+text exposition format, without any dependency on the Prometheus libraries.
+
+The metrics are:
+
+	carbonio_input_gain_db{address,label}       gain of each input
+	carbonio_input_pad{address,label}           1 if the pad is enabled
+	carbonio_input_phantom{address,label}       1 if phantom power is enabled
+	carbonio_led_state{address,label,state}     1 for the current state of each LED
+	carbonio_parameter_read_errors_total        parameters that could not be read
+	carbonio_spi_reads_total                    SPI reads
+	carbonio_spi_read_errors_total              failed SPI reads
+	carbonio_spi_writes_total                   SPI writes
+	carbonio_spi_write_errors_total             failed SPI writes
+	carbonio_spi_mismatches_total               writes whose read-after-write differed
+	carbonio_http_request_duration_seconds{method,route,code}
+	                                            histogram of HTTP requests
+*/
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/kward/avid-s3l/carbonio/address"
+	"github.com/kward/avid-s3l/carbonio/helpers"
+	"github.com/kward/avid-s3l/carbonio/spi"
+)
+
+// ContentType is the content type of the text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Namespace prefixes the names of all the metrics.
+const Namespace = "carbonio"
+
+// DefaultBuckets are the upper bounds of the buckets of the HTTP request
+// duration histogram, in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Metrics collects the metrics of a device, and of the HTTP requests of its
+// server.
+type Metrics struct {
+	// readErrors counts the parameters that could not be read when scraped. It
+	// is the first field, so that it is 64-bit aligned for atomic operations.
+	readErrors uint64
+
+	resolver address.Resolver
+	buckets  []float64
+
+	mu       sync.Mutex
+	requests map[requestKey]*histogram
+}
+
+// New returns the metrics of the device of the resolver.
+func New(resolver address.Resolver) *Metrics {
+	return &Metrics{
+		resolver: resolver,
+		buckets:  DefaultBuckets,
+		requests: map[requestKey]*histogram{},
+	}
+}
+
+// requestKey holds the labels of an HTTP request.
+type requestKey struct {
+	method, route, code string
+}
+
+// histogram holds the observations of a histogram series.
+type histogram struct {
+	counts []uint64 // Per bucket, not cumulative.
+	count  uint64
+	sum    float64
+}
+
+// Middleware observes the duration of the requests of the router. It is used
+// with mux.Router.Use, so that requests are labeled with the path template of
+// their route rather than their path, which would be unbounded.
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := helpers.NewResponseRecorder(w)
+		next.ServeHTTP(rec, r)
+
+		route := "unknown"
+		if cr := mux.CurrentRoute(r); cr != nil {
+			if tmpl, err := cr.GetPathTemplate(); err == nil {
+				route = tmpl
+			}
+		}
+		code := rec.Status
+		if code == 0 {
+			code = http.StatusOK
+		}
+		m.observe(requestKey{r.Method, route, strconv.Itoa(code)}, time.Since(start).Seconds())
+	})
+}
+
+// observe a request duration.
+func (m *Metrics) observe(k requestKey, secs float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	h, ok := m.requests[k]
+	if !ok {
+		h = &histogram{counts: make([]uint64, len(m.buckets)+1)}
+		m.requests[k] = h
+	}
+	i := sort.SearchFloat64s(m.buckets, secs) // First bucket with bound >= secs.
+	h.counts[i]++
+	h.count++
+	h.sum += secs
+}
+
+// ServeHTTP writes the metrics in the text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	buf := &bytes.Buffer{}
+	m.Write(buf)
+	w.Header().Set("Content-Type", ContentType)
+	w.Write(buf.Bytes())
+}
+
+// Write the metrics in the text exposition format.
+func (m *Metrics) Write(w io.Writer) error {
+	e := &encoder{w: w}
+	m.writeParameters(e)
+
+	st := spi.GetStats()
+	for _, c := range []struct {
+		name, help string
+		v          uint64
+	}{
+		{"spi_reads_total", "SPI reads.", st.Reads},
+		{"spi_read_errors_total", "SPI reads that failed.", st.ReadErrors},
+		{"spi_writes_total", "SPI writes.", st.Writes},
+		{"spi_write_errors_total", "SPI writes that failed.", st.WriteErrors},
+		{"spi_mismatches_total", "SPI writes whose read-after-write returned another value.", st.Mismatches},
+	} {
+		e.family(c.name, c.help, "counter")
+		e.sample(c.name, nil, float64(c.v))
+	}
+
+	m.writeRequests(e)
+	return e.err
+}
+
+// writeParameters writes a gauge of every parameter of the device. Int
+// properties are written with their unit, Bool properties as 0 or 1, and Enum
+// properties as one series per state, of which the current state is 1.
+func (m *Metrics) writeParameters(e *encoder) {
+	ts, err := address.ResolveString(m.resolver, address.Wildcard)
+	if err != nil {
+		atomic.AddUint64(&m.readErrors, 1)
+		ts = nil
+	}
+
+	// Group the targets by metric, so that each family is written once.
+	type series struct {
+		t *address.Target
+		v interface{}
+	}
+	names := []string{}
+	families := map[string][]series{}
+	for _, t := range ts {
+		v, err := t.Value()
+		if err != nil {
+			atomic.AddUint64(&m.readErrors, 1)
+			continue
+		}
+		name := metricName(t)
+		if _, ok := families[name]; !ok {
+			names = append(names, name)
+		}
+		families[name] = append(families[name], series{t, v})
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		ss := families[name]
+		p := ss[0].t.Property
+		help := fmt.Sprintf("The %s of each %s.", p.Name, ss[0].t.Address.Segment(0))
+		if p.Unit != "" {
+			help = fmt.Sprintf("The %s of each %s, in %s.", p.Name, ss[0].t.Address.Segment(0), p.Unit)
+		}
+		e.family(name, help, "gauge")
+		for _, s := range ss {
+			labels := []string{"address", s.t.Node().String(), "label", s.t.Label}
+			switch v := s.v.(type) {
+			case int:
+				e.sample(name, labels, float64(v))
+			case bool:
+				e.sample(name, labels, boolValue(v))
+			case string:
+				for _, state := range p.States {
+					e.sample(name, append(labels, "state", state), boolValue(state == v))
+				}
+			}
+		}
+	}
+
+	e.family("parameter_read_errors_total", "Parameters that could not be read for metrics.", "counter")
+	e.sample("parameter_read_errors_total", nil, float64(atomic.LoadUint64(&m.readErrors)))
+}
+
+// writeRequests writes the HTTP request duration histogram.
+func (m *Metrics) writeRequests(e *encoder) {
+	const name = "http_request_duration_seconds"
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	keys := []requestKey{}
+	for k := range m.requests {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := keys[i], keys[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.code < b.code
+	})
+
+	e.family(name, "Duration of the HTTP requests, by route.", "histogram")
+	for _, k := range keys {
+		h := m.requests[k]
+		labels := []string{"method", k.method, "route", k.route, "code", k.code}
+		cum := uint64(0)
+		for i, bound := range append(m.buckets, math.Inf(1)) {
+			cum += h.counts[i]
+			e.sample(name+"_bucket", append(labels, "le", formatFloat(bound)), float64(cum))
+		}
+		e.sample(name+"_sum", labels, h.sum)
+		e.sample(name+"_count", labels, float64(h.count))
+	}
+}
+
+// invalidNameRE matches the characters that are not valid in metric names.
+var invalidNameRE = regexp.MustCompile(`[^a-zA-Z0-9_]+`)
+
+// metricName returns the name of the metric of a target, without the
+// namespace, e.g. `input_gain_db`.
+func metricName(t *address.Target) string {
+	parts := []string{t.Address.Segment(0), t.Property.Name}
+	if t.Property.Unit != "" {
+		parts = append(parts, t.Property.Unit)
+	}
+	return strings.ToLower(invalidNameRE.ReplaceAllString(strings.Join(parts, "_"), "_"))
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// formatFloat formats a value as the exposition format does.
+func formatFloat(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return "+Inf"
+	case math.IsInf(f, -1):
+		return "-Inf"
+	case math.IsNaN(f):
+		return "NaN"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// labelEscaper escapes label values.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// encoder writes metrics in the text exposition format, keeping the first
+// error.
+type encoder struct {
+	w   io.Writer
+	err error
+}
+
+func (e *encoder) printf(format string, a ...interface{}) {
+	if e.err != nil {
+		return
+	}
+	_, e.err = fmt.Fprintf(e.w, format, a...)
+}
+
+// family writes the HELP and TYPE lines of a metric family.
+func (e *encoder) family(name, help, typ string) {
+	e.printf("# HELP %s_%s %s\n# TYPE %s_%s %s\n", Namespace, name, help, Namespace, name, typ)
+}
+
+// sample writes a sample with labels, given as name/value pairs.
+func (e *encoder) sample(name string, labels []string, v float64) {
+	strs := []string{}
+	for i := 0; i+1 < len(labels); i += 2 {
+		strs = append(strs, fmt.Sprintf(`%s="%s"`, labels[i], labelEscaper.Replace(labels[i+1])))
+	}
+	ls := ""
+	if len(strs) > 0 {
+		ls = "{" + strings.Join(strs, ",") + "}"
+	}
+	e.printf("%s_%s%s %s\n", Namespace, name, ls, formatFloat(v))
+}
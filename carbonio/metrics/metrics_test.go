@@ -0,0 +1,128 @@
+package metrics
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kward/avid-s3l/carbonio/address"
+	"github.com/kward/avid-s3l/carbonio/devices/devicestest"
+)
+
+func newMetrics(t *testing.T) (*Metrics, func()) {
+	dir, err := ioutil.TempDir("", "metrics")
+	if err != nil {
+		t.Fatalf("error creating temp dir; %s", err)
+	}
+	d, err := devicestest.NewStage16(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("error instantiating Stage16; %s", err)
+	}
+	return New(address.NewDeviceResolver(d)), func() { os.RemoveAll(dir) }
+}
+
+func TestWrite(t *testing.T) {
+	m, cleanup := newMetrics(t)
+	defer cleanup()
+
+	r := mux.NewRouter()
+	r.Use(m.Middleware)
+	r.HandleFunc("/api/v1/signals/{name}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/signals/x", nil))
+
+	buf := &bytes.Buffer{}
+	if err := m.Write(buf); err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"# TYPE carbonio_input_gain_db gauge\n",
+		`carbonio_input_gain_db{address="input/mic/1",label="Mic input #1"} `,
+		`carbonio_input_pad{address="input/mic/16",label="Mic input #16"} `,
+		`carbonio_input_phantom{address="input/mic/1",label="Mic input #1"} `,
+		"# TYPE carbonio_led_state gauge\n",
+		`carbonio_led_state{address="led/status",label="Status LED",state="On"} `,
+		"# TYPE carbonio_spi_reads_total counter\n",
+		"carbonio_spi_mismatches_total ",
+		"# TYPE carbonio_http_request_duration_seconds histogram\n",
+		`carbonio_http_request_duration_seconds_bucket{method="GET",route="/api/v1/signals/{name}",code="404",le="+Inf"} 1` + "\n",
+		`carbonio_http_request_duration_seconds_count{method="GET",route="/api/v1/signals/{name}",code="404"} 1` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics are missing %q", want)
+		}
+	}
+
+	// Every LED has exactly one current state.
+	on := 0
+	for _, line := range strings.Split(out, "\n") {
+		if strings.HasPrefix(line, `carbonio_led_state{address="led/status"`) && strings.HasSuffix(line, " 1") {
+			on++
+		}
+	}
+	if on != 1 {
+		t.Errorf("led/status has %d current states, want 1", on)
+	}
+}
+
+func TestObserve(t *testing.T) {
+	m := New(nil)
+	k := requestKey{"GET", "/", "200"}
+	for _, secs := range []float64{0.001, 0.005, 0.2, 60} {
+		m.observe(k, secs)
+	}
+	h := m.requests[k]
+	for _, tc := range []struct {
+		bound float64
+		count uint64
+	}{
+		{0.005, 2}, // Bounds are inclusive.
+		{0.25, 1},
+		{10, 0},
+	} {
+		i := 0
+		for i < len(m.buckets) && m.buckets[i] != tc.bound {
+			i++
+		}
+		if got := h.counts[i]; got != tc.count {
+			t.Errorf("bucket le=%v count = %d, want %d", tc.bound, got, tc.count)
+		}
+	}
+	if got := h.counts[len(m.buckets)]; got != 1 {
+		t.Errorf("bucket le=+Inf count = %d, want 1", got)
+	}
+	if h.count != 4 {
+		t.Errorf("count = %d, want 4", h.count)
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	for _, tc := range []struct {
+		desc   string
+		labels []string
+		v      float64
+		want   string
+	}{
+		{"no labels", nil, 1, "carbonio_x 1\n"},
+		{"labels", []string{"a", "b", "c", "d"}, 2.5, `carbonio_x{a="b",c="d"} 2.5` + "\n"},
+		{"escaped", []string{"a", "\"q\"\\\n"}, 0, `carbonio_x{a="\"q\"\\\n"} 0` + "\n"},
+	} {
+		t.Run(fmt.Sprintf("sample() %s", tc.desc), func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			e := &encoder{w: buf}
+			e.sample("x", tc.labels, tc.v)
+			if got := buf.String(); got != tc.want {
+				t.Errorf("= %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
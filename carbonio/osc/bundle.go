@@ -0,0 +1,153 @@
+package osc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+// Packet is an OSC packet, either a *Message or a *Bundle.
+type Packet interface {
+	MarshalBinary() ([]byte, error)
+	String() string
+}
+
+// bundleTag starts the data of bundles.
+const bundleTag = "#bundle\x00"
+
+// Timetag is an OSC time tag: the NTP seconds since 1900-01-01 in the upper
+// 32 bits, and the fraction of a second in the lower 32 bits.
+type Timetag uint64
+
+// Immediately is the time tag of bundles to apply on receipt.
+const Immediately Timetag = 1
+
+// ntpEpochOffset is the number of seconds from the NTP epoch, 1900-01-01, to
+// the Unix epoch.
+const ntpEpochOffset = 2208988800
+
+// NewTimetag returns the time tag of the time.
+func NewTimetag(t time.Time) Timetag {
+	secs := uint64(t.Unix() + ntpEpochOffset)
+	frac := (uint64(t.Nanosecond()) << 32) / uint64(time.Second)
+	return Timetag(secs<<32 | frac)
+}
+
+// Time returns the time of the time tag.
+func (t Timetag) Time() time.Time {
+	secs := int64(t>>32) - ntpEpochOffset
+	nsecs := (uint64(t&0xffffffff) * uint64(time.Second)) >> 32
+	return time.Unix(secs, int64(nsecs))
+}
+
+// IsImmediate returns true for Immediately.
+func (t Timetag) IsImmediate() bool { return t == Immediately }
+
+// String implements fmt.Stringer.
+func (t Timetag) String() string {
+	if t.IsImmediate() {
+		return "immediately"
+	}
+	return t.Time().UTC().Format(time.RFC3339Nano)
+}
+
+// Bundle is an OSC bundle of packets, which are to be applied together at its
+// time.
+type Bundle struct {
+	Time     Timetag
+	Elements []Packet
+}
+
+// NewBundle returns a bundle of the packets.
+func NewBundle(t Timetag, elems ...Packet) *Bundle {
+	return &Bundle{Time: t, Elements: elems}
+}
+
+// String implements fmt.Stringer.
+func (b *Bundle) String() string {
+	return fmt.Sprintf("#bundle %s (%d elements)", b.Time, len(b.Elements))
+}
+
+// Messages returns the messages of the bundle, including those of nested
+// bundles, in order.
+func (b *Bundle) Messages() []*Message {
+	ms := []*Message{}
+	for _, e := range b.Elements {
+		switch t := e.(type) {
+		case *Message:
+			ms = append(ms, t)
+		case *Bundle:
+			ms = append(ms, t.Messages()...)
+		}
+	}
+	return ms
+}
+
+// MarshalBinary encodes the bundle.
+func (b *Bundle) MarshalBinary() ([]byte, error) {
+	buf := &bytes.Buffer{}
+	buf.WriteString(bundleTag)
+	binary.Write(buf, binary.BigEndian, uint64(b.Time))
+	for _, e := range b.Elements {
+		data, err := e.MarshalBinary()
+		if err != nil {
+			return nil, err
+		}
+		binary.Write(buf, binary.BigEndian, int32(len(data)))
+		buf.Write(data)
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a bundle. The time tags of nested bundles must not
+// be earlier than that of their bundle, unless they are Immediately.
+func (b *Bundle) UnmarshalBinary(data []byte) error {
+	if !bytes.HasPrefix(data, []byte(bundleTag)) {
+		return fmt.Errorf("not a bundle")
+	}
+	r := &reader{data: data, off: len(bundleTag)}
+	t, err := r.uint64()
+	if err != nil {
+		return fmt.Errorf("error reading bundle time tag; %s", err)
+	}
+	b.Time, b.Elements = Timetag(t), nil
+	for r.len() > 0 {
+		n, err := r.uint32()
+		if err != nil {
+			return fmt.Errorf("error reading bundle element size; %s", err)
+		}
+		if n == 0 || n%4 != 0 {
+			return fmt.Errorf("invalid bundle element size %d; want a multiple of 4", n)
+		}
+		elem, err := r.next(int(int32(n)))
+		if err != nil {
+			return fmt.Errorf("error reading bundle element; %s", err)
+		}
+		p, err := ParsePacket(elem)
+		if err != nil {
+			return err
+		}
+		if nb, ok := p.(*Bundle); ok && !nb.Time.IsImmediate() && nb.Time < b.Time {
+			return fmt.Errorf("nested bundle time tag %s is earlier than %s", nb.Time, b.Time)
+		}
+		b.Elements = append(b.Elements, p)
+	}
+	return nil
+}
+
+// ParsePacket decodes a message or a bundle.
+func ParsePacket(data []byte) (Packet, error) {
+	if bytes.HasPrefix(data, []byte(bundleTag)) {
+		b := &Bundle{}
+		if err := b.UnmarshalBinary(data); err != nil {
+			return nil, err
+		}
+		return b, nil
+	}
+	m := &Message{}
+	if err := m.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
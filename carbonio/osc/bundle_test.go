@@ -0,0 +1,103 @@
+package osc
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTimetag(t *testing.T) {
+	for _, tc := range []struct {
+		desc string
+		time time.Time
+		tag  Timetag
+	}{
+		{"unix epoch", time.Unix(0, 0), Timetag(ntpEpochOffset << 32)},
+		{"half second", time.Unix(0, 500000000), Timetag(ntpEpochOffset<<32 | 1<<31)},
+		{"2020", time.Date(2020, 2, 24, 13, 0, 0, 0, time.UTC), Timetag(uint64(3791538000) << 32)},
+	} {
+		t.Run(fmt.Sprintf("NewTimetag() %s", tc.desc), func(t *testing.T) {
+			if got := NewTimetag(tc.time); got != tc.tag {
+				t.Errorf("NewTimetag() = %#x, want %#x", uint64(got), uint64(tc.tag))
+			}
+			if got := tc.tag.Time(); !got.Equal(tc.time) {
+				t.Errorf("Time() = %s, want %s", got, tc.time)
+			}
+		})
+	}
+
+	// The precision is about 233 picoseconds, so round trips are within a
+	// nanosecond.
+	now := time.Now()
+	if d := NewTimetag(now).Time().Sub(now); d > time.Nanosecond || d < -time.Nanosecond {
+		t.Errorf("round trip of %s is off by %s", now, d)
+	}
+}
+
+func TestBundle(t *testing.T) {
+	at := NewTimetag(time.Date(2020, 2, 24, 13, 0, 0, 0, time.UTC))
+	b := NewBundle(at,
+		NewMessage("/input/mic/1/gain", int32(30)),
+		NewBundle(at+1, NewMessage("/input/mic/1/pad", true)),
+		NewMessage("/input/mic/1/phantom", int32(1)))
+	data, err := b.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	if !bytes.HasPrefix(data, []byte("#bundle\x00")) {
+		t.Errorf("data = %q, want a #bundle prefix", data)
+	}
+
+	p, err := ParsePacket(data)
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	if !reflect.DeepEqual(p, b) {
+		t.Errorf("ParsePacket() = %#v, want %#v", p, b)
+	}
+	got := []string{}
+	for _, m := range p.(*Bundle).Messages() {
+		got = append(got, m.Address)
+	}
+	if want := []string{"/input/mic/1/gain", "/input/mic/1/pad", "/input/mic/1/phantom"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Messages() = %v, want %v", got, want)
+	}
+}
+
+func TestParsePacket(t *testing.T) {
+	nested := func(outer, inner Timetag) []byte {
+		data, err := NewBundle(outer, NewBundle(inner, NewMessage("/a"))).MarshalBinary()
+		if err != nil {
+			t.Fatalf("unexpected error; %s", err)
+		}
+		return data
+	}
+	for _, tc := range []struct {
+		desc string
+		data []byte
+		ok   bool
+	}{
+		{"message", []byte("/a\x00\x00,\x00\x00\x00"), true},
+		{"empty bundle", []byte("#bundle\x00\x00\x00\x00\x00\x00\x00\x00\x01"), true},
+		{"later nested bundle", nested(100, 200), true},
+		{"immediate nested bundle", nested(100, Immediately), true},
+		{"earlier nested bundle", nested(200, 100), false},
+		{"short time tag", []byte("#bundle\x00\x00\x00"), false},
+		{"short element", []byte("#bundle\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x08/a\x00\x00"), false},
+		{"unaligned element", []byte("#bundle\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x03/a\x00\x00"), false},
+		{"empty element", []byte("#bundle\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x00"), false},
+		{"invalid element", []byte("#bundle\x00\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x04abc\x00"), false},
+	} {
+		t.Run(fmt.Sprintf("ParsePacket() %s", tc.desc), func(t *testing.T) {
+			_, err := ParsePacket(tc.data)
+			if err == nil && !tc.ok {
+				t.Fatalf("expected an error")
+			}
+			if err != nil && tc.ok {
+				t.Fatalf("unexpected error; %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,249 @@
+/*
+Package osc encodes and decodes Open Sound Control (OSC) 1.0 packets.
+
+A packet is either a message, or a bundle of packets with a time tag. A message
+consists of an address pattern, e.g. `/input/mic/1/gain`, and arguments. The
+supported argument types, and their Go types, are:
+
+	i  int32
+	f  float32
+	s  string
+	b  []byte (blob)
+	h  int64
+	d  float64
+	T  true
+	F  false
+	N  nil
+
+See http://opensoundcontrol.org/spec-1_0 for the specification.
+*/
+package osc
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Message is an OSC message.
+type Message struct {
+	// Address pattern of the message, e.g. `/led/status`.
+	Address string
+	// Args of the message.
+	Args []interface{}
+}
+
+// NewMessage returns a message of the address, with the arguments.
+func NewMessage(addr string, args ...interface{}) *Message {
+	return &Message{Address: addr, Args: args}
+}
+
+// String implements fmt.Stringer.
+func (m *Message) String() string {
+	strs := []string{m.Address}
+	for _, arg := range m.Args {
+		strs = append(strs, fmt.Sprintf("%v", arg))
+	}
+	return strings.Join(strs, " ")
+}
+
+// TypeTags returns the type tags of the arguments, e.g. `,if`.
+func (m *Message) TypeTags() (string, error) {
+	tags := []byte{','}
+	for _, arg := range m.Args {
+		tag, err := typeTag(arg)
+		if err != nil {
+			return "", err
+		}
+		tags = append(tags, tag)
+	}
+	return string(tags), nil
+}
+
+func typeTag(arg interface{}) (byte, error) {
+	switch t := arg.(type) {
+	case int32, int:
+		return 'i', nil
+	case float32:
+		return 'f', nil
+	case string:
+		return 's', nil
+	case []byte:
+		return 'b', nil
+	case int64:
+		return 'h', nil
+	case float64:
+		return 'd', nil
+	case bool:
+		if t {
+			return 'T', nil
+		}
+		return 'F', nil
+	case nil:
+		return 'N', nil
+	}
+	return 0, fmt.Errorf("unsupported argument type %T", arg)
+}
+
+// MarshalBinary encodes the message.
+func (m *Message) MarshalBinary() ([]byte, error) {
+	if !strings.HasPrefix(m.Address, "/") {
+		return nil, fmt.Errorf("invalid address %q; want a leading /", m.Address)
+	}
+	tags, err := m.TypeTags()
+	if err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	writeString(buf, m.Address)
+	writeString(buf, tags)
+	for _, arg := range m.Args {
+		switch t := arg.(type) {
+		case int:
+			if t < math.MinInt32 || t > math.MaxInt32 {
+				return nil, fmt.Errorf("int argument %d overflows int32", t)
+			}
+			binary.Write(buf, binary.BigEndian, int32(t))
+		case int32, float32, int64, float64:
+			binary.Write(buf, binary.BigEndian, t)
+		case string:
+			writeString(buf, t)
+		case []byte:
+			binary.Write(buf, binary.BigEndian, int32(len(t)))
+			buf.Write(t)
+			buf.Write(make([]byte, padding(len(t))))
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// writeString writes a null-terminated string, padded to 4 bytes.
+func writeString(buf *bytes.Buffer, s string) {
+	buf.WriteString(s)
+	buf.Write(make([]byte, 4-len(s)%4))
+}
+
+// padding returns the padding of `n` bytes of data to 4 bytes.
+func padding(n int) int {
+	return (4 - n%4) % 4
+}
+
+// UnmarshalBinary decodes a message.
+func (m *Message) UnmarshalBinary(data []byte) error {
+	r := &reader{data: data}
+	addr, err := r.string()
+	if err != nil {
+		return fmt.Errorf("error reading address; %s", err)
+	}
+	if !strings.HasPrefix(addr, "/") {
+		return fmt.Errorf("invalid address %q; want a leading /", addr)
+	}
+	m.Address, m.Args = addr, nil
+	if r.len() == 0 {
+		return nil // Type tags are optional in OSC 1.0.
+	}
+	tags, err := r.string()
+	if err != nil {
+		return fmt.Errorf("error reading type tags of %s; %s", addr, err)
+	}
+	if !strings.HasPrefix(tags, ",") {
+		return fmt.Errorf("invalid type tags %q of %s", tags, addr)
+	}
+	for _, tag := range []byte(tags[1:]) {
+		arg, err := r.arg(tag)
+		if err != nil {
+			return fmt.Errorf("error reading argument of %s; %s", addr, err)
+		}
+		m.Args = append(m.Args, arg)
+	}
+	if r.len() > 0 {
+		return fmt.Errorf("%d unexpected bytes after the arguments of %s", r.len(), addr)
+	}
+	return nil
+}
+
+// reader reads the OSC data types.
+type reader struct {
+	data []byte
+	off  int
+}
+
+func (r *reader) len() int { return len(r.data) - r.off }
+
+func (r *reader) next(n int) ([]byte, error) {
+	if n < 0 || r.len() < n {
+		return nil, fmt.Errorf("short data; want %d bytes, have %d", n, r.len())
+	}
+	b := r.data[r.off : r.off+n]
+	r.off += n
+	return b, nil
+}
+
+func (r *reader) string() (string, error) {
+	i := bytes.IndexByte(r.data[r.off:], 0)
+	if i < 0 {
+		return "", fmt.Errorf("unterminated string")
+	}
+	s := string(r.data[r.off : r.off+i])
+	if _, err := r.next(i + 1 + padding(i+1)); err != nil {
+		return "", err
+	}
+	return s, nil
+}
+
+func (r *reader) uint32() (uint32, error) {
+	b, err := r.next(4)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint32(b), nil
+}
+
+func (r *reader) uint64() (uint64, error) {
+	b, err := r.next(8)
+	if err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(b), nil
+}
+
+func (r *reader) arg(tag byte) (interface{}, error) {
+	switch tag {
+	case 'i':
+		v, err := r.uint32()
+		return int32(v), err
+	case 'f':
+		v, err := r.uint32()
+		return math.Float32frombits(v), err
+	case 's':
+		return r.string()
+	case 'b':
+		n, err := r.uint32()
+		if err != nil {
+			return nil, err
+		}
+		b, err := r.next(int(int32(n)))
+		if err != nil {
+			return nil, err
+		}
+		if _, err := r.next(padding(len(b))); err != nil {
+			return nil, err
+		}
+		return append([]byte{}, b...), nil
+	case 'h':
+		v, err := r.uint64()
+		return int64(v), err
+	case 'd':
+		v, err := r.uint64()
+		return math.Float64frombits(v), err
+	case 'T':
+		return true, nil
+	case 'F':
+		return false, nil
+	case 'N':
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported type tag %q", tag)
+}
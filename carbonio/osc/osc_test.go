@@ -0,0 +1,108 @@
+package osc
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestMessageMarshalBinary(t *testing.T) {
+	for _, tc := range []struct {
+		desc string
+		msg  *Message
+		data []byte
+		ok   bool
+	}{
+		{"no args", NewMessage("/led"),
+			[]byte("/led\x00\x00\x00\x00,\x00\x00\x00"), true},
+		{"int", NewMessage("/a", int32(1000)),
+			[]byte("/a\x00\x00,i\x00\x00\x00\x00\x03\xe8"), true},
+		{"go int", NewMessage("/a", 1000),
+			[]byte("/a\x00\x00,i\x00\x00\x00\x00\x03\xe8"), true},
+		{"float", NewMessage("/a", float32(440)),
+			[]byte("/a\x00\x00,f\x00\x00\x43\xdc\x00\x00"), true},
+		{"string", NewMessage("/a", "hello"),
+			[]byte("/a\x00\x00,s\x00\x00hello\x00\x00\x00"), true},
+		{"blob", NewMessage("/a", []byte{1, 2, 3}),
+			[]byte("/a\x00\x00,b\x00\x00\x00\x00\x00\x03\x01\x02\x03\x00"), true},
+		{"bools and nil", NewMessage("/a", true, false, nil),
+			[]byte("/a\x00\x00,TFN\x00\x00\x00\x00"), true},
+		{"no leading slash", NewMessage("a"), nil, false},
+		{"unsupported type", NewMessage("/a", struct{}{}), nil, false},
+		{"int overflow", NewMessage("/a", 1<<40), nil, false},
+	} {
+		t.Run(fmt.Sprintf("MarshalBinary() %s", tc.desc), func(t *testing.T) {
+			data, err := tc.msg.MarshalBinary()
+			if err == nil && !tc.ok {
+				t.Fatalf("expected an error")
+			}
+			if err != nil && tc.ok {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			if !bytes.Equal(data, tc.data) {
+				t.Errorf("MarshalBinary() = %q, want %q", data, tc.data)
+			}
+		})
+	}
+}
+
+func TestMessageUnmarshalBinary(t *testing.T) {
+	for _, tc := range []struct {
+		desc string
+		data []byte
+		msg  *Message
+		ok   bool
+	}{
+		{"no type tags", []byte("/led\x00\x00\x00\x00"), NewMessage("/led"), true},
+		{"no args", []byte("/led\x00\x00\x00\x00,\x00\x00\x00"), NewMessage("/led"), true},
+		{"int and float", []byte("/a\x00\x00,if\x00\x00\x00\x03\xe8\x43\xdc\x00\x00"),
+			NewMessage("/a", int32(1000), float32(440)), true},
+		{"string", []byte("/a\x00\x00,s\x00\x00abcd\x00\x00\x00\x00"), NewMessage("/a", "abcd"), true},
+		{"int64 and double", []byte("/a\x00\x00,hd\x00\x00\x00\x00\x00\x00\x00\x00\x01\x3f\xf0\x00\x00\x00\x00\x00\x00"),
+			NewMessage("/a", int64(1), float64(1)), true},
+		{"empty", []byte{}, nil, false},
+		{"no leading slash", []byte("a\x00\x00\x00"), nil, false},
+		{"unterminated", []byte("/abc"), nil, false},
+		{"short int", []byte("/a\x00\x00,i\x00\x00\x00\x01"), nil, false},
+		{"bad type tags", []byte("/a\x00\x00i\x00\x00\x00"), nil, false},
+		{"unknown tag", []byte("/a\x00\x00,x\x00\x00"), nil, false},
+		{"trailing data", []byte("/a\x00\x00,\x00\x00\x00\x00\x00\x00\x01"), nil, false},
+		{"short blob", []byte("/a\x00\x00,b\x00\x00\x00\x00\x00\x08\x01"), nil, false},
+	} {
+		t.Run(fmt.Sprintf("UnmarshalBinary() %s", tc.desc), func(t *testing.T) {
+			m := &Message{}
+			err := m.UnmarshalBinary(tc.data)
+			if err == nil && !tc.ok {
+				t.Fatalf("expected an error")
+			}
+			if err != nil && tc.ok {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			if !tc.ok {
+				return
+			}
+			if !reflect.DeepEqual(m, tc.msg) {
+				t.Errorf("UnmarshalBinary() = %#v, want %#v", m, tc.msg)
+			}
+		})
+	}
+}
+
+func TestMessageRoundTrip(t *testing.T) {
+	m := NewMessage("/input/mic/1/gain", int32(-3), float32(1.5), "x", []byte{9}, int64(1<<40), 2.5, true, false, nil)
+	data, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	if len(data)%4 != 0 {
+		t.Errorf("len = %d, want a multiple of 4", len(data))
+	}
+	got := &Message{}
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	if !reflect.DeepEqual(got, m) {
+		t.Errorf("round trip = %#v, want %#v", got, m)
+	}
+}
@@ -0,0 +1,91 @@
+package osc
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+)
+
+// SLIP (RFC 1055) special characters, which frame the packets of stream
+// transports in OSC 1.1.
+const (
+	slipEnd    = 0xc0
+	slipEsc    = 0xdb
+	slipEscEnd = 0xdc
+	slipEscEsc = 0xdd
+)
+
+// MaxFrameSize is the size of the largest packet that a SLIPReader accepts.
+const MaxFrameSize = 65535
+
+// EncodeSLIP returns the SLIP frame of a packet. The frame is both started
+// and ended with an END character (double-ended SLIP), as OSC 1.1 recommends.
+func EncodeSLIP(data []byte) []byte {
+	buf := &bytes.Buffer{}
+	buf.WriteByte(slipEnd)
+	for _, c := range data {
+		switch c {
+		case slipEnd:
+			buf.Write([]byte{slipEsc, slipEscEnd})
+		case slipEsc:
+			buf.Write([]byte{slipEsc, slipEscEsc})
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	buf.WriteByte(slipEnd)
+	return buf.Bytes()
+}
+
+// SLIPReader reads the packets of a SLIP framed stream.
+type SLIPReader struct {
+	r *bufio.Reader
+}
+
+// NewSLIPReader returns a reader of the SLIP frames of the stream.
+func NewSLIPReader(r io.Reader) *SLIPReader {
+	return &SLIPReader{r: bufio.NewReader(r)}
+}
+
+// ReadPacket returns the data of the next frame. Empty frames, e.g. between
+// the END characters of double-ended frames, are skipped. It returns io.EOF
+// at the end of the stream, and io.ErrUnexpectedEOF within a frame.
+func (sr *SLIPReader) ReadPacket() ([]byte, error) {
+	data := []byte{}
+	for {
+		c, err := sr.r.ReadByte()
+		if err != nil {
+			if err == io.EOF && len(data) > 0 {
+				return nil, io.ErrUnexpectedEOF
+			}
+			return nil, err
+		}
+		switch c {
+		case slipEnd:
+			if len(data) > 0 {
+				return data, nil
+			}
+			continue
+		case slipEsc:
+			if c, err = sr.r.ReadByte(); err != nil {
+				if err == io.EOF {
+					return nil, io.ErrUnexpectedEOF
+				}
+				return nil, err
+			}
+			switch c {
+			case slipEscEnd:
+				c = slipEnd
+			case slipEscEsc:
+				c = slipEsc
+			default:
+				return nil, fmt.Errorf("invalid SLIP escape 0x%02x", c)
+			}
+		}
+		if len(data) >= MaxFrameSize {
+			return nil, fmt.Errorf("SLIP frame exceeds %d bytes", MaxFrameSize)
+		}
+		data = append(data, c)
+	}
+}
@@ -0,0 +1,92 @@
+package osc
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestEncodeSLIP(t *testing.T) {
+	for _, tc := range []struct {
+		desc  string
+		data  []byte
+		frame []byte
+	}{
+		{"plain", []byte("abc"), []byte("\xc0abc\xc0")},
+		{"end", []byte("a\xc0b"), []byte("\xc0a\xdb\xdcb\xc0")},
+		{"esc", []byte("a\xdbb"), []byte("\xc0a\xdb\xddb\xc0")},
+	} {
+		t.Run(fmt.Sprintf("EncodeSLIP() %s", tc.desc), func(t *testing.T) {
+			if got := EncodeSLIP(tc.data); !bytes.Equal(got, tc.frame) {
+				t.Errorf("EncodeSLIP() = %q, want %q", got, tc.frame)
+			}
+		})
+	}
+}
+
+func TestSLIPReader(t *testing.T) {
+	for _, tc := range []struct {
+		desc    string
+		stream  []byte
+		packets [][]byte
+		err     error // nil for a framing error.
+	}{
+		{"double-ended frames", []byte("\xc0abc\xc0\xc0de\xc0"),
+			[][]byte{[]byte("abc"), []byte("de")}, io.EOF},
+		{"single-ended frames", []byte("abc\xc0de\xc0"),
+			[][]byte{[]byte("abc"), []byte("de")}, io.EOF},
+		{"escapes", []byte("\xc0a\xdb\xdcb\xdb\xdd\xc0"),
+			[][]byte{[]byte("a\xc0b\xdb")}, io.EOF},
+		{"truncated frame", []byte("\xc0abc\xc0de"),
+			[][]byte{[]byte("abc")}, io.ErrUnexpectedEOF},
+		{"truncated escape", []byte("\xc0a\xdb"),
+			[][]byte{}, io.ErrUnexpectedEOF},
+		{"invalid escape", []byte("\xc0a\xdbb\xc0"),
+			[][]byte{}, nil},
+	} {
+		t.Run(fmt.Sprintf("ReadPacket() %s", tc.desc), func(t *testing.T) {
+			r := NewSLIPReader(bytes.NewReader(tc.stream))
+			got := [][]byte{}
+			var err error
+			for {
+				var p []byte
+				if p, err = r.ReadPacket(); err != nil {
+					break
+				}
+				got = append(got, p)
+			}
+			if !reflect.DeepEqual(got, tc.packets) {
+				t.Errorf("packets = %q, want %q", got, tc.packets)
+			}
+			switch {
+			case tc.err != nil && err != tc.err:
+				t.Errorf("error = %v, want %v", err, tc.err)
+			case tc.err == nil && (err == io.EOF || err == io.ErrUnexpectedEOF):
+				t.Errorf("error = %v, want a framing error", err)
+			}
+		})
+	}
+
+	t.Run("ReadPacket() round trip", func(t *testing.T) {
+		data, err := NewMessage("/input/mic/1/gain", int32(0xc0dbc0)).MarshalBinary()
+		if err != nil {
+			t.Fatalf("unexpected error; %s", err)
+		}
+		got, err := NewSLIPReader(bytes.NewReader(EncodeSLIP(data))).ReadPacket()
+		if err != nil {
+			t.Fatalf("unexpected error; %s", err)
+		}
+		if !bytes.Equal(got, data) {
+			t.Errorf("ReadPacket() = %q, want %q", got, data)
+		}
+	})
+
+	t.Run("ReadPacket() oversized frame", func(t *testing.T) {
+		r := NewSLIPReader(bytes.NewReader(make([]byte, MaxFrameSize+1)))
+		if _, err := r.ReadPacket(); err == nil || err == io.ErrUnexpectedEOF {
+			t.Errorf("ReadPacket() error = %v, want a size error", err)
+		}
+	})
+}
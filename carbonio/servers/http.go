@@ -1,79 +1,260 @@
 package servers
 
 import (
-	"bytes"
+	"context"
+	"crypto/tls"
 	"fmt"
-	"html/template"
 	"log"
+	"net"
 	"net/http"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/kward/avid-s3l/carbonio/address"
+	"github.com/kward/avid-s3l/carbonio/auth"
 	"github.com/kward/avid-s3l/carbonio/devices"
+	"github.com/kward/avid-s3l/carbonio/events"
 	"github.com/kward/avid-s3l/carbonio/handlers"
-	"github.com/kward/avid-s3l/carbonio/helpers"
+	"github.com/kward/avid-s3l/carbonio/health"
+	"github.com/kward/avid-s3l/carbonio/metrics"
 	"github.com/kward/avid-s3l/carbonio/static"
 )
 
-var (
-	htmlTmpls map[string]*template.Template = map[string]*template.Template{}
-	device    devices.Device
-)
+// HTTP serves the device over HTTP, or over HTTPS with the TLSConfig option.
+type HTTP struct {
+	opts   *options
+	device devices.Device
+	srv    *http.Server
+	lns    []net.Listener
+	// admin holds the addresses of the admin listeners.
+	admin   map[string]bool
+	watcher *events.Watcher
+	// ownWatcher is whether the watcher is run by the server.
+	ownWatcher bool
+	health     *health.Checker
+	// ctx is the base context of requests, and of the watcher. It is canceled
+	// on shutdown, so that event streams end.
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+var _ Server = new(HTTP)
 
-func HttpServer(port int, device devices.Device) {
+// NewHTTP returns an HTTP server of the device.
+func NewHTTP(device devices.Device, opts ...func(*options) error) (*HTTP, error) {
 	if device == nil {
-		log.Printf("device is unitialized")
-		return
+		return nil, fmt.Errorf("device is uninitialized")
+	}
+	o := &options{port: 8080, listen: []string{LinkLocal}}
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, fmt.Errorf("invalid option; %s", err)
+		}
+	}
+	if err := o.validate(); err != nil {
+		return nil, fmt.Errorf("failed to validate options; %s", err)
+	}
+
+	var authn *auth.Authenticator
+	if o.authFile != "" {
+		var err error
+		if authn, err = auth.Load(o.authFile); err != nil {
+			return nil, err
+		}
+	} else {
+		log.Printf("warning: authentication is disabled; anyone on the network may change the device")
+	}
+
+	// Watch for changes of the values, including those by external writers.
+	watcher, ownWatcher := o.watcher, false
+	if watcher == nil {
+		watcher = events.NewWatcher(events.NewBus(), address.NewDeviceResolver(device), events.DefaultInterval)
+		ownWatcher = true
 	}
 
 	h, err := handlers.NewHandlers(device,
-		handlers.Port(port))
+		handlers.Port(o.port),
+		handlers.SnapshotDir(o.snapshotDir),
+		handlers.Watcher(watcher),
+		handlers.Auth(authn))
 	if err != nil {
-		log.Printf("error instantiating handlers; %s", err)
-		return
+		return nil, fmt.Errorf("error instantiating handlers; %s", err)
 	}
 
-	ip := device.IP()
-	var host string
-	if ip.DefaultMask() != nil {
-		host = ip.String()
-	} else {
-		host = fmt.Sprintf("[%s]", ip)
+	checker := health.NewChecker()
+	checker.Add("device", health.DeviceCheck(device))
+	checker.Add("spi", health.SPICheck(devices.Implementations(device)))
+
+	var handler http.Handler = newRouter(h, metrics.New(address.NewDeviceResolver(device)), checker)
+	if o.accessLog != nil {
+		// Wrap the router, so that requests that match no route are logged too.
+		handler = o.accessLog.Handler(handler)
 	}
-	addr := fmt.Sprintf("%s:%d", host, port)
-	fmt.Printf("carbonio server starting on http://%s\n", addr)
 
-	r := mux.NewRouter()
-	r.HandleFunc("/", rootHandler)
-	// TODO(2020-02-24) Add logging for /static requests.
-	r.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(static.AssetFile())))
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &HTTP{
+		opts:       o,
+		device:     device,
+		admin:      map[string]bool{},
+		watcher:    watcher,
+		ownWatcher: ownWatcher,
+		health:     checker,
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+	s.srv = &http.Server{
+		Handler:     handler,
+		TLSConfig:   o.tlsConfig,
+		ReadTimeout: 10 * time.Second,
+		// No WriteTimeout, as the event streams (/ws and /api/v1/events) stay
+		// open. Idle keep-alive connections are closed instead.
+		IdleTimeout: 2 * time.Minute,
+		BaseContext: func(net.Listener) context.Context { return ctx },
+		ConnContext: s.connContext,
+	}
+	return s, nil
+}
 
-	r.HandleFunc("/list", h.ListHandler)
-	r.HandleFunc("/list_query", h.ListQueryHandler)
-	r.HandleFunc("/status", h.StatusHandler)
+// Name implements Server.
+func (s *HTTP) Name() string { return "http" }
 
-	srv := &http.Server{
-		Handler:      r,
-		Addr:         addr,
-		ReadTimeout:  10 * time.Second,
-		WriteTimeout: 10 * time.Second,
+// Listen implements Server. Every address of the listen specs is bound, and
+// the failures are returned as Errors of *ListenError.
+func (s *HTTP) Listen() error {
+	errs := Errors{}
+	bind := func(spec string, admin bool) {
+		addrs, err := ResolveListen(spec, s.opts.port, s.device.IP())
+		if err != nil {
+			errs = append(errs, &ListenError{Server: s.Name(), Addr: spec, Err: err})
+			return
+		}
+		for _, addr := range addrs {
+			if admin && !isLoopback(addr) {
+				errs = append(errs, &ListenError{Server: s.Name(), Addr: addr,
+					Err: fmt.Errorf("admin listeners must be loopback addresses")})
+				continue
+			}
+			ln, err := net.Listen("tcp", addr)
+			if err != nil {
+				errs = append(errs, &ListenError{Server: s.Name(), Addr: addr, Err: err})
+				continue
+			}
+			if admin {
+				s.admin[ln.Addr().String()] = true
+			}
+			s.lns = append(s.lns, ln)
+		}
+	}
+	for _, spec := range s.opts.listen {
+		bind(spec, false)
+	}
+	for _, spec := range s.opts.adminListen {
+		bind(spec, true)
+	}
+	if len(errs) > 0 {
+		for _, ln := range s.lns {
+			ln.Close()
+		}
+		s.lns = nil
+		return errs
 	}
 
-	fmt.Println("server started")
-	log.SetFlags(0)
-	log.Fatal(srv.ListenAndServe())
+	scheme := "http"
+	if s.opts.tlsConfig != nil {
+		scheme = "https"
+	}
+	for i, ln := range s.lns {
+		addr := ln.Addr().String()
+		if s.opts.tlsConfig != nil {
+			s.lns[i] = tls.NewListener(ln, s.opts.tlsConfig)
+		}
+		if s.admin[addr] {
+			fmt.Printf("carbonio server listening on %s://%s (admin)\n", scheme, addr)
+			continue
+		}
+		fmt.Printf("carbonio server listening on %s://%s\n", scheme, addr)
+	}
+	return nil
 }
 
-func rootHandler(w http.ResponseWriter, r *http.Request) {
-	buf := &bytes.Buffer{}
-	buf.WriteString(fmt.Sprintf("Hello, world!\n"))
-	buf.WriteString(fmt.Sprintf("r.URL.Path: %s\n", r.URL.Path))
+// Health returns the checker of /readyz, to which the checks of other servers
+// may be added.
+func (s *HTTP) Health() *health.Checker { return s.health }
 
-	status := http.StatusOK
-	l, err := w.Write(buf.Bytes())
-	if err != nil {
-		status = http.StatusInternalServerError
-		w.WriteHeader(status)
+// Watcher returns the watcher of the device values, to share with other
+// servers.
+func (s *HTTP) Watcher() *events.Watcher { return s.watcher }
+
+// Addrs returns the addresses of the listeners, once bound.
+func (s *HTTP) Addrs() []net.Addr {
+	addrs := []net.Addr{}
+	for _, ln := range s.lns {
+		addrs = append(addrs, ln.Addr())
+	}
+	return addrs
+}
+
+// connContext gives the requests of admin listeners the admin role.
+func (s *HTTP) connContext(ctx context.Context, c net.Conn) context.Context {
+	if s.admin[c.LocalAddr().String()] {
+		return auth.NewContext(ctx, &auth.Principal{Name: "admin listener", Role: auth.Admin})
+	}
+	return ctx
+}
+
+// Serve implements Server. It returns the first error of any listener.
+func (s *HTTP) Serve() error {
+	if s.ownWatcher {
+		go s.watcher.Run(s.ctx)
+	}
+	errs := make(chan error, len(s.lns))
+	for _, ln := range s.lns {
+		go func(ln net.Listener) { errs <- s.srv.Serve(ln) }(ln)
 	}
-	helpers.CommonLogFormat(r, status, l)
+	for range s.lns {
+		if err := <-errs; err != http.ErrServerClosed {
+			return err
+		}
+	}
+	return nil
+}
+
+// Shutdown implements Server. Event streams are ended, and the requests in
+// progress are waited for.
+func (s *HTTP) Shutdown(ctx context.Context) error {
+	s.cancel()
+	err := s.srv.Shutdown(ctx)
+	for _, ln := range s.lns {
+		ln.Close() // In case it was never served.
+	}
+	return err
+}
+
+// newRouter returns the router of the HTTP server. With metrics, the requests
+// are observed, and the metrics are served at /metrics. With a checker, the
+// health endpoints are served without authentication, so that probes need no
+// credentials.
+func newRouter(h *handlers.Handlers, m *metrics.Metrics, c *health.Checker) *mux.Router {
+	root := mux.NewRouter()
+	if c != nil {
+		root.HandleFunc("/healthz", c.Healthz).Methods(http.MethodGet, http.MethodHead)
+		root.HandleFunc("/readyz", c.Readyz).Methods(http.MethodGet, http.MethodHead)
+	}
+	r := root.NewRoute().Subrouter()
+	if m != nil {
+		// Before authentication, so that rejected requests are observed too.
+		r.Use(m.Middleware)
+		r.Handle("/metrics", m)
+	}
+	r.Use(h.Authenticate)
+	r.HandleFunc("/", h.ControlHandler)
+	r.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(static.AssetFile())))
+
+	r.HandleFunc("/list", h.ListHandler)
+	r.HandleFunc("/list_query", h.ListQueryHandler)
+	r.HandleFunc("/status", h.StatusHandler)
+	r.HandleFunc("/ws", h.LiveHandler)
+
+	h.RegisterAPI(r)
+	return root
 }
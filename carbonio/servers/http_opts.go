@@ -0,0 +1,108 @@
+package servers
+
+import (
+	"crypto/tls"
+	"fmt"
+
+	"github.com/kward/avid-s3l/carbonio/accesslog"
+	"github.com/kward/avid-s3l/carbonio/events"
+)
+
+type options struct {
+	port        int
+	listen      []string
+	adminListen []string
+	snapshotDir string
+	authFile    string
+	tlsConfig   *tls.Config
+	accessLog   *accesslog.Logger
+	watcher     *events.Watcher
+}
+
+func (o *options) validate() error {
+	if o.port < 0 || o.port > 65535 {
+		return fmt.Errorf("invalid port %d", o.port)
+	}
+	if len(o.listen) == 0 && len(o.adminListen) == 0 {
+		return fmt.Errorf("no listen addresses")
+	}
+	return nil
+}
+
+// Port returns the port to listen on.
+func Port(v int) func(*options) error {
+	return func(o *options) error { return o.setPort(v) }
+}
+func (o *options) setPort(v int) error {
+	o.port = v
+	return nil
+}
+
+// Listen returns the listen specs (see ResolveListen).
+func Listen(v []string) func(*options) error {
+	return func(o *options) error { return o.setListen(v) }
+}
+func (o *options) setListen(v []string) error {
+	o.listen = v
+	return nil
+}
+
+// AdminListen returns the listen specs of the admin listeners. They must be
+// loopback addresses, as their requests are given the admin role without
+// authentication.
+func AdminListen(v []string) func(*options) error {
+	return func(o *options) error { return o.setAdminListen(v) }
+}
+func (o *options) setAdminListen(v []string) error {
+	o.adminListen = v
+	return nil
+}
+
+// SnapshotDir returns the directory where snapshots are stored.
+func SnapshotDir(v string) func(*options) error {
+	return func(o *options) error { return o.setSnapshotDir(v) }
+}
+func (o *options) setSnapshotDir(v string) error {
+	o.snapshotDir = v
+	return nil
+}
+
+// AuthFile returns the credentials file. Without it, authentication is
+// disabled.
+func AuthFile(v string) func(*options) error {
+	return func(o *options) error { return o.setAuthFile(v) }
+}
+func (o *options) setAuthFile(v string) error {
+	o.authFile = v
+	return nil
+}
+
+// TLSConfig returns the TLS configuration. With it, HTTPS is served.
+func TLSConfig(v *tls.Config) func(*options) error {
+	return func(o *options) error { return o.setTLSConfig(v) }
+}
+func (o *options) setTLSConfig(v *tls.Config) error {
+	o.tlsConfig = v
+	return nil
+}
+
+// AccessLog returns the logger of the requests. Without it, requests are not
+// logged.
+func AccessLog(v *accesslog.Logger) func(*options) error {
+	return func(o *options) error { return o.setAccessLog(v) }
+}
+func (o *options) setAccessLog(v *accesslog.Logger) error {
+	o.accessLog = v
+	return nil
+}
+
+// Watcher returns the watcher of the device values, shared with another
+// server. A server given a watcher polls it after its changes, but does not
+// run it; without it, the server runs a watcher of its own.
+func Watcher(v *events.Watcher) func(*options) error {
+	return func(o *options) error { return o.setWatcher(v) }
+}
+func (o *options) setWatcher(v *events.Watcher) error {
+	o.watcher = v
+	return nil
+}
@@ -0,0 +1,251 @@
+package servers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/kward/avid-s3l/carbonio/address"
+	"github.com/kward/avid-s3l/carbonio/devices/devicestest"
+	"github.com/kward/avid-s3l/carbonio/handlers"
+	"github.com/kward/avid-s3l/carbonio/health"
+	"github.com/kward/avid-s3l/carbonio/metrics"
+)
+
+func newHandlers(t *testing.T) (*handlers.Handlers, func()) {
+	dir, err := ioutil.TempDir("", "servers")
+	if err != nil {
+		t.Fatalf("error creating temp dir; %s", err)
+	}
+	d, err := devicestest.NewStage16(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("error instantiating Stage16; %s", err)
+	}
+	h, err := handlers.NewHandlers(d, handlers.SnapshotDir(dir+"/snapshots"))
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("error instantiating handlers; %s", err)
+	}
+	return h, func() { os.RemoveAll(dir) }
+}
+
+// TestOpenAPI verifies that the OpenAPI document describes exactly the API
+// routes that are registered with the router.
+func TestOpenAPI(t *testing.T) {
+	h, cleanup := newHandlers(t)
+	defer cleanup()
+
+	// Operations of the router, as `METHOD path`.
+	registered := []string{}
+	err := newRouter(h, nil, nil).Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		tmpl, err := route.GetPathTemplate()
+		if err != nil || !strings.HasPrefix(tmpl, handlers.APIPrefix+"/") {
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			t.Errorf("API route %s has no methods", tmpl)
+			return nil
+		}
+		for _, m := range methods {
+			registered = append(registered, m+" "+handlers.OpenAPIPath(tmpl))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+
+	// Operations of the document, served by the router.
+	srv := httptest.NewServer(newRouter(h, nil, nil))
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + handlers.APIPrefix + "/openapi.json")
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	defer resp.Body.Close()
+	if got, want := resp.StatusCode, http.StatusOK; got != want {
+		t.Fatalf("status = %d, want %d", got, want)
+	}
+	doc := struct {
+		OpenAPI string                                       `json:"openapi"`
+		Paths   map[string]map[string]map[string]interface{} `json:"paths"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		t.Fatalf("error decoding document; %s", err)
+	}
+	if got, want := doc.OpenAPI, handlers.OpenAPIVersion; got != want {
+		t.Errorf("openapi = %s, want %s", got, want)
+	}
+	documented := []string{}
+	for path, ops := range doc.Paths {
+		for method, op := range ops {
+			documented = append(documented, strings.ToUpper(method)+" "+path)
+			if _, ok := op["responses"]; !ok {
+				t.Errorf("%s %s has no responses", method, path)
+			}
+		}
+	}
+
+	sort.Strings(registered)
+	sort.Strings(documented)
+	if got, want := strings.Join(documented, "\n"), strings.Join(registered, "\n"); got != want {
+		t.Errorf("documented operations =\n%s\nwant (registered)\n%s", got, want)
+	}
+}
+
+// TestControlPage verifies that the control page, and all the static assets
+// that it references, are served.
+func TestControlPage(t *testing.T) {
+	h, cleanup := newHandlers(t)
+	defer cleanup()
+	srv := httptest.NewServer(newRouter(h, nil, nil))
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		path        string
+		contentType string
+	}{
+		{"/", "text/html"},
+		{"/static/reset.css", "text/css"},
+		{"/static/control.css", "text/css"},
+		{"/static/live.js", "javascript"},
+		{"/static/control.js", "javascript"},
+	} {
+		t.Run(fmt.Sprintf("GET %s", tc.path), func(t *testing.T) {
+			resp, err := http.Get(srv.URL + tc.path)
+			if err != nil {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			resp.Body.Close()
+			if got, want := resp.StatusCode, http.StatusOK; got != want {
+				t.Fatalf("status = %d, want %d", got, want)
+			}
+			if got := resp.Header.Get("Content-Type"); !strings.Contains(got, tc.contentType) {
+				t.Errorf("Content-Type = %q, want %s", got, tc.contentType)
+			}
+		})
+	}
+}
+
+// TestMetrics verifies that the metrics are served, and observe the requests of
+// the router.
+func TestMetrics(t *testing.T) {
+	dir, err := ioutil.TempDir("", "servers")
+	if err != nil {
+		t.Fatalf("error creating temp dir; %s", err)
+	}
+	defer os.RemoveAll(dir)
+	d, err := devicestest.NewStage16(dir)
+	if err != nil {
+		t.Fatalf("error instantiating Stage16; %s", err)
+	}
+	h, err := handlers.NewHandlers(d, handlers.SnapshotDir(dir+"/snapshots"))
+	if err != nil {
+		t.Fatalf("error instantiating handlers; %s", err)
+	}
+	srv := httptest.NewServer(newRouter(h, metrics.New(address.NewDeviceResolver(d)), nil))
+	defer srv.Close()
+
+	for i := 0; i < 2; i++ {
+		resp, err := http.Get(srv.URL + "/metrics")
+		if err != nil {
+			t.Fatalf("unexpected error; %s", err)
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("error reading metrics; %s", err)
+		}
+		if got, want := resp.Header.Get("Content-Type"), metrics.ContentType; got != want {
+			t.Errorf("Content-Type = %q, want %q", got, want)
+		}
+		// The first scrape is observed by the second.
+		want := `carbonio_http_request_duration_seconds_count{method="GET",route="/metrics",code="200"} 1`
+		if got := strings.Contains(string(body), want); got != (i == 1) {
+			t.Errorf("scrape %d includes %q = %t, want %t", i, want, got, i == 1)
+		}
+	}
+}
+
+// TestHealth verifies that the health endpoints are served without
+// authentication, and that /readyz reports the failed checks.
+func TestHealth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "servers")
+	if err != nil {
+		t.Fatalf("error creating temp dir; %s", err)
+	}
+	defer os.RemoveAll(dir)
+	d, err := devicestest.NewStage16(dir)
+	if err != nil {
+		t.Fatalf("error instantiating Stage16; %s", err)
+	}
+	authFile := filepath.Join(dir, "auth.yaml")
+	if err := ioutil.WriteFile(authFile, []byte("tokens: [{name: a, token: b, role: admin}]\n"), 0600); err != nil {
+		t.Fatalf("error writing auth file; %s", err)
+	}
+	s, err := NewHTTP(d,
+		SnapshotDir(filepath.Join(dir, "snapshots")),
+		AuthFile(authFile))
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	srv := httptest.NewServer(s.srv.Handler)
+	defer srv.Close()
+
+	get := func(path string) (int, *health.Report) {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatalf("unexpected error; %s", err)
+		}
+		defer resp.Body.Close()
+		rep := &health.Report{}
+		if resp.StatusCode != http.StatusUnauthorized {
+			if err := json.NewDecoder(resp.Body).Decode(rep); err != nil {
+				t.Fatalf("error decoding %s; %s", path, err)
+			}
+		}
+		return resp.StatusCode, rep
+	}
+
+	for _, tc := range []struct {
+		path   string
+		status int
+		checks int
+	}{
+		{"/healthz", http.StatusOK, 0},
+		{"/readyz", http.StatusOK, 2},
+		{"/api/v1/values", http.StatusUnauthorized, 0},
+	} {
+		t.Run(fmt.Sprintf("GET %s", tc.path), func(t *testing.T) {
+			stts, rep := get(tc.path)
+			if stts != tc.status {
+				t.Fatalf("status = %d, want %d", stts, tc.status)
+			}
+			if got := len(rep.Checks); got != tc.checks {
+				t.Errorf("checks = %d, want %d", got, tc.checks)
+			}
+		})
+	}
+
+	s.Health().Add("listeners", func() error { return fmt.Errorf("down") })
+	stts, rep := get("/readyz")
+	if got, want := stts, http.StatusServiceUnavailable; got != want {
+		t.Errorf("status = %d, want %d", got, want)
+	}
+	if got, want := rep.Status, health.Error; got != want {
+		t.Errorf("report status = %q, want %q", got, want)
+	}
+	if n := len(rep.Checks); n != 3 || rep.Checks[2].Error != "down" {
+		t.Errorf("checks = %+v, want the listeners check to fail", rep.Checks)
+	}
+}
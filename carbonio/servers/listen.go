@@ -0,0 +1,116 @@
+package servers
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/kward/avid-s3l/carbonio/devices"
+)
+
+// Listen specs, in addition to host[:port] addresses and interface names.
+const (
+	// LinkLocal is the link-local address of the device.
+	LinkLocal = "link-local"
+	// AllInterfaces is every address of every interface.
+	AllInterfaces = "all"
+)
+
+// ResolveListen returns the addresses of a listen spec, which is one of:
+//
+//	link-local             the link-local address of the device (ip)
+//	all, *                 all interfaces
+//	eth0                   the addresses of an interface
+//	10.0.0.5, ::1          an IP
+//	fe80::1%eth0           an IPv6 address with a zone
+//	localhost              a hostname
+//
+// each optionally followed by `:port`, e.g. `eth0:8080` or `[::1]:8080`. The
+// port defaults to `port`. IPv6 link-local addresses of interfaces are given
+// the interface as their zone.
+func ResolveListen(spec string, port int, ip net.IP) ([]string, error) {
+	host, p := spec, strconv.Itoa(port)
+	if h, sp, err := net.SplitHostPort(spec); err == nil {
+		n, err := strconv.Atoi(sp)
+		if err != nil || n < 0 || n > 65535 {
+			return nil, fmt.Errorf("invalid port in listen address %q", spec)
+		}
+		host, p = h, sp
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+
+	switch host {
+	case "", "*", AllInterfaces:
+		return []string{net.JoinHostPort("", p)}, nil
+	case LinkLocal:
+		if ip == nil {
+			return nil, fmt.Errorf("the device has no link-local address")
+		}
+		return []string{net.JoinHostPort(zoned(ip, devices.Zone(ip)), p)}, nil
+	}
+	if parseIP(host) != nil {
+		return []string{net.JoinHostPort(host, p)}, nil
+	}
+	if iface, err := net.InterfaceByName(host); err == nil {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return nil, fmt.Errorf("error reading the addresses of %s; %s", host, err)
+		}
+		strs := []string{}
+		for _, addr := range addrs {
+			if n, ok := addr.(*net.IPNet); ok {
+				strs = append(strs, net.JoinHostPort(zoned(n.IP, iface.Name), p))
+			}
+		}
+		if len(strs) == 0 {
+			return nil, fmt.Errorf("interface %s has no addresses", host)
+		}
+		return strs, nil
+	}
+	return []string{net.JoinHostPort(host, p)}, nil
+}
+
+// ListenHosts returns the listen specs without their ports, so that they
+// resolve to the default port, e.g. `eth0:8080` becomes `eth0`.
+func ListenHosts(specs []string) []string {
+	hosts := []string{}
+	for _, spec := range specs {
+		if h, _, err := net.SplitHostPort(spec); err == nil {
+			spec = h
+		}
+		hosts = append(hosts, spec)
+	}
+	return hosts
+}
+
+// zoned returns the IP as a string, with the zone if it is an IPv6 link-local
+// address.
+func zoned(ip net.IP, zone string) string {
+	if zone == "" || ip.To4() != nil || !ip.IsLinkLocalUnicast() {
+		return ip.String()
+	}
+	return ip.String() + "%" + zone
+}
+
+// parseIP parses an IP, ignoring its zone.
+func parseIP(host string) net.IP {
+	if i := strings.LastIndex(host, "%"); i >= 0 {
+		host = host[:i]
+	}
+	return net.ParseIP(host)
+}
+
+// isLoopback returns true if the host of the address is a loopback IP, or
+// `localhost`.
+func isLoopback(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return false
+	}
+	if host == "localhost" {
+		return true
+	}
+	ip := parseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
@@ -0,0 +1,263 @@
+package servers
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kward/avid-s3l/carbonio/devices/devicestest"
+)
+
+func TestResolveListen(t *testing.T) {
+	ip := net.ParseIP("169.254.1.2")
+	for _, tc := range []struct {
+		desc  string
+		spec  string
+		addrs []string
+		ok    bool
+	}{
+		{"link-local", LinkLocal, []string{"169.254.1.2:8080"}, true},
+		{"link-local with port", LinkLocal + ":9000", []string{"169.254.1.2:9000"}, true},
+		{"all", AllInterfaces, []string{":8080"}, true},
+		{"star", "*", []string{":8080"}, true},
+		{"port only", ":9000", []string{":9000"}, true},
+		{"ipv4", "10.0.0.5", []string{"10.0.0.5:8080"}, true},
+		{"ipv4 with port", "10.0.0.5:9000", []string{"10.0.0.5:9000"}, true},
+		{"ipv6", "::1", []string{"[::1]:8080"}, true},
+		{"bracketed ipv6", "[::1]", []string{"[::1]:8080"}, true},
+		{"ipv6 with port", "[::1]:9000", []string{"[::1]:9000"}, true},
+		{"ipv6 with zone", "fe80::1%eth0", []string{"[fe80::1%eth0]:8080"}, true},
+		{"ipv6 with zone and port", "[fe80::1%eth0]:9000", []string{"[fe80::1%eth0]:9000"}, true},
+		{"hostname", "localhost:9000", []string{"localhost:9000"}, true},
+		{"invalid port", "10.0.0.5:http", nil, false},
+		{"port out of range", "10.0.0.5:65536", nil, false},
+	} {
+		t.Run(fmt.Sprintf("ResolveListen() %s", tc.desc), func(t *testing.T) {
+			addrs, err := ResolveListen(tc.spec, 8080, ip)
+			if err == nil && !tc.ok {
+				t.Fatalf("expected an error")
+			}
+			if err != nil && tc.ok {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			if fmt.Sprint(addrs) != fmt.Sprint(tc.addrs) {
+				t.Errorf("= %v, want %v", addrs, tc.addrs)
+			}
+		})
+	}
+}
+
+func TestListenHosts(t *testing.T) {
+	specs := []string{LinkLocal, LinkLocal + ":9000", ":9000", "eth0:9000", "10.0.0.5:9000", "::1", "[::1]:9000", "[fe80::1%eth0]:9000"}
+	want := []string{LinkLocal, LinkLocal, "", "eth0", "10.0.0.5", "::1", "::1", "fe80::1%eth0"}
+	if got := ListenHosts(specs); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("ListenHosts() = %q, want %q", got, want)
+	}
+	for i, host := range ListenHosts(specs) {
+		addrs, err := ResolveListen(host, 8000, net.ParseIP("169.254.1.2"))
+		if err != nil {
+			t.Errorf("ResolveListen(%q) unexpected error; %s", host, err)
+			continue
+		}
+		for _, addr := range addrs {
+			if _, p, _ := net.SplitHostPort(addr); p != "8000" {
+				t.Errorf("ResolveListen(%q) of %q = %s, want port 8000", host, specs[i], addr)
+			}
+		}
+	}
+}
+
+func TestResolveListenInterface(t *testing.T) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		t.Fatalf("error enumerating interfaces; %s", err)
+	}
+	name := ""
+	for _, i := range ifaces {
+		if i.Flags&net.FlagLoopback != 0 {
+			name = i.Name
+			break
+		}
+	}
+	if name == "" {
+		t.Skip("no loopback interface")
+	}
+	addrs, err := ResolveListen(name, 8080, nil)
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	found := false
+	for _, addr := range addrs {
+		found = found || addr == "127.0.0.1:8080"
+	}
+	if !found {
+		t.Errorf("ResolveListen(%q) = %v, want 127.0.0.1:8080 included", name, addrs)
+	}
+}
+
+func TestZoned(t *testing.T) {
+	for _, tc := range []struct {
+		ip   string
+		want string
+	}{
+		{"fe80::1", "fe80::1%eth0"},
+		{"fd00::1", "fd00::1"},
+		{"169.254.1.2", "169.254.1.2"},
+	} {
+		if got := zoned(net.ParseIP(tc.ip), "eth0"); got != tc.want {
+			t.Errorf("zoned(%s) = %s, want %s", tc.ip, got, tc.want)
+		}
+	}
+}
+
+// TestHTTPListeners verifies that the requests of an admin listener are not
+// authenticated, unlike those of the other listeners.
+func TestHTTPListeners(t *testing.T) {
+	dir, err := ioutil.TempDir("", "servers")
+	if err != nil {
+		t.Fatalf("error creating temp dir; %s", err)
+	}
+	defer os.RemoveAll(dir)
+	d, err := devicestest.NewStage16(dir)
+	if err != nil {
+		t.Fatalf("error instantiating Stage16; %s", err)
+	}
+	authFile := filepath.Join(dir, "auth.yaml")
+	if err := ioutil.WriteFile(authFile, []byte("tokens: [{name: a, token: b, role: admin}]\n"), 0600); err != nil {
+		t.Fatalf("error writing auth file; %s", err)
+	}
+
+	s, err := NewHTTP(d,
+		Listen([]string{"127.0.0.1:0"}),
+		AdminListen([]string{"127.0.0.1:0"}),
+		SnapshotDir(filepath.Join(dir, "snapshots")),
+		AuthFile(authFile))
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	if err := s.Listen(); err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	served := make(chan error)
+	go func() { served <- s.Serve() }()
+	defer func() {
+		if err := s.Shutdown(context.Background()); err != nil {
+			t.Errorf("error shutting down; %s", err)
+		}
+		if err := <-served; err != nil {
+			t.Errorf("error serving; %s", err)
+		}
+	}()
+
+	addrs := s.Addrs()
+	if len(addrs) != 2 {
+		t.Fatalf("Addrs() = %v, want 2 addresses", addrs)
+	}
+	for i, want := range []int{http.StatusUnauthorized, http.StatusOK} {
+		res, err := http.Get(fmt.Sprintf("http://%s/api/v1/values", addrs[i]))
+		if err != nil {
+			t.Fatalf("unexpected error; %s", err)
+		}
+		res.Body.Close()
+		if res.StatusCode != want {
+			t.Errorf("GET %s status = %d, want %d", addrs[i], res.StatusCode, want)
+		}
+	}
+}
+
+func TestHTTPAdminListenLoopback(t *testing.T) {
+	dir, err := ioutil.TempDir("", "servers")
+	if err != nil {
+		t.Fatalf("error creating temp dir; %s", err)
+	}
+	defer os.RemoveAll(dir)
+	d, err := devicestest.NewStage16(dir)
+	if err != nil {
+		t.Fatalf("error instantiating Stage16; %s", err)
+	}
+	s, err := NewHTTP(d,
+		Listen(nil),
+		AdminListen([]string{"0.0.0.0:0"}),
+		SnapshotDir(filepath.Join(dir, "snapshots")))
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	err = s.Listen()
+	errs, ok := err.(Errors)
+	if !ok || len(errs) != 1 {
+		t.Fatalf("Listen() = %v, want one error", err)
+	}
+	if _, ok := errs[0].(*ListenError); !ok {
+		t.Errorf("Listen() error is a %T, want *ListenError", errs[0])
+	}
+	s.Shutdown(context.Background())
+}
+
+// TestHTTPAndOSCListeners verifies that HTTP and OSC listen together on a spec
+// with a port, the port being that of HTTP.
+func TestHTTPAndOSCListeners(t *testing.T) {
+	dir, err := ioutil.TempDir("", "servers")
+	if err != nil {
+		t.Fatalf("error creating temp dir; %s", err)
+	}
+	defer os.RemoveAll(dir)
+	d, err := devicestest.NewStage16(dir)
+	if err != nil {
+		t.Fatalf("error instantiating Stage16; %s", err)
+	}
+
+	httpPort, oscPort := freePort(t), freePort(t)
+	specs := []string{fmt.Sprintf("127.0.0.1:%d", httpPort)}
+	h, err := NewHTTP(d, Listen(specs), SnapshotDir(filepath.Join(dir, "snapshots")))
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	o, err := NewOSC(d, Port(oscPort), Listen(specs), Watcher(h.Watcher()))
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	for _, s := range []Server{h, o} {
+		if err := s.Listen(); err != nil {
+			t.Fatalf("%s Listen() unexpected error; %s", s.Name(), err)
+		}
+		defer s.Shutdown(context.Background())
+	}
+
+	if got, want := fmt.Sprint(h.Addrs()), fmt.Sprintf("[127.0.0.1:%d]", httpPort); got != want {
+		t.Errorf("HTTP Addrs() = %s, want %s", got, want)
+	}
+	want := fmt.Sprintf("127.0.0.1:%d", oscPort)
+	if got := fmt.Sprint(o.Addrs()); got != "["+want+"]" {
+		t.Errorf("OSC Addrs() = %s, want [%s]", got, want)
+	}
+	for _, l := range o.listeners {
+		if got := l.Addr().String(); got != want {
+			t.Errorf("OSC TCP listener = %s, want %s", got, want)
+		}
+	}
+}
+
+// freePort returns a port that is free for both UDP and TCP on the loopback
+// address.
+func freePort(t *testing.T) int {
+	for i := 0; i < 10; i++ {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("unexpected error; %s", err)
+		}
+		port := l.Addr().(*net.TCPAddr).Port
+		conn, err := net.ListenPacket("udp", l.Addr().String())
+		l.Close()
+		if err == nil {
+			conn.Close()
+			return port
+		}
+	}
+	t.Fatalf("no free port")
+	return 0
+}
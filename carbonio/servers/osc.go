@@ -1,3 +1,565 @@
 package servers
 
-type OSC struct{}
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"sync"
+
+	"github.com/kward/avid-s3l/carbonio/address"
+	"github.com/kward/avid-s3l/carbonio/auth"
+	"github.com/kward/avid-s3l/carbonio/changes"
+	"github.com/kward/avid-s3l/carbonio/devices"
+	"github.com/kward/avid-s3l/carbonio/events"
+	"github.com/kward/avid-s3l/carbonio/osc"
+	"github.com/kward/golib/errors"
+	"google.golang.org/grpc/codes"
+)
+
+// DefaultOSCPort is the default UDP and TCP port of the OSC server.
+const DefaultOSCPort = 41789
+
+// OSC addresses of the server, besides those of the values.
+const (
+	// ErrorAddress is the address of error replies. Their arguments are the
+	// address of the failed message, the gRPC code, and a description.
+	ErrorAddress = "/error"
+	// SubscribeAddress subscribes the client to the changes of the values
+	// matched by an address pattern (default all), for a time-to-live in
+	// seconds (default DefaultOSCSubscriptionTTL, or the lifetime of the
+	// connection for TCP clients). Subscribing again renews the subscription.
+	// It is replied to with a SubscribedAddress message.
+	SubscribeAddress  = "/subscribe"
+	SubscribedAddress = "/subscribed"
+	// UnsubscribeAddress ends the subscription to an address pattern (default
+	// all). It is replied to with an UnsubscribedAddress message.
+	UnsubscribeAddress  = "/unsubscribe"
+	UnsubscribedAddress = "/unsubscribed"
+	// DumpAddress queries all the values matched by an address pattern
+	// (default all), e.g. for the initial sync of a control surface.
+	DumpAddress = "/dump"
+	// ScheduledAddress replies to a bundle with a future time tag, with the ID
+	// of the bundle, and its time.
+	ScheduledAddress = "/scheduled"
+	// AppliedAddress precedes the results of a scheduled bundle, with its ID.
+	AppliedAddress = "/applied"
+	// CancelAddress cancels the pending bundle of an ID, or all of them. Each
+	// canceled bundle is replied to with a CanceledAddress message of its ID.
+	CancelAddress   = "/cancel"
+	CanceledAddress = "/canceled"
+)
+
+// maxPacketSize is the maximum size of a UDP datagram.
+const maxPacketSize = 65535
+
+// OSC serves the device over Open Sound Control (OSC) on UDP, and on TCP with
+// the SLIP framing of OSC 1.1 on the same addresses. The OSC address
+// of a value is its address with a leading slash, e.g. `/input/mic/3/gain`. A
+// message without arguments queries the values of the address, and a message
+// with one argument sets them. Both are replied to with the values of the
+// matched addresses, and failures with an ErrorAddress message. Clients may
+// subscribe to the changes of values, which are pushed to them. The values
+// set by the messages of a bundle are applied as a single transaction, at the
+// time tag of the bundle. The subscriptions of TCP clients last until their
+// connection is closed.
+type OSC struct {
+	opts     *options
+	device   devices.Device
+	resolver address.Resolver
+	watcher  *events.Watcher
+	// ownWatcher is whether the watcher is run by the server.
+	ownWatcher bool
+	// role of the clients, which cannot authenticate.
+	role      auth.Role
+	conns     []net.PacketConn
+	listeners []net.Listener
+	subs      *oscSubscriptions
+	sched     *oscScheduler
+	ctx       context.Context
+	// cancel is called on shutdown.
+	cancel   context.CancelFunc
+	mu       sync.Mutex
+	serving  bool              // Whether Serve was called.
+	tcpConns map[net.Conn]bool // The open TCP connections.
+	// connWG waits for the TCP connections to be served.
+	connWG sync.WaitGroup
+	// served is closed once all the connections are served.
+	served chan struct{}
+}
+
+var _ Server = new(OSC)
+
+// NewOSC returns an OSC server of the device. As OSC has no credentials, its
+// clients have the anonymous role of the AuthFile option, if any.
+func NewOSC(device devices.Device, opts ...func(*options) error) (*OSC, error) {
+	if device == nil {
+		return nil, fmt.Errorf("device is uninitialized")
+	}
+	o := &options{port: DefaultOSCPort, listen: []string{LinkLocal}}
+	for _, opt := range opts {
+		if err := opt(o); err != nil {
+			return nil, fmt.Errorf("invalid option; %s", err)
+		}
+	}
+	if err := o.validate(); err != nil {
+		return nil, fmt.Errorf("failed to validate options; %s", err)
+	}
+
+	role := auth.Admin
+	if o.authFile != "" {
+		authn, err := auth.Load(o.authFile)
+		if err != nil {
+			return nil, err
+		}
+		role = authn.AnonymousRole()
+		if role == auth.None {
+			log.Printf("warning: OSC clients cannot authenticate, and anonymous access is disabled; OSC requests will be denied")
+		}
+	}
+
+	resolver := address.NewDeviceResolver(device)
+	watcher, ownWatcher := o.watcher, false
+	if watcher == nil {
+		watcher = events.NewWatcher(events.NewBus(), resolver, events.DefaultInterval)
+		ownWatcher = true
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &OSC{
+		opts:       o,
+		device:     device,
+		resolver:   resolver,
+		watcher:    watcher,
+		ownWatcher: ownWatcher,
+		role:       role,
+		subs:       newOSCSubscriptions(),
+		sched:      newOSCScheduler(),
+		ctx:        ctx,
+		cancel:     cancel,
+		tcpConns:   map[net.Conn]bool{},
+		served:     make(chan struct{}),
+	}, nil
+}
+
+// Name implements Server.
+func (s *OSC) Name() string { return "osc" }
+
+// Listen implements Server. Every address of the listen specs is bound on the
+// port of the server, for both UDP and TCP; the ports of the specs are those of
+// HTTP. The failures are returned as Errors of *ListenError.
+func (s *OSC) Listen() error {
+	errs := Errors{}
+	for _, spec := range ListenHosts(s.opts.listen) {
+		addrs, err := ResolveListen(spec, s.opts.port, s.device.IP())
+		if err != nil {
+			errs = append(errs, &ListenError{Server: s.Name(), Addr: spec, Err: err})
+			continue
+		}
+		for _, addr := range addrs {
+			conn, err := net.ListenPacket("udp", addr)
+			if err != nil {
+				errs = append(errs, &ListenError{Server: s.Name(), Addr: addr, Err: err})
+				continue
+			}
+			s.conns = append(s.conns, conn)
+			// The TCP port is that of UDP, even when it was chosen by the system.
+			l, err := net.Listen("tcp", conn.LocalAddr().String())
+			if err != nil {
+				errs = append(errs, &ListenError{Server: s.Name(), Addr: "tcp://" + addr, Err: err})
+				continue
+			}
+			s.listeners = append(s.listeners, l)
+		}
+	}
+	if len(errs) > 0 {
+		s.close()
+		s.conns, s.listeners = nil, nil
+		return errs
+	}
+	for i, conn := range s.conns {
+		fmt.Printf("carbonio OSC server listening on udp://%s and tcp://%s\n", conn.LocalAddr(), s.listeners[i].Addr())
+	}
+	return nil
+}
+
+// Addrs returns the addresses of the UDP listeners, once bound. The TCP
+// listeners are bound to the same addresses.
+func (s *OSC) Addrs() []net.Addr {
+	addrs := []net.Addr{}
+	for _, conn := range s.conns {
+		addrs = append(addrs, conn.LocalAddr())
+	}
+	return addrs
+}
+
+// Serve implements Server. The messages of each UDP listener, and of each TCP
+// connection, are handled in the order they are received.
+func (s *OSC) Serve() error {
+	s.mu.Lock()
+	if s.ctx.Err() != nil {
+		s.mu.Unlock()
+		return nil // Already shut down.
+	}
+	s.serving = true
+	s.mu.Unlock()
+	defer close(s.served)
+
+	if s.ownWatcher {
+		go s.watcher.Run(s.ctx)
+	}
+	go s.push()
+	errs := make(chan error, len(s.conns)+len(s.listeners))
+	for _, conn := range s.conns {
+		go func(conn net.PacketConn) { errs <- s.serve(conn) }(conn)
+	}
+	for _, l := range s.listeners {
+		go func(l net.Listener) { errs <- s.serveTCP(l) }(l)
+	}
+	var err error
+	for i := 0; i < len(s.conns)+len(s.listeners); i++ {
+		if e := <-errs; e != nil && err == nil {
+			err = e
+		}
+	}
+	s.connWG.Wait()
+	return err
+}
+
+// serve the packets of a listener, until it is closed.
+func (s *OSC) serve(conn net.PacketConn) error {
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, addr, err := conn.ReadFrom(buf)
+		if err != nil {
+			if s.ctx.Err() != nil {
+				return nil // Shut down.
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				continue
+			}
+			return err
+		}
+		p := &udpPeer{conn: conn, addr: addr}
+		if err := p.Send(s.handlePacket(p, buf[:n])); err != nil {
+			log.Printf("osc: error replying to %s; %s", p, err)
+		}
+	}
+}
+
+// Shutdown implements Server. The TCP connections are closed, the messages in
+// progress are waited for, and the pending bundles are canceled.
+func (s *OSC) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.cancel()
+	s.mu.Unlock()
+	if ids := s.sched.cancelAll(); len(ids) > 0 {
+		log.Printf("osc: canceled %d pending bundles", len(ids))
+	}
+	s.close()
+	s.mu.Lock()
+	serving := s.serving
+	s.mu.Unlock()
+	if !serving {
+		return nil
+	}
+	select {
+	case <-s.served:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// close the listeners, and the TCP connections.
+func (s *OSC) close() {
+	for _, conn := range s.conns {
+		conn.Close()
+	}
+	for _, l := range s.listeners {
+		l.Close()
+	}
+	s.mu.Lock()
+	for conn := range s.tcpConns {
+		conn.Close()
+	}
+	s.mu.Unlock()
+}
+
+// handlePacket returns the replies to a packet of the peer.
+func (s *OSC) handlePacket(p oscPeer, data []byte) []*osc.Message {
+	pkt, err := osc.ParsePacket(data)
+	if err != nil {
+		return []*osc.Message{errorMessage("", errors.Errorf(codes.InvalidArgument, "error decoding packet; %s", err))}
+	}
+	if b, ok := pkt.(*osc.Bundle); ok {
+		return s.bundle(p, b)
+	}
+	return s.handle(p, pkt.(*osc.Message))
+}
+
+// handle returns the replies to a message of the peer.
+func (s *OSC) handle(p oscPeer, m *osc.Message) []*osc.Message {
+	replies, err := s.dispatch(p, m)
+	if err != nil {
+		return []*osc.Message{errorMessage(m.Address, err)}
+	}
+	return replies
+}
+
+// dispatch handles the subscriptions of the peer, or queries or sets the
+// values of the message address.
+func (s *OSC) dispatch(p oscPeer, m *osc.Message) ([]*osc.Message, error) {
+	switch m.Address {
+	case SubscribeAddress:
+		return s.subscribe(p, m.Args)
+	case UnsubscribeAddress:
+		return s.unsubscribe(p, m.Args)
+	case DumpAddress:
+		return s.dump(m.Args)
+	case CancelAddress:
+		return s.cancelBundles(m.Args)
+	}
+
+	a, err := address.Parse(m.Address)
+	if err != nil {
+		return nil, err
+	}
+	ts, err := s.resolver.Resolve(a)
+	if err != nil {
+		return nil, err
+	}
+	switch len(m.Args) {
+	case 0:
+		return s.query(ts)
+	case 1:
+		ws, err := writes(ts, m.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		return s.apply(ws)
+	}
+	return nil, errors.Errorf(codes.InvalidArgument, "%d arguments; want none to query, or a value to set", len(m.Args))
+}
+
+// authorize returns an error unless the clients have the role.
+func (s *OSC) authorize(role auth.Role) error {
+	if s.role < role {
+		return errors.Errorf(codes.PermissionDenied, "OSC clients have the %s role; %s is required", s.role, role)
+	}
+	return nil
+}
+
+// query returns the values of the targets.
+func (s *OSC) query(ts []*address.Target) ([]*osc.Message, error) {
+	if err := s.authorize(auth.Viewer); err != nil {
+		return nil, err
+	}
+	replies := []*osc.Message{}
+	for _, t := range ts {
+		v, err := t.Value()
+		if err != nil {
+			return nil, errors.Errorf(codes.Unavailable, "%s", err)
+		}
+		replies = append(replies, valueMessage(t.Address, v))
+	}
+	return replies, nil
+}
+
+// oscWrite is the write of a value to a target.
+type oscWrite struct {
+	target *address.Target
+	value  interface{}
+}
+
+// writes returns the writes of the value of the argument to the editable
+// targets. The values are validated.
+func writes(ts []*address.Target, arg interface{}) ([]*oscWrite, error) {
+	ws := []*oscWrite{}
+	for _, t := range ts {
+		if !t.Property.Editable {
+			continue
+		}
+		v, err := argValue(t.Property, arg)
+		if err != nil {
+			return nil, err
+		}
+		n, err := t.Property.Normalize(v)
+		if err != nil {
+			return nil, errors.Errorf(errors.Code(err), "%s; %s", t.Address, errors.ErrorDesc(err))
+		}
+		ws = append(ws, &oscWrite{target: t, value: n})
+	}
+	if len(ws) == 0 {
+		return nil, errors.Errorf(codes.PermissionDenied, "no editable values match")
+	}
+	return ws, nil
+}
+
+// authorizeWrites returns an error unless the clients may make all the writes.
+func (s *OSC) authorizeWrites(ws []*oscWrite) error {
+	role := auth.Operator
+	for _, w := range ws {
+		if r := auth.ChangeRole(w.target); r > role {
+			role = r
+		}
+	}
+	return s.authorize(role)
+}
+
+// apply the writes as a single transaction, and return the new values. Of
+// several writes of a target, the last one is applied.
+func (s *OSC) apply(ws []*oscWrite) ([]*osc.Message, error) {
+	if err := s.authorizeWrites(ws); err != nil {
+		return nil, err
+	}
+	ts := []*address.Target{}
+	last := map[string]*oscWrite{}
+	for _, w := range ws {
+		a := w.target.Address.String()
+		if _, ok := last[a]; !ok {
+			ts = append(ts, w.target)
+		}
+		last[a] = w
+	}
+	cs := []*changes.Change{}
+	for _, t := range ts {
+		c, err := changes.New(t, last[t.Address.String()].value)
+		if err != nil {
+			return nil, err
+		}
+		if c.From != c.To {
+			cs = append(cs, c)
+		}
+	}
+	if _, err := changes.Apply(cs); err != nil {
+		return nil, err
+	}
+	if len(cs) > 0 {
+		// Notify the subscribers of the other servers immediately.
+		if err := s.watcher.Poll(); err != nil {
+			log.Printf("osc: error polling values; %s", err)
+		}
+	}
+	return s.query(ts)
+}
+
+// subscribe the peer to the changes of the values matched by the pattern of
+// the arguments.
+func (s *OSC) subscribe(p oscPeer, args []interface{}) ([]*osc.Message, error) {
+	if len(args) > 2 {
+		return nil, errors.Errorf(codes.InvalidArgument, "%d arguments; want a pattern, and a time-to-live", len(args))
+	}
+	if err := s.authorize(auth.Viewer); err != nil {
+		return nil, err
+	}
+	pattern, err := subscriptionPattern(args)
+	if err != nil {
+		return nil, err
+	}
+	ttl := DefaultOSCSubscriptionTTL
+	if _, ok := p.(*tcpPeer); ok {
+		ttl = 0 // Until the connection is closed.
+	}
+	if len(args) == 2 {
+		if ttl, err = subscriptionTTL(args[1:]); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := s.resolver.Resolve(pattern); err != nil {
+		return nil, err
+	}
+	if err := s.subs.add(p, pattern, ttl); err != nil {
+		return nil, err
+	}
+	return []*osc.Message{osc.NewMessage(SubscribedAddress, "/"+pattern.String(), float32(ttl.Seconds()))}, nil
+}
+
+// unsubscribe the peer from the pattern of the arguments.
+func (s *OSC) unsubscribe(p oscPeer, args []interface{}) ([]*osc.Message, error) {
+	if len(args) > 1 {
+		return nil, errors.Errorf(codes.InvalidArgument, "%d arguments; want a pattern", len(args))
+	}
+	pattern, err := subscriptionPattern(args)
+	if err != nil {
+		return nil, err
+	}
+	if !s.subs.remove(p, pattern) {
+		return nil, errors.Errorf(codes.NotFound, "no subscription to /%s", pattern)
+	}
+	return []*osc.Message{osc.NewMessage(UnsubscribedAddress, "/"+pattern.String())}, nil
+}
+
+// dump returns all the values matched by the pattern of the arguments.
+func (s *OSC) dump(args []interface{}) ([]*osc.Message, error) {
+	if len(args) > 1 {
+		return nil, errors.Errorf(codes.InvalidArgument, "%d arguments; want a pattern", len(args))
+	}
+	pattern, err := subscriptionPattern(args)
+	if err != nil {
+		return nil, err
+	}
+	ts, err := s.resolver.Resolve(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return s.query(ts)
+}
+
+// argValue converts an OSC argument into a value of the property. Numbers
+// are accepted for Bool properties, as many control surfaces only send them,
+// and floats are rounded to the nearest step of Int properties, as faders send
+// them.
+func argValue(p *address.Property, arg interface{}) (interface{}, error) {
+	var v interface{}
+	switch t := arg.(type) {
+	case int32:
+		v = int(t)
+	case int64:
+		v = int(t)
+	case float32:
+		v = float64(t)
+	case float64, bool, string:
+		v = t
+	default:
+		return nil, errors.Errorf(codes.InvalidArgument, "unsupported %s argument %v (%T)", p.Name, arg, arg)
+	}
+	switch p.Kind {
+	case address.Bool:
+		switch t := v.(type) {
+		case int:
+			v = t != 0
+		case float64:
+			v = t != 0
+		}
+	case address.Int:
+		if t, ok := v.(float64); ok {
+			if math.IsNaN(t) || math.IsInf(t, 0) {
+				return nil, errors.Errorf(codes.InvalidArgument, "invalid %s value %v; want a number", p.Name, t)
+			}
+			v = math.Round(t)
+		}
+	}
+	return v, nil
+}
+
+// valueMessage returns the message of the value of an address. Bool values
+// are sent as 0 or 1, as not all clients support the OSC 1.1 T and F types.
+func valueMessage(a address.Address, v interface{}) *osc.Message {
+	addr := "/" + a.String()
+	switch tv := v.(type) {
+	case int:
+		return osc.NewMessage(addr, int32(tv))
+	case bool:
+		if tv {
+			return osc.NewMessage(addr, int32(1))
+		}
+		return osc.NewMessage(addr, int32(0))
+	}
+	return osc.NewMessage(addr, fmt.Sprintf("%v", v))
+}
+
+// errorMessage returns the ErrorAddress message of a failed message.
+func errorMessage(addr string, err error) *osc.Message {
+	return osc.NewMessage(ErrorAddress, addr, errors.Code(err).String(), errors.ErrorDesc(err))
+}
@@ -0,0 +1,181 @@
+package servers
+
+import (
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kward/avid-s3l/carbonio/address"
+	"github.com/kward/avid-s3l/carbonio/auth"
+	"github.com/kward/avid-s3l/carbonio/osc"
+	"github.com/kward/golib/errors"
+	"google.golang.org/grpc/codes"
+)
+
+// MaxOSCPendingBundles is the number of bundles that may be pending, beyond
+// which bundles are refused.
+const MaxOSCPendingBundles = 64
+
+// bundleAddress is the address of the error replies of bundles.
+const bundleAddress = "#bundle"
+
+// oscPending is a bundle that is scheduled.
+type oscPending struct {
+	id    int32
+	timer *time.Timer
+}
+
+// oscScheduler runs functions at the time tags of bundles, against the system
+// clock.
+type oscScheduler struct {
+	mu      sync.Mutex
+	lastID  int32
+	pending map[int32]*oscPending
+}
+
+func newOSCScheduler() *oscScheduler {
+	return &oscScheduler{pending: map[int32]*oscPending{}}
+}
+
+// schedule the function at the time, and return its ID, which the function is
+// called with.
+func (sc *oscScheduler) schedule(at time.Time, fn func(id int32)) (int32, error) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if len(sc.pending) >= MaxOSCPendingBundles {
+		return 0, errors.Errorf(codes.ResourceExhausted, "too many pending bundles; at most %d are allowed", MaxOSCPendingBundles)
+	}
+	sc.lastID++
+	p := &oscPending{id: sc.lastID}
+	p.timer = time.AfterFunc(time.Until(at), func() {
+		sc.mu.Lock()
+		_, ok := sc.pending[p.id]
+		delete(sc.pending, p.id)
+		sc.mu.Unlock()
+		if ok { // Not canceled in the meantime.
+			fn(p.id)
+		}
+	})
+	sc.pending[p.id] = p
+	return p.id, nil
+}
+
+// cancel the pending function of the ID. It returns false if there is none.
+func (sc *oscScheduler) cancel(id int32) bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	p, ok := sc.pending[id]
+	if ok {
+		p.timer.Stop()
+		delete(sc.pending, id)
+	}
+	return ok
+}
+
+// cancelAll cancels all the pending functions, and returns their IDs.
+func (sc *oscScheduler) cancelAll() []int32 {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	ids := []int32{}
+	for id, p := range sc.pending {
+		p.timer.Stop()
+		delete(sc.pending, id)
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// bundle applies the writes of the messages of the bundle as a single
+// transaction at its time tag. Bundles of the past, or to be applied
+// Immediately, are applied on receipt, and replied to with the new values.
+// Others are replied to with a ScheduledAddress message, and their results
+// are sent to the peer after an AppliedAddress message when they are applied.
+// The bundle is validated and authorized on receipt.
+func (s *OSC) bundle(p oscPeer, b *osc.Bundle) []*osc.Message {
+	ws, err := s.bundleWrites(b)
+	if err != nil {
+		return []*osc.Message{errorMessage(bundleAddress, err)}
+	}
+	if err := s.authorizeWrites(ws); err != nil {
+		return []*osc.Message{errorMessage(bundleAddress, err)}
+	}
+
+	apply := func() []*osc.Message {
+		replies, err := s.apply(ws)
+		if err != nil {
+			return []*osc.Message{errorMessage(bundleAddress, err)}
+		}
+		return replies
+	}
+	at := b.Time.Time()
+	if b.Time.IsImmediate() || !at.After(time.Now()) {
+		return apply()
+	}
+	id, err := s.sched.schedule(at, func(id int32) {
+		replies := append([]*osc.Message{osc.NewMessage(AppliedAddress, id)}, apply()...)
+		if err := p.Send(replies); err != nil {
+			log.Printf("osc: error sending the results of bundle %d to %s; %s", id, p, err)
+		}
+	})
+	if err != nil {
+		return []*osc.Message{errorMessage(bundleAddress, err)}
+	}
+	return []*osc.Message{osc.NewMessage(ScheduledAddress, id, at.UTC().Format(time.RFC3339Nano))}
+}
+
+// bundleWrites returns the writes of the messages of the bundle, which must
+// all set values.
+func (s *OSC) bundleWrites(b *osc.Bundle) ([]*oscWrite, error) {
+	ms := b.Messages()
+	if len(ms) == 0 {
+		return nil, errors.Errorf(codes.InvalidArgument, "empty bundle")
+	}
+	ws := []*oscWrite{}
+	for _, m := range ms {
+		if len(m.Args) != 1 {
+			return nil, errors.Errorf(codes.InvalidArgument, "%s has %d arguments; bundles may only set values", m.Address, len(m.Args))
+		}
+		a, err := address.Parse(m.Address)
+		if err != nil {
+			return nil, err
+		}
+		ts, err := s.resolver.Resolve(a)
+		if err != nil {
+			return nil, err
+		}
+		mws, err := writes(ts, m.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		ws = append(ws, mws...)
+	}
+	return ws, nil
+}
+
+// cancelBundles cancels the pending bundle of the ID of the arguments, or all
+// of them.
+func (s *OSC) cancelBundles(args []interface{}) ([]*osc.Message, error) {
+	if err := s.authorize(auth.Operator); err != nil {
+		return nil, err
+	}
+	switch len(args) {
+	case 0:
+		replies := []*osc.Message{}
+		for _, id := range s.sched.cancelAll() {
+			replies = append(replies, osc.NewMessage(CanceledAddress, id))
+		}
+		return replies, nil
+	case 1:
+		id, ok := args[0].(int32)
+		if !ok {
+			return nil, errors.Errorf(codes.InvalidArgument, "invalid bundle ID %v (%T); want an int32", args[0], args[0])
+		}
+		if !s.sched.cancel(id) {
+			return nil, errors.Errorf(codes.NotFound, "no pending bundle %d", id)
+		}
+		return []*osc.Message{osc.NewMessage(CanceledAddress, id)}, nil
+	}
+	return nil, errors.Errorf(codes.InvalidArgument, "%d arguments; want a bundle ID", len(args))
+}
@@ -0,0 +1,138 @@
+package servers
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kward/avid-s3l/carbonio/osc"
+)
+
+// gainBundle returns a bundle that sets the gain, pad, and phantom of mic input
+// #1.
+func gainBundle(at osc.Timetag, gain int32) *osc.Bundle {
+	return osc.NewBundle(at,
+		osc.NewMessage("/input/mic/1/gain", gain),
+		osc.NewMessage("/input/mic/1/pad", int32(1)),
+		osc.NewMessage("/input/mic/1/phantom", int32(1)))
+}
+
+func TestOSCBundle(t *testing.T) {
+	_, conn, cleanup := startOSC(t)
+	defer cleanup()
+
+	for _, tc := range []struct {
+		desc    string
+		bundle  *osc.Bundle
+		replies []*osc.Message
+	}{
+		{"immediately", gainBundle(osc.Immediately, 20), []*osc.Message{
+			osc.NewMessage("/input/mic/1/gain", int32(20)),
+			osc.NewMessage("/input/mic/1/pad", int32(1)),
+			osc.NewMessage("/input/mic/1/phantom", int32(1)),
+		}},
+		{"in the past", gainBundle(osc.NewTimetag(time.Now().Add(-time.Minute)), 25), []*osc.Message{
+			osc.NewMessage("/input/mic/1/gain", int32(25)),
+			osc.NewMessage("/input/mic/1/pad", int32(1)),
+			osc.NewMessage("/input/mic/1/phantom", int32(1)),
+		}},
+		{"nested", osc.NewBundle(osc.Immediately,
+			osc.NewMessage("/input/mic/2/gain", int32(30)),
+			osc.NewBundle(osc.Immediately, osc.NewMessage("/input/mic/2/gain", int32(31)))),
+			[]*osc.Message{osc.NewMessage("/input/mic/2/gain", int32(31))}},
+		{"out of range", gainBundle(osc.Immediately, 99), []*osc.Message{
+			osc.NewMessage(ErrorAddress, bundleAddress, "OutOfRange", "input/mic/1/gain; gain value 99 out of range [10:60]"),
+		}},
+		{"query", osc.NewBundle(osc.Immediately, osc.NewMessage("/input/mic/1/gain")), []*osc.Message{
+			osc.NewMessage(ErrorAddress, bundleAddress, "InvalidArgument", "/input/mic/1/gain has 0 arguments; bundles may only set values"),
+		}},
+		{"empty", osc.NewBundle(osc.Immediately), []*osc.Message{
+			osc.NewMessage(ErrorAddress, bundleAddress, "InvalidArgument", "empty bundle"),
+		}},
+	} {
+		t.Run(fmt.Sprintf("OSC bundle %s", tc.desc), func(t *testing.T) {
+			got := exchange(t, conn, mustMarshal(t, tc.bundle), len(tc.replies))
+			if !reflect.DeepEqual(got, tc.replies) {
+				t.Errorf("replies = %v, want %v", got, tc.replies)
+			}
+		})
+	}
+
+	// The failed bundle changed nothing.
+	got := exchange(t, conn, mustMarshal(t, osc.NewMessage("/input/mic/1/gain")), 1)
+	if want := osc.NewMessage("/input/mic/1/gain", int32(25)); !reflect.DeepEqual(got[0], want) {
+		t.Errorf("gain = %v, want %v", got[0], want)
+	}
+}
+
+func TestOSCBundleScheduled(t *testing.T) {
+	_, conn, cleanup := startOSC(t)
+	defer cleanup()
+
+	at := time.Now().Add(100 * time.Millisecond)
+	got := exchange(t, conn, mustMarshal(t, gainBundle(osc.NewTimetag(at), 40)), 1)
+	if got[0].Address != ScheduledAddress || got[0].Args[0] != int32(1) {
+		t.Fatalf("reply = %v, want %s 1", got[0], ScheduledAddress)
+	}
+	got = exchange(t, conn, nil, 4)
+	if time.Now().Before(at) {
+		t.Errorf("bundle applied before its time tag")
+	}
+	want := []*osc.Message{
+		osc.NewMessage(AppliedAddress, int32(1)),
+		osc.NewMessage("/input/mic/1/gain", int32(40)),
+		osc.NewMessage("/input/mic/1/pad", int32(1)),
+		osc.NewMessage("/input/mic/1/phantom", int32(1)),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("replies = %v, want %v", got, want)
+	}
+}
+
+func TestOSCBundleCancel(t *testing.T) {
+	_, conn, cleanup := startOSC(t)
+	defer cleanup()
+
+	later := osc.NewTimetag(time.Now().Add(time.Hour))
+	for i := 1; i <= 3; i++ {
+		got := exchange(t, conn, mustMarshal(t, gainBundle(later, 50)), 1)
+		if got[0].Address != ScheduledAddress || got[0].Args[0] != int32(i) {
+			t.Fatalf("reply = %v, want %s %d", got[0], ScheduledAddress, i)
+		}
+	}
+
+	for _, tc := range []struct {
+		desc    string
+		msg     *osc.Message
+		replies []*osc.Message
+	}{
+		{"one", osc.NewMessage(CancelAddress, int32(2)),
+			[]*osc.Message{osc.NewMessage(CanceledAddress, int32(2))}},
+		{"canceled", osc.NewMessage(CancelAddress, int32(2)),
+			[]*osc.Message{osc.NewMessage(ErrorAddress, CancelAddress, "NotFound", "no pending bundle 2")}},
+		{"all", osc.NewMessage(CancelAddress),
+			[]*osc.Message{osc.NewMessage(CanceledAddress, int32(1)), osc.NewMessage(CanceledAddress, int32(3))}},
+	} {
+		t.Run(fmt.Sprintf("OSC cancel %s", tc.desc), func(t *testing.T) {
+			got := exchange(t, conn, mustMarshal(t, tc.msg), len(tc.replies))
+			if !reflect.DeepEqual(got, tc.replies) {
+				t.Errorf("replies = %v, want %v", got, tc.replies)
+			}
+		})
+	}
+}
+
+func TestOSCSchedulerLimit(t *testing.T) {
+	sc := newOSCScheduler()
+	defer sc.cancelAll()
+	later := time.Now().Add(time.Hour)
+	for i := 0; i < MaxOSCPendingBundles; i++ {
+		if _, err := sc.schedule(later, func(int32) {}); err != nil {
+			t.Fatalf("unexpected error; %s", err)
+		}
+	}
+	if _, err := sc.schedule(later, func(int32) {}); err == nil {
+		t.Errorf("expected an error")
+	}
+}
@@ -0,0 +1,238 @@
+package servers
+
+import (
+	"log"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kward/avid-s3l/carbonio/address"
+	"github.com/kward/avid-s3l/carbonio/events"
+	"github.com/kward/avid-s3l/carbonio/osc"
+	"github.com/kward/golib/errors"
+	"google.golang.org/grpc/codes"
+)
+
+// Subscription settings of the OSC server.
+const (
+	// DefaultOSCSubscriptionTTL is the time-to-live of subscriptions that do
+	// not give one.
+	DefaultOSCSubscriptionTTL = time.Minute
+	// MaxOSCSubscriptionTTL is the longest time-to-live of a subscription.
+	MaxOSCSubscriptionTTL = time.Hour
+	// MaxOSCSubscriptions is the number of subscriptions of all the clients,
+	// beyond which subscribing fails.
+	MaxOSCSubscriptions = 256
+)
+
+// oscPeer is a client of the OSC server, to which messages can be sent at any
+// time.
+type oscPeer interface {
+	// String identifies the peer, e.g. by its address.
+	String() string
+	// Send the messages to the peer.
+	Send(ms []*osc.Message) error
+}
+
+// udpPeer is the client at a remote address of a UDP listener.
+type udpPeer struct {
+	conn net.PacketConn
+	addr net.Addr
+}
+
+// String implements oscPeer.
+func (p *udpPeer) String() string { return "udp://" + p.addr.String() }
+
+// Send implements oscPeer. Each message is sent as a datagram.
+func (p *udpPeer) Send(ms []*osc.Message) error {
+	for _, m := range ms {
+		data, err := m.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		if _, err := p.conn.WriteTo(data, p.addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// oscSubscription of a peer to the changes of the values matched by a pattern.
+type oscSubscription struct {
+	peer    oscPeer
+	pattern address.Address
+	expires time.Time // Zero for subscriptions that do not expire.
+}
+
+// oscSubscriptions holds the subscriptions of the OSC clients.
+type oscSubscriptions struct {
+	mu   sync.Mutex
+	now  func() time.Time
+	subs map[string]*oscSubscription // By peer and pattern.
+}
+
+func newOSCSubscriptions() *oscSubscriptions {
+	return &oscSubscriptions{now: time.Now, subs: map[string]*oscSubscription{}}
+}
+
+func subscriptionKey(p oscPeer, pattern address.Address) string {
+	return p.String() + " " + pattern.String()
+}
+
+// add or renew the subscription of the peer to the pattern. A zero ttl never
+// expires, e.g. for the peers of connections, whose subscriptions are removed
+// when the connection is closed.
+func (ss *oscSubscriptions) add(p oscPeer, pattern address.Address, ttl time.Duration) error {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.expire()
+	key := subscriptionKey(p, pattern)
+	if _, ok := ss.subs[key]; !ok && len(ss.subs) >= MaxOSCSubscriptions {
+		return errors.Errorf(codes.ResourceExhausted, "too many subscriptions; at most %d are allowed", MaxOSCSubscriptions)
+	}
+	sub := &oscSubscription{peer: p, pattern: pattern}
+	if ttl > 0 {
+		sub.expires = ss.now().Add(ttl)
+	}
+	ss.subs[key] = sub
+	return nil
+}
+
+// remove the subscription of the peer to the pattern. It returns false if
+// there was none.
+func (ss *oscSubscriptions) remove(p oscPeer, pattern address.Address) bool {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	key := subscriptionKey(p, pattern)
+	_, ok := ss.subs[key]
+	delete(ss.subs, key)
+	return ok
+}
+
+// removePeer removes all the subscriptions of the peer, and returns their
+// number.
+func (ss *oscSubscriptions) removePeer(p oscPeer) int {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	n := 0
+	for key, sub := range ss.subs {
+		if sub.peer.String() == p.String() {
+			delete(ss.subs, key)
+			n++
+		}
+	}
+	return n
+}
+
+// matching returns the peers with a subscription that matches the address,
+// each once.
+func (ss *oscSubscriptions) matching(a address.Address) []oscPeer {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	ss.expire()
+	seen := map[string]bool{}
+	peers := []oscPeer{}
+	for _, sub := range ss.subs {
+		key := sub.peer.String()
+		if seen[key] || !sub.pattern.Match(a) {
+			continue
+		}
+		seen[key] = true
+		peers = append(peers, sub.peer)
+	}
+	sort.Slice(peers, func(i, j int) bool { return peers[i].String() < peers[j].String() })
+	return peers
+}
+
+// expire removes the expired subscriptions. The mutex must be held.
+func (ss *oscSubscriptions) expire() {
+	now := ss.now()
+	for key, sub := range ss.subs {
+		if !sub.expires.IsZero() && !now.Before(sub.expires) {
+			delete(ss.subs, key)
+		}
+	}
+}
+
+// subscriptionTTL returns the time-to-live of a subscription from an optional
+// argument, in seconds.
+func subscriptionTTL(args []interface{}) (time.Duration, error) {
+	if len(args) == 0 {
+		return DefaultOSCSubscriptionTTL, nil
+	}
+	var secs float64
+	switch t := args[0].(type) {
+	case int32:
+		secs = float64(t)
+	case int64:
+		secs = float64(t)
+	case float32:
+		secs = float64(t)
+	case float64:
+		secs = t
+	default:
+		return 0, errors.Errorf(codes.InvalidArgument, "invalid time-to-live %v (%T); want seconds", args[0], args[0])
+	}
+	ttl := time.Duration(secs * float64(time.Second))
+	if ttl <= 0 || ttl > MaxOSCSubscriptionTTL {
+		return 0, errors.Errorf(codes.OutOfRange, "time-to-live %vs out of range (0:%.0f]", secs, MaxOSCSubscriptionTTL.Seconds())
+	}
+	return ttl, nil
+}
+
+// subscriptionPattern returns the address pattern of an optional argument,
+// which defaults to all the values.
+func subscriptionPattern(args []interface{}) (address.Address, error) {
+	if len(args) == 0 {
+		return address.Parse(address.Wildcard)
+	}
+	s, ok := args[0].(string)
+	if !ok {
+		return address.Address{}, errors.Errorf(codes.InvalidArgument, "invalid pattern %v (%T); want an address", args[0], args[0])
+	}
+	return address.Parse(s)
+}
+
+// push the value changes of the watcher to the subscribed peers, until the
+// server is shut down.
+func (s *OSC) push() {
+	bus := s.watcher.Bus()
+	for {
+		sub := bus.Subscribe(256)
+		if !s.pushEvents(sub.C) {
+			bus.Unsubscribe(sub)
+			return
+		}
+		// The subscription fell behind, and was closed; subscribe again.
+		log.Printf("osc: subscription to changes fell behind; some changes were not pushed")
+	}
+}
+
+// pushEvents pushes the events until the channel is closed, and returns true,
+// or until the server is shut down.
+func (s *OSC) pushEvents(c <-chan *events.Event) bool {
+	for {
+		select {
+		case <-s.ctx.Done():
+			return false
+		case e, ok := <-c:
+			if !ok {
+				return true
+			}
+			if e.Address == "" {
+				continue // Not a change of a value.
+			}
+			a, err := address.Parse(e.Address)
+			if err != nil {
+				continue
+			}
+			m := valueMessage(a, e.Value)
+			for _, p := range s.subs.matching(a) {
+				if err := p.Send([]*osc.Message{m}); err != nil {
+					log.Printf("osc: error pushing %s to %s; %s", m, p, err)
+				}
+			}
+		}
+	}
+}
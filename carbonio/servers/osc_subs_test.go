@@ -0,0 +1,204 @@
+package servers
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kward/avid-s3l/carbonio/address"
+	"github.com/kward/avid-s3l/carbonio/osc"
+)
+
+// fakePeer records the messages sent to it.
+type fakePeer struct {
+	name string
+	sent []*osc.Message
+}
+
+func (p *fakePeer) String() string { return p.name }
+
+func (p *fakePeer) Send(ms []*osc.Message) error {
+	p.sent = append(p.sent, ms...)
+	return nil
+}
+
+func TestOSCSubscriptions(t *testing.T) {
+	now := time.Date(2020, 2, 24, 13, 0, 0, 0, time.UTC)
+	ss := newOSCSubscriptions()
+	ss.now = func() time.Time { return now }
+	a, b := &fakePeer{name: "a"}, &fakePeer{name: "b"}
+
+	ss.add(a, address.MustParse("input/mic/1-8"), time.Minute)
+	ss.add(a, address.MustParse("input/mic/*/gain"), time.Minute) // Overlaps.
+	ss.add(b, address.MustParse("input/mic/2/gain"), 2*time.Minute)
+
+	for _, tc := range []struct {
+		desc  string
+		after time.Duration
+		addr  string
+		peers []oscPeer
+	}{
+		{"both", 0, "input/mic/2/gain", []oscPeer{a, b}},
+		{"a once", 0, "input/mic/1/gain", []oscPeer{a}},
+		{"none", 0, "led/status/state", []oscPeer{}},
+		{"a expired", time.Minute, "input/mic/2/gain", []oscPeer{b}},
+		{"all expired", 2 * time.Minute, "input/mic/2/gain", []oscPeer{}},
+	} {
+		t.Run(fmt.Sprintf("matching() %s", tc.desc), func(t *testing.T) {
+			now = time.Date(2020, 2, 24, 13, 0, 0, 0, time.UTC).Add(tc.after)
+			if got := ss.matching(address.MustParse(tc.addr)); !reflect.DeepEqual(got, tc.peers) {
+				t.Errorf("matching(%s) = %v, want %v", tc.addr, got, tc.peers)
+			}
+		})
+	}
+}
+
+func TestOSCSubscriptionsRenew(t *testing.T) {
+	now := time.Date(2020, 2, 24, 13, 0, 0, 0, time.UTC)
+	ss := newOSCSubscriptions()
+	ss.now = func() time.Time { return now }
+	p := &fakePeer{name: "p"}
+	pattern := address.MustParse("led")
+
+	ss.add(p, pattern, time.Minute)
+	now = now.Add(50 * time.Second)
+	ss.add(p, pattern, time.Minute)
+	now = now.Add(50 * time.Second)
+	if got := len(ss.matching(address.MustParse("led/status/state"))); got != 1 {
+		t.Errorf("renewed subscription matches %d peers, want 1", got)
+	}
+	if !ss.remove(p, pattern) {
+		t.Errorf("remove() = false, want true")
+	}
+	if ss.remove(p, pattern) {
+		t.Errorf("remove() of a removed subscription = true, want false")
+	}
+}
+
+func TestOSCSubscriptionsLimit(t *testing.T) {
+	ss := newOSCSubscriptions()
+	for i := 0; i < MaxOSCSubscriptions; i++ {
+		if err := ss.add(&fakePeer{name: fmt.Sprint(i)}, address.MustParse("led"), time.Minute); err != nil {
+			t.Fatalf("unexpected error; %s", err)
+		}
+	}
+	if err := ss.add(&fakePeer{name: "one more"}, address.MustParse("led"), time.Minute); err == nil {
+		t.Errorf("expected an error")
+	}
+	// Renewing is allowed.
+	if err := ss.add(&fakePeer{name: "0"}, address.MustParse("led"), time.Minute); err != nil {
+		t.Errorf("unexpected error renewing; %s", err)
+	}
+}
+
+func TestSubscriptionTTL(t *testing.T) {
+	for _, tc := range []struct {
+		desc string
+		args []interface{}
+		ttl  time.Duration
+		ok   bool
+	}{
+		{"default", nil, DefaultOSCSubscriptionTTL, true},
+		{"int", []interface{}{int32(30)}, 30 * time.Second, true},
+		{"float", []interface{}{float32(1.5)}, 1500 * time.Millisecond, true},
+		{"zero", []interface{}{int32(0)}, 0, false},
+		{"too long", []interface{}{int32(7200)}, 0, false},
+		{"string", []interface{}{"30"}, 0, false},
+	} {
+		t.Run(fmt.Sprintf("subscriptionTTL() %s", tc.desc), func(t *testing.T) {
+			ttl, err := subscriptionTTL(tc.args)
+			if err == nil && !tc.ok {
+				t.Fatalf("expected an error")
+			}
+			if err != nil && tc.ok {
+				t.Fatalf("unexpected error; %s", err)
+			}
+			if ttl != tc.ttl {
+				t.Errorf("ttl = %s, want %s", ttl, tc.ttl)
+			}
+		})
+	}
+}
+
+func TestOSCSubscribe(t *testing.T) {
+	s, conn, cleanup := startOSC(t)
+	defer cleanup()
+
+	got := exchange(t, conn, mustMarshal(t, osc.NewMessage(SubscribeAddress, "/input/mic/1", int32(30))), 1)
+	if want := osc.NewMessage(SubscribedAddress, "/input/mic/1", float32(30)); !reflect.DeepEqual(got[0], want) {
+		t.Fatalf("reply = %v, want %v", got[0], want)
+	}
+
+	// A change by another client is pushed.
+	ts, err := address.ResolveString(s.resolver, "input/mic/1/gain")
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	if err := s.watcher.Poll(); err != nil { // Record the values.
+		t.Fatalf("unexpected error; %s", err)
+	}
+	if err := ts[0].SetValue(42); err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	if err := s.watcher.Poll(); err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	got = exchange(t, conn, nil, 1)
+	if want := osc.NewMessage("/input/mic/1/gain", int32(42)); !reflect.DeepEqual(got[0], want) {
+		t.Errorf("push = %v, want %v", got[0], want)
+	}
+
+	got = exchange(t, conn, mustMarshal(t, osc.NewMessage(UnsubscribeAddress, "/input/mic/1")), 1)
+	if want := osc.NewMessage(UnsubscribedAddress, "/input/mic/1"); !reflect.DeepEqual(got[0], want) {
+		t.Errorf("reply = %v, want %v", got[0], want)
+	}
+	got = exchange(t, conn, mustMarshal(t, osc.NewMessage(UnsubscribeAddress, "/input/mic/1")), 1)
+	if got[0].Address != ErrorAddress {
+		t.Errorf("reply = %v, want an error", got[0])
+	}
+}
+
+func TestOSCDump(t *testing.T) {
+	_, conn, cleanup := startOSC(t)
+	defer cleanup()
+
+	for _, tc := range []struct {
+		desc    string
+		msg     *osc.Message
+		replies int
+	}{
+		{"all", osc.NewMessage(DumpAddress), 16*3 + 3},
+		{"pattern", osc.NewMessage(DumpAddress, "/input/mic/1-2"), 2 * 3},
+	} {
+		t.Run(fmt.Sprintf("OSC dump %s", tc.desc), func(t *testing.T) {
+			got := exchange(t, conn, mustMarshal(t, tc.msg), tc.replies)
+			for _, m := range got {
+				if m.Address == ErrorAddress || len(m.Args) != 1 {
+					t.Errorf("reply = %v, want a value", m)
+				}
+			}
+		})
+	}
+}
+
+func TestOSCSubscriptionsRemovePeer(t *testing.T) {
+	now := time.Date(2020, 2, 24, 13, 0, 0, 0, time.UTC)
+	ss := newOSCSubscriptions()
+	ss.now = func() time.Time { return now }
+	a, b := &fakePeer{name: "a"}, &fakePeer{name: "b"}
+	ss.add(a, address.MustParse("input/mic/1"), 0) // Never expires.
+	ss.add(a, address.MustParse("input/mic/2"), 0)
+	ss.add(b, address.MustParse("input/mic"), time.Minute)
+
+	now = now.Add(time.Hour)
+	if got, want := ss.matching(address.MustParse("input/mic/1/gain")), []oscPeer{a}; !reflect.DeepEqual(got, want) {
+		t.Errorf("matching() = %v, want %v", got, want)
+	}
+	if got, want := ss.removePeer(a), 2; got != want {
+		t.Errorf("removePeer() = %d, want %d", got, want)
+	}
+	if got := ss.matching(address.MustParse("input/mic/1/gain")); len(got) != 0 {
+		t.Errorf("matching() = %v, want none", got)
+	}
+}
@@ -0,0 +1,165 @@
+package servers
+
+import (
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/kward/avid-s3l/carbonio/osc"
+	"github.com/kward/golib/errors"
+	"google.golang.org/grpc/codes"
+)
+
+// TCP settings of the OSC server.
+const (
+	// MaxOSCConnections is the number of TCP connections of all the clients,
+	// beyond which connections are refused.
+	MaxOSCConnections = 64
+	// oscSendQueue is the number of messages that may be queued for a TCP
+	// client, beyond which the client is disconnected.
+	oscSendQueue = 1024
+	// oscWriteTimeout is the time allowed to write a message to a TCP client,
+	// after which the client is disconnected.
+	oscWriteTimeout = 5 * time.Second
+)
+
+// tcpPeer is the client of a TCP connection. Its packets are SLIP framed, as
+// per OSC 1.1. Messages are queued, and written by a goroutine of the peer, so
+// that a stalled client does not hold up the others.
+type tcpPeer struct {
+	conn  net.Conn
+	queue chan []byte // SLIP frames.
+	done  chan struct{}
+	once  sync.Once
+}
+
+func newTCPPeer(conn net.Conn) *tcpPeer {
+	return &tcpPeer{
+		conn:  conn,
+		queue: make(chan []byte, oscSendQueue),
+		done:  make(chan struct{}),
+	}
+}
+
+// String implements oscPeer.
+func (p *tcpPeer) String() string { return "tcp://" + p.conn.RemoteAddr().String() }
+
+// Send implements oscPeer. Each message is queued as a frame, without
+// blocking. The connection is closed if the queue is full, as the client does
+// not keep up.
+func (p *tcpPeer) Send(ms []*osc.Message) error {
+	for _, m := range ms {
+		data, err := m.MarshalBinary()
+		if err != nil {
+			return err
+		}
+		select {
+		case <-p.done:
+			return errors.Errorf(codes.Unavailable, "%s is disconnected", p)
+		default:
+		}
+		select {
+		case p.queue <- osc.EncodeSLIP(data):
+		default:
+			p.close()
+			return errors.Errorf(codes.ResourceExhausted, "%d messages are queued for %s; disconnected", oscSendQueue, p)
+		}
+	}
+	return nil
+}
+
+// write the queued frames to the connection, until the peer is closed. The
+// peer is closed if writing fails.
+func (p *tcpPeer) write() {
+	for {
+		select {
+		case <-p.done:
+			return
+		case f := <-p.queue:
+			p.conn.SetWriteDeadline(time.Now().Add(oscWriteTimeout))
+			if _, err := p.conn.Write(f); err != nil {
+				p.close()
+				return
+			}
+		}
+	}
+}
+
+// close the connection of the peer, once.
+func (p *tcpPeer) close() {
+	p.once.Do(func() {
+		close(p.done)
+		p.conn.Close()
+	})
+}
+
+// serveTCP accepts the connections of a listener, until it is closed.
+func (s *OSC) serveTCP(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if s.ctx.Err() != nil {
+				return nil // Shut down.
+			}
+			if ne, ok := err.(net.Error); ok && ne.Temporary() {
+				time.Sleep(10 * time.Millisecond)
+				continue
+			}
+			return err
+		}
+		if !s.track(conn) {
+			log.Printf("osc: refusing tcp://%s; too many connections", conn.RemoteAddr())
+			conn.Close()
+			continue
+		}
+		go s.serveConn(conn)
+	}
+}
+
+// track records an open connection, so that it is closed on shutdown. It
+// returns false if the connection must be refused.
+func (s *OSC) track(conn net.Conn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ctx.Err() != nil || len(s.tcpConns) >= MaxOSCConnections {
+		return false
+	}
+	s.tcpConns[conn] = true
+	s.connWG.Add(1)
+	return true
+}
+
+// serveConn handles the packets of a connection in the order they are
+// received, until the connection is closed. The subscriptions of the client
+// are removed with the connection.
+func (s *OSC) serveConn(conn net.Conn) {
+	p := newTCPPeer(conn)
+	go p.write()
+	defer func() {
+		p.close()
+		if n := s.subs.removePeer(p); n > 0 {
+			log.Printf("osc: removed %d subscriptions of %s", n, p)
+		}
+		s.mu.Lock()
+		delete(s.tcpConns, conn)
+		s.mu.Unlock()
+		s.connWG.Done()
+	}()
+
+	r := osc.NewSLIPReader(conn)
+	for {
+		data, err := r.ReadPacket()
+		if err != nil {
+			if err != io.EOF && s.ctx.Err() == nil {
+				log.Printf("osc: closing %s; %s", p, err)
+			}
+			return
+		}
+		if err := p.Send(s.handlePacket(p, data)); err != nil {
+			log.Printf("osc: error replying to %s; %s", p, err)
+			return
+		}
+	}
+}
@@ -0,0 +1,172 @@
+package servers
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kward/avid-s3l/carbonio/address"
+	"github.com/kward/avid-s3l/carbonio/osc"
+)
+
+// dialTCP connects to the TCP listener of the OSC server.
+func dialTCP(t *testing.T, s *OSC) (net.Conn, *osc.SLIPReader) {
+	conn, err := net.Dial("tcp", s.Addrs()[0].String())
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	return conn, osc.NewSLIPReader(conn)
+}
+
+// exchangeTCP sends a packet, if any, in a SLIP frame, and returns the `n`
+// replies.
+func exchangeTCP(t *testing.T, conn net.Conn, r *osc.SLIPReader, data []byte, n int) []*osc.Message {
+	if data != nil {
+		if _, err := conn.Write(osc.EncodeSLIP(data)); err != nil {
+			t.Fatalf("error sending; %s", err)
+		}
+	}
+	replies := []*osc.Message{}
+	for len(replies) < n {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		p, err := r.ReadPacket()
+		if err != nil {
+			t.Fatalf("error receiving reply %d of %d; %s", len(replies)+1, n, err)
+		}
+		m := &osc.Message{}
+		if err := m.UnmarshalBinary(p); err != nil {
+			t.Fatalf("error decoding reply; %s", err)
+		}
+		replies = append(replies, m)
+	}
+	return replies
+}
+
+func TestOSCTCP(t *testing.T) {
+	s, udp, cleanup := startOSC(t)
+	defer cleanup()
+	conn, r := dialTCP(t, s)
+	defer conn.Close()
+
+	got := exchangeTCP(t, conn, r, mustMarshal(t, osc.NewMessage("/input/mic/1-2/gain", int32(30))), 2)
+	want := []*osc.Message{
+		osc.NewMessage("/input/mic/1/gain", int32(30)),
+		osc.NewMessage("/input/mic/2/gain", int32(30)),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("replies = %v, want %v", got, want)
+	}
+
+	// The feedback of all the values is framed message by message.
+	if got := exchangeTCP(t, conn, r, mustMarshal(t, osc.NewMessage(DumpAddress)), 16*3+3); len(got) != 16*3+3 {
+		t.Errorf("dump = %d values, want %d", len(got), 16*3+3)
+	}
+
+	got = exchangeTCP(t, conn, r, mustMarshal(t, osc.NewBundle(osc.Immediately,
+		osc.NewMessage("/input/mic/1/pad", int32(1)),
+		osc.NewMessage("/input/mic/1/phantom", int32(1)))), 2)
+	want = []*osc.Message{
+		osc.NewMessage("/input/mic/1/pad", int32(1)),
+		osc.NewMessage("/input/mic/1/phantom", int32(1)),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("replies = %v, want %v", got, want)
+	}
+
+	// UDP is served alongside.
+	got = exchange(t, udp, mustMarshal(t, osc.NewMessage("/input/mic/1/gain")), 1)
+	if want := osc.NewMessage("/input/mic/1/gain", int32(30)); !reflect.DeepEqual(got[0], want) {
+		t.Errorf("reply = %v, want %v", got[0], want)
+	}
+}
+
+func TestOSCTCPSubscribe(t *testing.T) {
+	s, _, cleanup := startOSC(t)
+	defer cleanup()
+	conn, r := dialTCP(t, s)
+
+	got := exchangeTCP(t, conn, r, mustMarshal(t, osc.NewMessage(SubscribeAddress, "/input/mic/1")), 1)
+	if want := osc.NewMessage(SubscribedAddress, "/input/mic/1", float32(0)); !reflect.DeepEqual(got[0], want) {
+		t.Fatalf("reply = %v, want %v", got[0], want)
+	}
+
+	ts, err := address.ResolveString(s.resolver, "input/mic/1/gain")
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	if err := s.watcher.Poll(); err != nil { // Record the values.
+		t.Fatalf("unexpected error; %s", err)
+	}
+	if err := ts[0].SetValue(42); err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	if err := s.watcher.Poll(); err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	got = exchangeTCP(t, conn, r, nil, 1)
+	if want := osc.NewMessage("/input/mic/1/gain", int32(42)); !reflect.DeepEqual(got[0], want) {
+		t.Errorf("push = %v, want %v", got[0], want)
+	}
+
+	// The subscription ends with the connection.
+	conn.Close()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(s.subs.matching(address.MustParse("input/mic/1/gain"))) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("subscription was not removed on disconnect")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestOSCTCPShutdown(t *testing.T) {
+	s, _, cleanup := startOSC(t)
+	conn, r := dialTCP(t, s)
+	defer conn.Close()
+	exchangeTCP(t, conn, r, mustMarshal(t, osc.NewMessage("/input/mic/1/gain")), 1)
+
+	// Shutting down closes the open connections.
+	cleanup()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if _, err := r.ReadPacket(); err == nil {
+		t.Errorf("connection is open after shutdown")
+	}
+}
+
+func TestTCPPeerStalled(t *testing.T) {
+	server, client := net.Pipe() // Writes block until the client reads.
+	defer client.Close()
+	p := newTCPPeer(server)
+	go p.write()
+
+	// Sending does not block on the stalled client, which is disconnected once
+	// its queue is full.
+	m := []*osc.Message{osc.NewMessage("/input/mic/1/gain", int32(30))}
+	done := make(chan error)
+	go func() {
+		for i := 0; i <= oscSendQueue+1; i++ {
+			if err := p.Send(m); err != nil {
+				done <- err
+				return
+			}
+		}
+		done <- nil
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Send() blocked")
+	}
+	select {
+	case <-p.done:
+	default:
+		t.Error("peer is not closed")
+	}
+	if err := p.Send(m); err == nil {
+		t.Error("expected an error sending to a closed peer")
+	}
+}
@@ -0,0 +1,174 @@
+package servers
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kward/avid-s3l/carbonio/devices/devicestest"
+	"github.com/kward/avid-s3l/carbonio/osc"
+)
+
+// startOSC starts an OSC server of a test device on a loopback address, and
+// returns a client connection to it.
+func startOSC(t *testing.T, opts ...func(*options) error) (*OSC, net.Conn, func()) {
+	dir, err := ioutil.TempDir("", "servers")
+	if err != nil {
+		t.Fatalf("error creating temp dir; %s", err)
+	}
+	d, err := devicestest.NewStage16(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("error instantiating Stage16; %s", err)
+	}
+	opts = append([]func(*options) error{Port(0), Listen([]string{"127.0.0.1"})}, opts...)
+	s, err := NewOSC(d, opts...)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("unexpected error; %s", err)
+	}
+	if err := s.Listen(); err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("unexpected error; %s", err)
+	}
+	served := make(chan error)
+	go func() { served <- s.Serve() }()
+	conn, err := net.Dial("udp", s.Addrs()[0].String())
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	return s, conn, func() {
+		conn.Close()
+		if err := s.Shutdown(context.Background()); err != nil {
+			t.Errorf("error shutting down; %s", err)
+		}
+		if err := <-served; err != nil {
+			t.Errorf("error serving; %s", err)
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+// exchange sends a packet, if any, and returns the `n` replies.
+func exchange(t *testing.T, conn net.Conn, data []byte, n int) []*osc.Message {
+	if data != nil {
+		if _, err := conn.Write(data); err != nil {
+			t.Fatalf("error sending; %s", err)
+		}
+	}
+	replies := []*osc.Message{}
+	buf := make([]byte, maxPacketSize)
+	for len(replies) < n {
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		l, err := conn.Read(buf)
+		if err != nil {
+			t.Fatalf("error receiving reply %d of %d; %s", len(replies)+1, n, err)
+		}
+		m := &osc.Message{}
+		if err := m.UnmarshalBinary(buf[:l]); err != nil {
+			t.Fatalf("error decoding reply; %s", err)
+		}
+		replies = append(replies, m)
+	}
+	return replies
+}
+
+func mustMarshal(t *testing.T, p osc.Packet) []byte {
+	data, err := p.MarshalBinary()
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+	return data
+}
+
+func TestOSC(t *testing.T) {
+	_, conn, cleanup := startOSC(t)
+	defer cleanup()
+
+	for _, tc := range []struct {
+		desc    string
+		msg     *osc.Message
+		replies []*osc.Message
+	}{
+		{"query gain", osc.NewMessage("/input/mic/3/gain"),
+			[]*osc.Message{osc.NewMessage("/input/mic/3/gain", int32(10))}},
+		{"set gain", osc.NewMessage("/input/mic/3/gain", int32(30)),
+			[]*osc.Message{osc.NewMessage("/input/mic/3/gain", int32(30))}},
+		{"set gain as float", osc.NewMessage("/input/mic/3/gain", float32(35)),
+			[]*osc.Message{osc.NewMessage("/input/mic/3/gain", int32(35))}},
+		{"set gain as fractional float", osc.NewMessage("/input/mic/3/gain", float32(40.6)),
+			[]*osc.Message{osc.NewMessage("/input/mic/3/gain", int32(41))}},
+		{"set gain as max float", osc.NewMessage("/input/mic/3/gain", float32(60.4)),
+			[]*osc.Message{osc.NewMessage("/input/mic/3/gain", int32(60))}},
+		{"float out of range", osc.NewMessage("/input/mic/3/gain", float32(60.5)),
+			[]*osc.Message{osc.NewMessage(ErrorAddress, "/input/mic/3/gain", "OutOfRange", "input/mic/3/gain; gain value 61 out of range [10:60]")}},
+		{"float not a number", osc.NewMessage("/input/mic/3/gain", float32(math.NaN())),
+			[]*osc.Message{osc.NewMessage(ErrorAddress, "/input/mic/3/gain", "InvalidArgument", "invalid gain value NaN; want a number")}},
+		{"set pads as floats", osc.NewMessage("/input/mic/1-2/pad", float32(1)),
+			[]*osc.Message{
+				osc.NewMessage("/input/mic/1/pad", int32(1)),
+				osc.NewMessage("/input/mic/2/pad", int32(1)),
+			}},
+		{"set phantom as bool", osc.NewMessage("/input/mic/1/phantom", true),
+			[]*osc.Message{osc.NewMessage("/input/mic/1/phantom", int32(1))}},
+		{"set led", osc.NewMessage("/led/status", "on"),
+			[]*osc.Message{osc.NewMessage("/led/status/state", "On")}},
+		{"out of range", osc.NewMessage("/input/mic/3/gain", int32(99)),
+			[]*osc.Message{osc.NewMessage(ErrorAddress, "/input/mic/3/gain", "OutOfRange", "input/mic/3/gain; gain value 99 out of range [10:60]")}},
+		{"unknown address", osc.NewMessage("/input/mic/99/gain"),
+			[]*osc.Message{osc.NewMessage(ErrorAddress, "/input/mic/99/gain", "NotFound", "no targets match input/mic/99/gain")}},
+		{"too many arguments", osc.NewMessage("/input/mic/3/gain", int32(1), int32(2)),
+			[]*osc.Message{osc.NewMessage(ErrorAddress, "/input/mic/3/gain", "InvalidArgument", "2 arguments; want none to query, or a value to set")}},
+	} {
+		t.Run(fmt.Sprintf("OSC %s", tc.desc), func(t *testing.T) {
+			got := exchange(t, conn, mustMarshal(t, tc.msg), len(tc.replies))
+			if !reflect.DeepEqual(got, tc.replies) {
+				t.Errorf("replies = %v, want %v", got, tc.replies)
+			}
+		})
+	}
+
+	t.Run("OSC invalid packet", func(t *testing.T) {
+		got := exchange(t, conn, []byte("garbage"), 1)
+		if got[0].Address != ErrorAddress || got[0].Args[1] != "InvalidArgument" {
+			t.Errorf("reply = %v, want an InvalidArgument error", got[0])
+		}
+	})
+}
+
+func TestOSCAnonymousRole(t *testing.T) {
+	dir, err := ioutil.TempDir("", "servers")
+	if err != nil {
+		t.Fatalf("error creating temp dir; %s", err)
+	}
+	defer os.RemoveAll(dir)
+	authFile := filepath.Join(dir, "auth.yaml")
+	if err := ioutil.WriteFile(authFile, []byte("anonymous: operator\n"), 0600); err != nil {
+		t.Fatalf("error writing auth file; %s", err)
+	}
+	_, conn, cleanup := startOSC(t, AuthFile(authFile))
+	defer cleanup()
+
+	for _, tc := range []struct {
+		desc string
+		msg  *osc.Message
+		addr string
+	}{
+		{"gain", osc.NewMessage("/input/mic/1/gain", int32(20)), "/input/mic/1/gain"},
+		{"phantom", osc.NewMessage("/input/mic/1/phantom", int32(1)), ErrorAddress},
+	} {
+		t.Run(fmt.Sprintf("OSC operator sets %s", tc.desc), func(t *testing.T) {
+			got := exchange(t, conn, mustMarshal(t, tc.msg), 1)
+			if got[0].Address != tc.addr {
+				t.Errorf("reply = %v, want %s", got[0], tc.addr)
+			}
+		})
+	}
+}
@@ -1,2 +1,165 @@
 // Package servers provides different protocols for accessing the server.
 package servers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kward/avid-s3l/carbonio/changes"
+)
+
+// Server of a protocol, as run by a Supervisor.
+type Server interface {
+	// Name of the protocol, e.g. `http`.
+	Name() string
+	// Listen binds the listeners of the server. The failures of listeners are
+	// returned as a *ListenError, or Errors of them, and the other listeners
+	// are closed.
+	Listen() error
+	// Serve requests until the server is shut down. A server that is shut down
+	// returns nil.
+	Serve() error
+	// Shutdown stops the server from accepting requests, and waits for the
+	// requests in progress until the context is done.
+	Shutdown(ctx context.Context) error
+}
+
+// ListenError describes a listener that could not be bound.
+type ListenError struct {
+	Server string
+	Addr   string
+	Err    error
+}
+
+// Error implements the error interface.
+func (e *ListenError) Error() string {
+	return fmt.Sprintf("%s: error listening on %s; %s", e.Server, e.Addr, e.Err)
+}
+
+// Errors holds the errors of several servers.
+type Errors []error
+
+// Error implements the error interface.
+func (e Errors) Error() string {
+	strs := []string{}
+	for _, err := range e {
+		strs = append(strs, err.Error())
+	}
+	return strings.Join(strs, "\n")
+}
+
+// DefaultShutdownTimeout is the default time that requests in progress are
+// given to complete on shutdown.
+const DefaultShutdownTimeout = 10 * time.Second
+
+// Supervisor runs a set of servers, and shuts them down together.
+type Supervisor struct {
+	servers []Server
+	timeout time.Duration
+	drain   func(context.Context) error
+
+	mu    sync.Mutex
+	ready bool // Whether all the servers are serving.
+}
+
+// NewSupervisor returns a supervisor of the servers, that gives requests in
+// progress `timeout` to complete on shutdown.
+func NewSupervisor(timeout time.Duration, servers ...Server) *Supervisor {
+	return &Supervisor{servers: servers, timeout: timeout, drain: changes.Drain}
+}
+
+// Run the servers until the context is done, or a server fails. The listeners
+// of all the servers are bound first; if any fails, no server is started, and
+// the errors of all the failed listeners are returned. On shutdown, the
+// servers stop accepting requests, and the writes in progress are drained.
+func (s *Supervisor) Run(ctx context.Context) error {
+	errs := Errors{}
+	started := []Server{}
+	for _, srv := range s.servers {
+		if err := srv.Listen(); err != nil {
+			if es, ok := err.(Errors); ok {
+				errs = append(errs, es...)
+			} else {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		started = append(started, srv)
+	}
+	if len(errs) > 0 {
+		sctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+		defer cancel()
+		for _, srv := range started {
+			srv.Shutdown(sctx)
+		}
+		return errs
+	}
+
+	served := make(chan error, len(s.servers))
+	for _, srv := range s.servers {
+		go func(srv Server) {
+			if err := srv.Serve(); err != nil {
+				served <- fmt.Errorf("%s: %s", srv.Name(), err)
+				return
+			}
+			served <- nil
+		}(srv)
+	}
+
+	s.setReady(true)
+
+	running := len(s.servers)
+	select {
+	case <-ctx.Done():
+		log.Printf("shutting down")
+	case err := <-served:
+		running--
+		if err != nil {
+			errs = append(errs, err)
+		}
+		log.Printf("shutting down after a server stopped")
+	}
+	s.setReady(false)
+
+	sctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	for _, srv := range s.servers {
+		if err := srv.Shutdown(sctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s: error shutting down; %s", srv.Name(), err))
+		}
+	}
+	if err := s.drain(sctx); err != nil {
+		errs = append(errs, err)
+	}
+	for ; running > 0; running-- {
+		if err := <-served; err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (s *Supervisor) setReady(v bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ready = v
+}
+
+// Ready returns an error unless the listeners of all the servers are bound,
+// and the servers are serving. It is meant as a health check.
+func (s *Supervisor) Ready() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.ready {
+		return fmt.Errorf("the listeners of the servers are not all up")
+	}
+	return nil
+}
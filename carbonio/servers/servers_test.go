@@ -0,0 +1,128 @@
+package servers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer records the calls of the supervisor.
+type fakeServer struct {
+	name      string
+	listenErr error
+	serveErr  error // Returned by Serve right away, if set.
+
+	mu       sync.Mutex
+	calls    []string
+	shutdown chan struct{}
+}
+
+func newFakeServer(name string) *fakeServer {
+	return &fakeServer{name: name, shutdown: make(chan struct{})}
+}
+
+func (s *fakeServer) record(call string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, call)
+}
+
+// Calls returns the calls in sorted order, as the servers run concurrently.
+func (s *fakeServer) Calls() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	calls := append([]string{}, s.calls...)
+	sort.Strings(calls)
+	return fmt.Sprint(calls)
+}
+
+func (s *fakeServer) Name() string { return s.name }
+
+func (s *fakeServer) Listen() error {
+	s.record("listen")
+	if s.listenErr != nil {
+		return &ListenError{Server: s.name, Addr: "[::1]:1", Err: s.listenErr}
+	}
+	return nil
+}
+
+func (s *fakeServer) Serve() error {
+	s.record("serve")
+	if s.serveErr != nil {
+		return s.serveErr
+	}
+	<-s.shutdown
+	return nil
+}
+
+func (s *fakeServer) Shutdown(ctx context.Context) error {
+	s.record("shutdown")
+	select {
+	case <-s.shutdown:
+	default:
+		close(s.shutdown)
+	}
+	return nil
+}
+
+func TestSupervisor(t *testing.T) {
+	for _, tc := range []struct {
+		desc      string
+		listenErr error
+		serveErr  error
+		ok        bool
+		a, b      string // Calls of the servers.
+	}{
+		{"shutdown", nil, nil, true, "[listen serve shutdown]", "[listen serve shutdown]"},
+		{"listen error", fmt.Errorf("address in use"), nil, false, "[listen shutdown]", "[listen]"},
+		{"serve error", nil, fmt.Errorf("boom"), false, "[listen serve shutdown]", "[listen serve shutdown]"},
+	} {
+		t.Run(fmt.Sprintf("Run() %s", tc.desc), func(t *testing.T) {
+			a, b := newFakeServer("a"), newFakeServer("b")
+			b.listenErr, b.serveErr = tc.listenErr, tc.serveErr
+			drained := false
+			s := NewSupervisor(time.Second, a, b)
+			s.drain = func(context.Context) error { drained = true; return nil }
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error)
+			go func() { done <- s.Run(ctx) }()
+			if tc.ok {
+				time.Sleep(10 * time.Millisecond) // Let the servers start.
+				if err := s.Ready(); err != nil {
+					t.Errorf("Ready() unexpected error; %s", err)
+				}
+				cancel()
+			}
+			var err error
+			select {
+			case err = <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("Run() did not return")
+			}
+			cancel()
+			if err := s.Ready(); err == nil {
+				t.Errorf("Ready() expected an error after Run()")
+			}
+
+			if err != nil && tc.ok {
+				t.Errorf("unexpected error; %s", err)
+			}
+			if err == nil && !tc.ok {
+				t.Errorf("expected an error")
+			}
+			if got := a.Calls(); got != tc.a {
+				t.Errorf("server a calls = %s, want %s", got, tc.a)
+			}
+			if got := b.Calls(); got != tc.b {
+				t.Errorf("server b calls = %s, want %s", got, tc.b)
+			}
+			if want := tc.listenErr == nil; drained != want {
+				t.Errorf("drained = %t, want %t", drained, want)
+			}
+		})
+	}
+}
@@ -209,7 +209,13 @@ func (p *Phantom) Disable() error {
 }
 
 func (p *Phantom) setState(state bool) error {
-	u := uint(p.spi.Value())
+	// The phantoms of four signals share a single SPI file, so the current value
+	// must be read before it can be modified.
+	c, err := p.spi.Read()
+	if err != nil {
+		return fmt.Errorf("error reading phantom; %s", err)
+	}
+	u := uint(c)
 	v := uint(1 << (3 - ((p.num - 1) % 4)))
 	if state == PhantomEnabled {
 		u = u | v
@@ -244,8 +250,9 @@ func (p *Phantom) IsEnabled() (bool, error) {
 	return u&w > 0, nil
 }
 
-// Initialize implements spi.Implementation.
-func (p *Phantom) Initialize() error { return p.Disable() }
+// Initialize implements spi.Implementation. As the SPI file is shared, the
+// phantoms of all four signals on the same device are disabled.
+func (p *Phantom) Initialize() error { return p.spi.Initialize() }
 
 // Name implements spi.Implementation.
 func (p *Phantom) Name() string { return spi.Phantom.String() }
@@ -64,6 +64,8 @@ func New(name string, opts ...func(*options) error) (*Signal, error) {
 	return s, nil
 }
 
+func (s *Signal) Name() string             { return s.name }
+func (s *Signal) Number() int              { return s.opts.num }
 func (s *Signal) Connector() ConnectorEnum { return s.opts.conn }
 func (s *Signal) Direction() DirectionEnum { return s.opts.dir }
 func (s *Signal) Format() FormatEnum       { return s.opts.fmt }
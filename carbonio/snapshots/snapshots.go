@@ -0,0 +1,230 @@
+/*
+Package snapshots provides named snapshots of the device state.
+
+A snapshot holds the typed values of the snapshotted targets by address, e.g.
+`input/mic/1/gain: 30`. Snapshots are stored as JSON documents, one file per
+snapshot, in a store directory.
+*/
+package snapshots
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kward/avid-s3l/carbonio/address"
+	"github.com/kward/avid-s3l/carbonio/api"
+	"github.com/kward/avid-s3l/carbonio/changes"
+	"github.com/kward/golib/errors"
+	"google.golang.org/grpc/codes"
+)
+
+// Prefix of snapshot names on the command-line, e.g. `@pre-show`.
+const Prefix = "@"
+
+// ext is the file extension of stored snapshots.
+const ext = ".json"
+
+var nameRE = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_.-]*$`)
+
+// ValidName returns an error if the snapshot name is invalid.
+func ValidName(name string) error {
+	if !nameRE.MatchString(name) {
+		return errors.Errorf(codes.InvalidArgument, "invalid snapshot name %q; want letters, digits, `_`, `.`, or `-`", name)
+	}
+	return nil
+}
+
+// Snapshot of the device state.
+type Snapshot struct {
+	Version int       `json:"version"`
+	Name    string    `json:"name"`
+	Created time.Time `json:"created"`
+	// Addresses that were snapshotted, e.g. `input` or `led`.
+	Addresses []string `json:"addresses"`
+	// Values holds the typed values by target address.
+	Values map[string]interface{} `json:"values"`
+}
+
+// ListKind is the kind of a List document.
+const ListKind = "Snapshots"
+
+// List of snapshots, as served by the HTTP API.
+type List struct {
+	Version int         `json:"version"`
+	Kind    string      `json:"kind"`
+	Items   []*Snapshot `json:"items"`
+}
+
+// NewList returns a list of the snapshots.
+func NewList(ss []*Snapshot) *List {
+	return &List{
+		Version: api.Version,
+		Kind:    ListKind,
+		Items:   ss,
+	}
+}
+
+// Take a snapshot of the targets.
+func Take(name string, addrs []string, ts []*address.Target) (*Snapshot, error) {
+	if err := ValidName(name); err != nil {
+		return nil, err
+	}
+	s := &Snapshot{
+		Version:   api.Version,
+		Name:      name,
+		Created:   time.Now().UTC(),
+		Addresses: addrs,
+		Values:    map[string]interface{}{},
+	}
+	for _, t := range ts {
+		if !t.Property.Editable {
+			continue // Read-only values cannot be rolled back.
+		}
+		v, err := t.Value()
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s; %s", t.Address, err)
+		}
+		s.Values[t.Address.String()] = v
+	}
+	return s, nil
+}
+
+// Changes returns the changes required to roll the targets back to the
+// snapshot.
+func (s *Snapshot) Changes(ts []*address.Target) ([]*changes.Change, error) {
+	return changes.Diff(ts, s.Values)
+}
+
+// Keys returns the snapshotted addresses in sorted order.
+func (s *Snapshot) Keys() []string {
+	keys := []string{}
+	for k := range s.Values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Store of snapshots.
+type Store interface {
+	// List returns the snapshots, ordered by creation time.
+	List() ([]*Snapshot, error)
+	// Load the named snapshot.
+	Load(name string) (*Snapshot, error)
+	// Save the snapshot. An existing snapshot of the same name is replaced.
+	Save(s *Snapshot) error
+	// Delete the named snapshot.
+	Delete(name string) error
+}
+
+// DirStore stores snapshots in a directory.
+type DirStore struct {
+	dir string
+}
+
+var _ Store = new(DirStore)
+
+// NewDirStore returns a store of snapshots in the given directory. The
+// directory is created when the first snapshot is saved.
+func NewDirStore(dir string) *DirStore {
+	return &DirStore{dir: dir}
+}
+
+// DefaultDir returns the default snapshot directory.
+func DefaultDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "carbonio", "snapshots")
+	}
+	return filepath.Join(home, ".carbonio", "snapshots")
+}
+
+func (d *DirStore) path(name string) string { return filepath.Join(d.dir, name+ext) }
+
+// List implements Store.
+func (d *DirStore) List() ([]*Snapshot, error) {
+	fis, err := ioutil.ReadDir(d.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*Snapshot{}, nil
+		}
+		return nil, fmt.Errorf("error reading snapshot dir; %s", err)
+	}
+	ss := []*Snapshot{}
+	for _, fi := range fis {
+		if fi.IsDir() || !strings.HasSuffix(fi.Name(), ext) {
+			continue
+		}
+		s, err := d.Load(strings.TrimSuffix(fi.Name(), ext))
+		if err != nil {
+			return nil, err
+		}
+		ss = append(ss, s)
+	}
+	sort.SliceStable(ss, func(i, j int) bool { return ss[i].Created.Before(ss[j].Created) })
+	return ss, nil
+}
+
+// Load implements Store.
+func (d *DirStore) Load(name string) (*Snapshot, error) {
+	if err := ValidName(name); err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(d.path(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.Errorf(codes.NotFound, "snapshot %s%s not found", Prefix, name)
+		}
+		return nil, fmt.Errorf("error reading snapshot %s; %s", name, err)
+	}
+	s := &Snapshot{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("error parsing snapshot %s; %s", name, err)
+	}
+	return s, nil
+}
+
+// Save implements Store. The snapshot is written to a temporary file first, so
+// that an existing snapshot is never left half written.
+func (d *DirStore) Save(s *Snapshot) error {
+	if err := ValidName(s.Name); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshaling snapshot %s; %s", s.Name, err)
+	}
+	if err := os.MkdirAll(d.dir, 0755); err != nil {
+		return fmt.Errorf("error creating snapshot dir; %s", err)
+	}
+	tmp := d.path(s.Name) + ".tmp"
+	if err := ioutil.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("error writing snapshot %s; %s", s.Name, err)
+	}
+	if err := os.Rename(tmp, d.path(s.Name)); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error writing snapshot %s; %s", s.Name, err)
+	}
+	return nil
+}
+
+// Delete implements Store.
+func (d *DirStore) Delete(name string) error {
+	if err := ValidName(name); err != nil {
+		return err
+	}
+	if err := os.Remove(d.path(name)); err != nil {
+		if os.IsNotExist(err) {
+			return errors.Errorf(codes.NotFound, "snapshot %s%s not found", Prefix, name)
+		}
+		return fmt.Errorf("error deleting snapshot %s; %s", name, err)
+	}
+	return nil
+}
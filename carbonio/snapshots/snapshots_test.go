@@ -0,0 +1,72 @@
+package snapshots
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/kward/golib/errors"
+	"google.golang.org/grpc/codes"
+)
+
+func TestValidName(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		ok   bool
+	}{
+		{"pre-show", true},
+		{"v1.2_final", true},
+		{"", false},
+		{"-flag", false},
+		{"../etc", false},
+		{"a/b", false},
+	} {
+		t.Run(fmt.Sprintf("ValidName(%q)", tc.name), func(t *testing.T) {
+			err := ValidName(tc.name)
+			if err != nil && tc.ok {
+				t.Errorf("unexpected error; %s", err)
+			}
+			if err == nil && !tc.ok {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
+
+func TestDirStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshots")
+	if err != nil {
+		t.Fatalf("error creating temp dir; %s", err)
+	}
+	defer os.RemoveAll(dir)
+	s := NewDirStore(dir + "/snaps") // Created on save.
+
+	if ss, err := s.List(); err != nil || len(ss) != 0 {
+		t.Fatalf("List() = %v, %v; want no snapshots", ss, err)
+	}
+	for _, name := range []string{"a", "b"} {
+		if err := s.Save(&Snapshot{Name: name, Addresses: []string{"*"}, Values: map[string]interface{}{"input/mic/1/gain": 30}}); err != nil {
+			t.Fatalf("Save(%s) unexpected error; %s", name, err)
+		}
+	}
+	snap, err := s.Load("a")
+	if err != nil {
+		t.Fatalf("Load() unexpected error; %s", err)
+	}
+	if got, want := snap.Values["input/mic/1/gain"], float64(30); got != want {
+		t.Errorf("Load() gain = %v, want %v", got, want)
+	}
+	if err := s.Delete("a"); err != nil {
+		t.Fatalf("Delete() unexpected error; %s", err)
+	}
+	if _, err := s.Load("a"); errors.Code(err) != codes.NotFound {
+		t.Errorf("Load() of deleted snapshot = %v, want NotFound", err)
+	}
+	if err := s.Delete("a"); errors.Code(err) != codes.NotFound {
+		t.Errorf("Delete() of deleted snapshot = %v, want NotFound", err)
+	}
+	if ss, err := s.List(); err != nil || len(ss) != 1 || ss[0].Name != "b" {
+		t.Errorf("List() = %v, %v; want [b]", ss, err)
+	}
+}
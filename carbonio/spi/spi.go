@@ -9,8 +9,10 @@ SPI board ids:
   - 0x7 AES AES outputs
   - 0x5 DAC line outputs
   - 0x6 ADC mic inputs
+
 - E3 Engine
   - 2   ADC mutes, phantoms; switch
+
 - Stage 16
   - 1   ADC mutes, phantom; LEDs
 */
@@ -22,6 +24,8 @@ import (
 	"path"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/kward/avid-s3l/carbonio/helpers"
 )
@@ -31,8 +35,12 @@ const DevicesDir = "/sys/bus/spi/devices"
 type SPI struct {
 	opts *options
 
-	enum  Enum
-	path  string // Full path to the SPI file (e.g. `/some/path/spi1.0/ch0_pad_en`).
+	enum Enum
+	path string // Full path to the SPI file (e.g. `/some/path/spi1.0/ch0_pad_en`).
+
+	// The values are cached by reads, which may be concurrent, e.g. of the
+	// servers and of the watcher of changes.
+	mu    sync.Mutex
 	value int    // Current value of the SPI file.
 	raw   []byte // Most recent raw value read-or-written.
 }
@@ -95,8 +103,46 @@ func New(enum Enum, num int, opts ...func(*options) error) (*SPI, error) {
 	return spi, nil
 }
 
+// Counters of the SPI operations of the process. They are global variables, so
+// that they are 64-bit aligned for the atomic operations on 32-bit ARM.
+var (
+	reads, readErrors   uint64
+	writes, writeErrors uint64
+	mismatches          uint64
+)
+
+// Stats are the counts of the SPI operations of the process.
+type Stats struct {
+	Reads       uint64
+	ReadErrors  uint64
+	Writes      uint64
+	WriteErrors uint64
+	// Mismatches are the writes whose read-after-write returned another value.
+	Mismatches uint64
+}
+
+// GetStats returns the counts of the SPI operations of the process.
+func GetStats() Stats {
+	return Stats{
+		Reads:       atomic.LoadUint64(&reads),
+		ReadErrors:  atomic.LoadUint64(&readErrors),
+		Writes:      atomic.LoadUint64(&writes),
+		WriteErrors: atomic.LoadUint64(&writeErrors),
+		Mismatches:  atomic.LoadUint64(&mismatches),
+	}
+}
+
 // Read the current value from the SPI interface, storing a copy in `value`.
 func (s *SPI) Read() (int, error) {
+	atomic.AddUint64(&reads, 1)
+	v, err := s.read()
+	if err != nil {
+		atomic.AddUint64(&readErrors, 1)
+	}
+	return v, err
+}
+
+func (s *SPI) read() (int, error) {
 	data, err := helpers.ReadFileFn()(s.path)
 	if err != nil {
 		return 0, fmt.Errorf("failed to read %s from %s; %s", s.enum, s.path, err)
@@ -104,6 +150,8 @@ func (s *SPI) Read() (int, error) {
 	if len(data) == 0 {
 		return 0, fmt.Errorf("empty data read from %s", s.path)
 	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.raw = data
 	v, err := strconv.Atoi(strings.TrimRight(string(data), "\n"))
 	if err != nil {
@@ -117,8 +165,10 @@ var fileMode = os.FileMode(0644)
 
 // Write data to the SPI interface.
 func (s *SPI) Write(v int) error {
+	atomic.AddUint64(&writes, 1)
 	str := strconv.Itoa(v) + "\n"
 	if err := helpers.WriteFileFn()(s.path, []byte(str), fileMode); err != nil {
+		atomic.AddUint64(&writeErrors, 1)
 		return fmt.Errorf("failed to write %s value of %d to %s; %s", s.enum, v, s.path, err)
 	}
 
@@ -128,6 +178,7 @@ func (s *SPI) Write(v int) error {
 		return fmt.Errorf("read-after-write error: %s", err)
 	}
 	if v != data {
+		atomic.AddUint64(&mismatches, 1)
 		return fmt.Errorf("read-after-write data mismatch: got = %d, want = %d", data, v)
 	}
 	return nil
@@ -161,10 +212,18 @@ type PathFn func() string
 func (s *SPI) Path() string { return string(s.path) }
 
 // Raw implements Implementation.
-func (s *SPI) Raw() []byte { return s.raw }
+func (s *SPI) Raw() []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.raw
+}
 
 // Value returns the most recent value read from the SPI interface.
-func (s *SPI) Value() int { return s.value }
+func (s *SPI) Value() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.value
+}
 
 // adcDir maps the input number to the appropriate ADC SPI device directory.
 //
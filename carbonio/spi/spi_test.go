@@ -135,3 +135,40 @@ func TestSPIChannels(t *testing.T) {
 		})
 	}
 }
+
+func TestStats(t *testing.T) {
+	helpers.ResetMockReadWrite()
+	helpers.PrepareMockReadFile([]byte("1\n"), nil)
+	s, err := New(Gain, 1, DelayRead(true), BaseDir("/spi/base"))
+	if err != nil {
+		t.Fatalf("unexpected error; %s", err)
+	}
+
+	before := GetStats()
+	s.Read()
+	s.Write(2)
+	helpers.PrepareMockWriteFile(fmt.Errorf("mock WriteFile error"))
+	s.Write(3) // Write error, which also empties the data.
+	s.Read()   // Read error, of the empty data.
+	helpers.PrepareMockWriteFile(nil)
+	defer helpers.SetWriteFileFn(helpers.MockWriteFile)
+	helpers.SetWriteFileFn(func(string, []byte, os.FileMode) error { return nil })
+	helpers.PrepareMockReadFile([]byte("1\n"), nil)
+	s.Write(4) // Mismatch.
+	after := GetStats()
+
+	for _, tc := range []struct {
+		desc      string
+		got, want uint64
+	}{
+		{"Reads", after.Reads - before.Reads, 4},
+		{"ReadErrors", after.ReadErrors - before.ReadErrors, 1},
+		{"Writes", after.Writes - before.Writes, 3},
+		{"WriteErrors", after.WriteErrors - before.WriteErrors, 1},
+		{"Mismatches", after.Mismatches - before.Mismatches, 1},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("%s = %d, want %d", tc.desc, tc.got, tc.want)
+		}
+	}
+}
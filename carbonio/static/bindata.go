@@ -1,6 +1,9 @@
 // Package static Code generated by go-bindata. (@generated) DO NOT EDIT.
 // sources:
+// static/control.css
+// static/control.js
 // static/jquery-3.4.1.min.js
+// static/live.js
 // static/reset.css
 package static
 
@@ -153,6 +156,42 @@ func AssetFile() http.FileSystem {
 	return &assetOperator{}
 }
 
+// controlCss reads file data from disk. It returns an error on failure.
+func controlCss() (*asset, error) {
+	path := "/Users/kward/var/wa/github.com/kward/avid-s3l/carbonio/static/control.css"
+	name := "control.css"
+	bytes, err := bindataRead(path, name)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		err = fmt.Errorf("Error reading asset info %s at %s: %v", name, path, err)
+	}
+
+	a := &asset{bytes: bytes, info: fi}
+	return a, err
+}
+
+// controlJs reads file data from disk. It returns an error on failure.
+func controlJs() (*asset, error) {
+	path := "/Users/kward/var/wa/github.com/kward/avid-s3l/carbonio/static/control.js"
+	name := "control.js"
+	bytes, err := bindataRead(path, name)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		err = fmt.Errorf("Error reading asset info %s at %s: %v", name, path, err)
+	}
+
+	a := &asset{bytes: bytes, info: fi}
+	return a, err
+}
+
 // jquery341MinJs reads file data from disk. It returns an error on failure.
 func jquery341MinJs() (*asset, error) {
 	path := "/Users/kward/var/wa/github.com/kward/avid-s3l/carbonio/static/jquery-3.4.1.min.js"
@@ -171,6 +210,24 @@ func jquery341MinJs() (*asset, error) {
 	return a, err
 }
 
+// liveJs reads file data from disk. It returns an error on failure.
+func liveJs() (*asset, error) {
+	path := "/Users/kward/var/wa/github.com/kward/avid-s3l/carbonio/static/live.js"
+	name := "live.js"
+	bytes, err := bindataRead(path, name)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		err = fmt.Errorf("Error reading asset info %s at %s: %v", name, path, err)
+	}
+
+	a := &asset{bytes: bytes, info: fi}
+	return a, err
+}
+
 // resetCss reads file data from disk. It returns an error on failure.
 func resetCss() (*asset, error) {
 	path := "/Users/kward/var/wa/github.com/kward/avid-s3l/carbonio/static/reset.css"
@@ -241,7 +298,10 @@ func AssetNames() []string {
 
 // _bindata is a table, holding each asset generator, mapped to its name.
 var _bindata = map[string]func() (*asset, error){
+	"control.css":         controlCss,
+	"control.js":          controlJs,
 	"jquery-3.4.1.min.js": jquery341MinJs,
+	"live.js":             liveJs,
 	"reset.css":           resetCss,
 }
 
@@ -286,7 +346,10 @@ type bintree struct {
 }
 
 var _bintree = &bintree{nil, map[string]*bintree{
+	"control.css":         &bintree{controlCss, map[string]*bintree{}},
+	"control.js":          &bintree{controlJs, map[string]*bintree{}},
 	"jquery-3.4.1.min.js": &bintree{jquery341MinJs, map[string]*bintree{}},
+	"live.js":             &bintree{liveJs, map[string]*bintree{}},
 	"reset.css":           &bintree{resetCss, map[string]*bintree{}},
 }}
 
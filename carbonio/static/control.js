@@ -0,0 +1,185 @@
+// Control surface of the device. The controls are built from the targets of
+// the API, so new kinds of signals (e.g. outputs) show up without changes
+// here.
+(function () {
+  var sections = {input: "Inputs", output: "Outputs", led: "LEDs"};
+  var controls = {}; // Controls by target address.
+
+  function request(method, path, body, done) {
+    var req = new XMLHttpRequest();
+    req.onload = function () {
+      var doc = null;
+      try {
+        doc = JSON.parse(req.responseText);
+      } catch (e) {}
+      if (req.status >= 400) {
+        var msg = doc && doc.error ? doc.error.message : req.statusText;
+        showError(msg || "request failed");
+      } else {
+        showError("");
+      }
+      done(req.status, doc);
+    };
+    req.onerror = function () {
+      setState("offline");
+      done(0, null);
+    };
+    req.open(method, path);
+    if (body !== null) {
+      req.setRequestHeader("Content-Type", "application/json");
+      body = JSON.stringify(body);
+    }
+    req.send(body);
+  }
+
+  function showError(msg) {
+    var el = document.getElementById("error");
+    el.textContent = msg;
+    el.style.display = msg ? "block" : "none";
+  }
+
+  function setState(state) {
+    var el = document.getElementById("state");
+    el.className = state;
+    el.textContent = state;
+  }
+
+  // set writes the value of a target, and refreshes the controls.
+  function set(target, value) {
+    var values = {};
+    values[target.address] = value;
+    request("PATCH", "/api/v1/values", {values: values}, function () {
+      refresh();
+    });
+  }
+
+  function label(target) {
+    var p = target.property;
+    return p.name + (p.unit ? " (" + p.unit + ")" : "");
+  }
+
+  // control returns a control of the target, with an update(value) method.
+  function control(target) {
+    var p = target.property;
+    var div = document.createElement("div");
+    div.className = "control";
+    var lbl = document.createElement("label");
+    lbl.textContent = label(target);
+    div.appendChild(lbl);
+
+    if (p.kind === "int") {
+      var input = document.createElement("input");
+      input.type = "range";
+      input.min = p.min;
+      input.max = p.max;
+      input.disabled = !p.editable;
+      var out = document.createElement("span");
+      out.className = "value";
+      lbl.appendChild(document.createTextNode(" "));
+      lbl.appendChild(out);
+      input.oninput = function () { out.textContent = input.value; };
+      input.onchange = function () { set(target, parseInt(input.value, 10)); };
+      div.appendChild(input);
+      div.update = function (v) {
+        if (document.activeElement === input) {
+          return; // Do not move a fader from under a finger.
+        }
+        input.value = v;
+        out.textContent = v;
+      };
+    } else if (p.kind === "bool") {
+      var button = document.createElement("button");
+      button.disabled = !p.editable;
+      var on = false;
+      button.onclick = function () {
+        if (p.name === "phantom" &&
+            !window.confirm("Turn phantom power " + (on ? "off" : "on") + " for " + target.label + "?")) {
+          return;
+        }
+        set(target, !on);
+      };
+      div.appendChild(button);
+      div.update = function (v) {
+        on = v;
+        button.textContent = v ? "On" : "Off";
+        button.className = (v ? "on " : "") + p.name;
+      };
+    } else if (p.kind === "enum") {
+      var select = document.createElement("select");
+      select.disabled = !p.editable;
+      for (var i = 0; i < p.states.length; i++) {
+        var opt = document.createElement("option");
+        opt.value = opt.textContent = p.states[i];
+        select.appendChild(opt);
+      }
+      select.onchange = function () { set(target, select.value); };
+      div.appendChild(select);
+      div.update = function (v) { select.value = v; };
+    } else {
+      var span = document.createElement("span");
+      span.className = "value";
+      div.appendChild(span);
+      div.update = function (v) { span.textContent = v; };
+    }
+    return div;
+  }
+
+  // build the strips of the targets, one per node.
+  function build(targets) {
+    var main = document.getElementById("controls");
+    main.innerHTML = "";
+    controls = {};
+    var strips = {};
+    for (var i = 0; i < targets.length; i++) {
+      var t = targets[i];
+      var root = t.address.split("/")[0];
+      var node = t.address.substring(0, t.address.lastIndexOf("/"));
+      var section = document.getElementById("section-" + root);
+      if (!section) {
+        var h2 = document.createElement("h2");
+        h2.textContent = sections[root] || root;
+        main.appendChild(h2);
+        section = document.createElement("div");
+        section.id = "section-" + root;
+        section.className = "strips";
+        main.appendChild(section);
+      }
+      var strip = strips[node];
+      if (!strip) {
+        strip = document.createElement("div");
+        strip.className = "strip";
+        var h3 = document.createElement("h3");
+        h3.textContent = t.label;
+        strip.appendChild(h3);
+        section.appendChild(strip);
+        strips[node] = strip;
+      }
+      var c = control(t);
+      strip.appendChild(c);
+      controls[t.address] = c;
+    }
+  }
+
+  // refresh the controls with the current values.
+  function refresh() {
+    request("GET", "/api/v1/targets", null, function (status, doc) {
+      if (status !== 200 || !doc) {
+        return;
+      }
+      var known = doc.items.length === Object.keys(controls).length;
+      for (var i = 0; known && i < doc.items.length; i++) {
+        known = doc.items[i].address in controls;
+      }
+      if (!known) {
+        build(doc.items);
+      }
+      for (var j = 0; j < doc.items.length; j++) {
+        controls[doc.items[j].address].update(doc.items[j].value);
+      }
+    });
+  }
+
+  document.addEventListener("DOMContentLoaded", function () {
+    live(refresh, 3000, setState);
+  });
+})();
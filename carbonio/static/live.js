@@ -0,0 +1,56 @@
+// live calls refresh() whenever the device state changes, as pushed by the
+// server over a WebSocket. If the WebSocket is unavailable, refresh() is
+// instead called every `interval` milliseconds until it reconnects. The
+// optional onstate() is called with "live" or "polling" as the mode changes.
+function live(refresh, interval, onstate) {
+  interval = interval || 3000;
+  onstate = onstate || function () {};
+  var poller = null;
+  var retry = 1000;
+
+  function poll() {
+    if (poller === null) {
+      poller = setInterval(refresh, interval);
+      onstate("polling");
+    }
+  }
+
+  function connect() {
+    var proto = window.location.protocol === "https:" ? "wss:" : "ws:";
+    var ws;
+    try {
+      ws = new WebSocket(proto + "//" + window.location.host + "/ws");
+    } catch (e) {
+      poll();
+      return;
+    }
+    var pending = false;
+    ws.onopen = function () {
+      retry = 1000;
+      if (poller !== null) {
+        clearInterval(poller);
+        poller = null;
+      }
+      onstate("live");
+    };
+    ws.onmessage = function () {
+      // Coalesce bursts of events, e.g. a snapshot rollback, into one refresh.
+      if (!pending) {
+        pending = true;
+        setTimeout(function () { pending = false; refresh(); }, 50);
+      }
+    };
+    ws.onclose = function () {
+      poll();
+      setTimeout(connect, retry);
+      retry = Math.min(retry * 2, 30000);
+    };
+  }
+
+  refresh();
+  if ("WebSocket" in window) {
+    connect();
+  } else {
+    poll();
+  }
+}
@@ -1,5 +1,7 @@
 // Package templates Code generated by go-bindata. (@generated) DO NOT EDIT.
 // sources:
+// templates/html/control.tmpl
+// templates/html/error.tmpl
 // templates/html/list.tmpl
 // templates/html/status.tmpl
 package templates
@@ -64,6 +66,42 @@ func (fi bindataFileInfo) Sys() interface{} {
 	return nil
 }
 
+// htmlControlTmpl reads file data from disk. It returns an error on failure.
+func htmlControlTmpl() (*asset, error) {
+	path := "/Users/kward/var/wa/github.com/kward/avid-s3l/carbonio/templates/html/control.tmpl"
+	name := "html/control.tmpl"
+	bytes, err := bindataRead(path, name)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		err = fmt.Errorf("Error reading asset info %s at %s: %v", name, path, err)
+	}
+
+	a := &asset{bytes: bytes, info: fi}
+	return a, err
+}
+
+// htmlErrorTmpl reads file data from disk. It returns an error on failure.
+func htmlErrorTmpl() (*asset, error) {
+	path := "/Users/kward/var/wa/github.com/kward/avid-s3l/carbonio/templates/html/error.tmpl"
+	name := "html/error.tmpl"
+	bytes, err := bindataRead(path, name)
+	if err != nil {
+		return nil, err
+	}
+
+	fi, err := os.Stat(path)
+	if err != nil {
+		err = fmt.Errorf("Error reading asset info %s at %s: %v", name, path, err)
+	}
+
+	a := &asset{bytes: bytes, info: fi}
+	return a, err
+}
+
 // htmlListTmpl reads file data from disk. It returns an error on failure.
 func htmlListTmpl() (*asset, error) {
 	path := "/Users/kward/var/wa/github.com/kward/avid-s3l/carbonio/templates/html/list.tmpl"
@@ -152,8 +190,10 @@ func AssetNames() []string {
 
 // _bindata is a table, holding each asset generator, mapped to its name.
 var _bindata = map[string]func() (*asset, error){
-	"html/list.tmpl":   htmlListTmpl,
-	"html/status.tmpl": htmlStatusTmpl,
+	"html/control.tmpl": htmlControlTmpl,
+	"html/error.tmpl":   htmlErrorTmpl,
+	"html/list.tmpl":    htmlListTmpl,
+	"html/status.tmpl":  htmlStatusTmpl,
 }
 
 // AssetDir returns the file names below a certain
@@ -198,8 +238,10 @@ type bintree struct {
 
 var _bintree = &bintree{nil, map[string]*bintree{
 	"html": &bintree{nil, map[string]*bintree{
-		"list.tmpl":   &bintree{htmlListTmpl, map[string]*bintree{}},
-		"status.tmpl": &bintree{htmlStatusTmpl, map[string]*bintree{}},
+		"control.tmpl": &bintree{htmlControlTmpl, map[string]*bintree{}},
+		"error.tmpl":   &bintree{htmlErrorTmpl, map[string]*bintree{}},
+		"list.tmpl":    &bintree{htmlListTmpl, map[string]*bintree{}},
+		"status.tmpl":  &bintree{htmlStatusTmpl, map[string]*bintree{}},
 	}},
 }}
 
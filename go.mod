@@ -5,9 +5,12 @@ go 1.13
 require (
 	github.com/golang/protobuf v1.5.2
 	github.com/gorilla/mux v1.8.0
+	github.com/gorilla/websocket v1.4.2
 	github.com/kward/golib v0.0.0-20200208040419-af7b024db24a
 	github.com/kward/tabulate v0.0.0-20200301231205-23e535fd4003
 	github.com/spf13/cobra v1.2.1
+	golang.org/x/crypto v0.0.0-20211117183948-ae814b36b871
 	google.golang.org/grpc v1.38.0
 	google.golang.org/protobuf v1.26.0
+	gopkg.in/yaml.v2 v2.4.0
 )